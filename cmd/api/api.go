@@ -1,18 +1,32 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/ardanlabs/conf/v3"
 	"github.com/joho/godotenv"
 	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/api"
 	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/apikeys"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/audit"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/auth"
 	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/catalog"
 	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/payments"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/ratelimit"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/searches"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/sellers"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/sms"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/storage"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/vehicles"
 )
 
 func main() {
@@ -34,6 +48,70 @@ func run() error {
 			CamPayPassword string `conf:"env:CAMPAY_PASSWORD,required"`
 			WebHookAppKey  string `conf:"env:WEBHOOK_APP_KEY,required"`
 		}
+		Auth struct {
+			JWTHMACSecrets    string        `conf:"env:JWT_HMAC_SECRETS,mask"`
+			JWTPublicKeyFiles string        `conf:"env:JWT_PUBLIC_KEY_FILES"`
+			JWKSFile          string        `conf:"env:JWT_JWKS_FILE"`
+			JWTPrivateKeyFile string        `conf:"env:JWT_PRIVATE_KEY_FILE"`
+			JWTSigningKeyID   string        `conf:"env:JWT_SIGNING_KEY_ID"`
+			Issuer            string        `conf:"env:JWT_ISSUER"`
+			Audience          string        `conf:"env:JWT_AUDIENCE"`
+			AccessTokenTTL    time.Duration `conf:"env:ACCESS_TOKEN_TTL,default:15m"`
+			RefreshTokenTTL   time.Duration `conf:"env:REFRESH_TOKEN_TTL,default:720h"`
+			LoginMaxAttempts  int           `conf:"env:LOGIN_MAX_ATTEMPTS,default:5"`
+			LoginLockout      time.Duration `conf:"env:LOGIN_LOCKOUT,default:15m"`
+			// AccountTokenSecret signs email verification and password reset tokens.
+			AccountTokenSecret string        `conf:"env:ACCOUNT_TOKEN_SECRET,mask,required"`
+			VerifyEmailTTL     time.Duration `conf:"env:VERIFY_EMAIL_TTL,default:48h"`
+			PasswordResetTTL   time.Duration `conf:"env:PASSWORD_RESET_TTL,default:1h"`
+			AccountEmailsLimit int           `conf:"env:ACCOUNT_EMAILS_PER_HOUR,default:3"`
+			OTPTTL             time.Duration `conf:"env:OTP_TTL,default:5m"`
+			OTPMaxAttempts     int           `conf:"env:OTP_MAX_ATTEMPTS,default:5"`
+			OTPPerHour         int           `conf:"env:OTP_PER_HOUR,default:5"`
+		}
+		SMS struct {
+			// Backend is log to print the recipients of messages instead of sending them, or file to append messages to
+			// FilePath.
+			Backend            string `conf:"env:SMS_BACKEND,required"`
+			FilePath           string `conf:"env:SMS_FILE_PATH"`
+			DefaultCountryCode string `conf:"env:DEFAULT_PHONE_COUNTRY_CODE,default:237"`
+		}
+		Email struct {
+			// Backend is ses, or log to print the recipients and subjects of emails instead of sending them.
+			Backend          string `conf:"env:EMAIL_BACKEND,required"`
+			Sender           string `conf:"env:EMAIL_SENDER"`
+			AWSRegion        string `conf:"env:EMAIL_AWS_REGION,default:us-west-2"`
+			ConfigurationSet string `conf:"env:EMAIL_SES_CONFIGURATION_SET"`
+			AppBaseURL       string `conf:"env:APP_BASE_URL,required"`
+		}
+		Listings struct {
+			// WithdrawalCutoff is how long before the end of an auction sellers can no longer withdraw their car.
+			WithdrawalCutoff time.Duration `conf:"env:WITHDRAWAL_CUTOFF,default:24h"`
+		}
+		Watchlist struct {
+			// EndingWithin is how long before the end of an auction watchers are told it is ending.
+			EndingWithin time.Duration `conf:"env:WATCHLIST_ENDING_WITHIN,default:1h"`
+			// SweepInterval is how often ending and sold auctions are looked for.
+			SweepInterval time.Duration `conf:"env:WATCHLIST_SWEEP_INTERVAL,default:1m"`
+		}
+		SearchAlerts struct {
+			// SweepInterval is how often queued alerts of new listings matching saved searches are sent.
+			SweepInterval time.Duration `conf:"env:SEARCH_ALERTS_SWEEP_INTERVAL,default:1m"`
+		}
+		Photos struct {
+			// Storage is local to keep photos in Dir, or s3 to keep them in S3Bucket.
+			Storage string `conf:"env:PHOTO_STORAGE,default:local"`
+			Dir     string `conf:"env:PHOTO_DIR,default:./photos"`
+			// BaseURL is the public URL of the /photos route, which serves local photos.
+			BaseURL string `conf:"env:PHOTO_BASE_URL,default:http://localhost:9080/photos"`
+			// URLSecret signs the URLs of local photos.
+			URLSecret   string        `conf:"env:PHOTO_URL_SECRET,mask"`
+			URLTTL      time.Duration `conf:"env:PHOTO_URL_TTL,default:1h"`
+			S3Bucket    string        `conf:"env:PHOTO_S3_BUCKET"`
+			S3Region    string        `conf:"env:PHOTO_S3_REGION,default:us-west-2"`
+			S3Endpoint  string        `conf:"env:PHOTO_S3_ENDPOINT"`
+			S3PathStyle bool          `conf:"env:PHOTO_S3_PATH_STYLE,default:false"`
+		}
 		DB struct {
 			User           string `conf:"env:DB_USER,mask,required"`
 			Password       string `conf:"env:DB_PASSWORD,mask,required"`
@@ -43,6 +121,13 @@ func run() error {
 			DisableTLS     bool   `conf:"env:DB_DISABLE_TLS,default:false"`
 			MigrationsPath string `conf:"env:DB_MIGRATIONS_PATH,required"`
 		}
+		RateLimit struct {
+			// Backend is memory, postgres to share limits across replicas, or off.
+			Backend        string `conf:"env:RATE_LIMIT_BACKEND,default:memory"`
+			Limits         string `conf:"env:RATE_LIMITS,default:default=300/1m;auth=20/1m;bids=30/1m;listings=20/1h;kyc=20/1h"`
+			IPLimits       string `conf:"env:RATE_LIMITS_PER_IP,default:default=1200/1m;auth=20/1m;bids=120/1m;listings=80/1h;kyc=40/1h"`
+			TrustedProxies string `conf:"env:TRUSTED_PROXIES"`
+		}
 		DisableAuthorization bool   `conf:"env:DISABLE_AUTHORIZATION"`
 		AllowedOrigins       string `conf:"env:ALLOWED_ORIGINS,required"`
 	}
@@ -85,18 +170,234 @@ func run() error {
 		return err
 	}
 
+	// Car properties that could not be converted to typed columns are kept in car_migration_issues for review.
+	migrationIssues, err := repo.ListCarMigrationIssues(context.Background())
+	if err != nil {
+		return fmt.Errorf("listing car migration issues: %w", err)
+	}
+
+	for _, issue := range migrationIssues {
+		log.Printf("car %s: %s %q was not migrated: %s", issue.CarID, issue.Field, issue.Value, issue.Reason)
+	}
+
 	pymentService, err := payments.NewPymentService(cfg.Payments.CamPayUser, cfg.Payments.CamPayPassword, cfg.Payments.BaseURL)
 	if err != nil {
 		return err
 	}
 
-	eventService, err := cars.NewService(repo, pymentService, cfg.Payments.WebHookAppKey)
+	auditService, err := audit.NewService(repo)
+	if err != nil {
+		return err
+	}
+
+	keyConfig := auth.KeyConfig{
+		HMACSecrets:    cfg.Auth.JWTHMACSecrets,
+		PublicKeyFiles: cfg.Auth.JWTPublicKeyFiles,
+		JWKSFile:       cfg.Auth.JWKSFile,
+		PrivateKeyFile: cfg.Auth.JWTPrivateKeyFile,
+		SigningKeyID:   cfg.Auth.JWTSigningKeyID,
+	}
+
+	keys, err := auth.LoadKeySet(keyConfig)
+	if err != nil {
+		return fmt.Errorf("loading jwt keys: %w", err)
+	}
+
+	verifier, err := auth.NewVerifier(keys, cfg.Auth.Issuer, cfg.Auth.Audience)
+	if err != nil {
+		return err
+	}
+
+	signer, err := auth.LoadSigner(keyConfig, cfg.Auth.Issuer, cfg.Auth.Audience, cfg.Auth.AccessTokenTTL)
+	if err != nil {
+		return fmt.Errorf("loading jwt signing key: %w", err)
+	}
+
+	var mailer services.Mailer
+
+	switch cfg.Email.Backend {
+	case "ses":
+		mailer, err = services.NewSESMailer(cfg.Email.AWSRegion, cfg.Email.Sender, cfg.Email.ConfigurationSet)
+		if err != nil {
+			return fmt.Errorf("creating ses mailer: %w", err)
+		}
+	case "log":
+		mailer = services.LogMailer{}
+	default:
+		return fmt.Errorf("unknown email backend %q", cfg.Email.Backend)
+	}
+
+	var smsSender sms.Sender
+
+	switch cfg.SMS.Backend {
+	case "file":
+		smsSender, err = sms.NewFileSender(cfg.SMS.FilePath)
+		if err != nil {
+			return err
+		}
+	case "log":
+		smsSender = sms.LogSender{}
+	default:
+		return fmt.Errorf("unknown sms backend %q", cfg.SMS.Backend)
+	}
+
+	var photoStorage storage.Storage
+
+	switch cfg.Photos.Storage {
+	case "local":
+		photoStorage, err = storage.NewLocal(cfg.Photos.Dir, cfg.Photos.BaseURL, cfg.Photos.URLSecret)
+	case "s3":
+		photoStorage, err = storage.NewS3(storage.S3Config{
+			Bucket:    cfg.Photos.S3Bucket,
+			Region:    cfg.Photos.S3Region,
+			Endpoint:  cfg.Photos.S3Endpoint,
+			PathStyle: cfg.Photos.S3PathStyle,
+		})
+	default:
+		//nolint:goerr113
+		err = fmt.Errorf("unknown photo storage %q", cfg.Photos.Storage)
+	}
+
+	if err != nil {
+		return fmt.Errorf("creating photo storage: %w", err)
+	}
+
+	eventService, err := cars.NewService(repo, pymentService, cfg.Payments.WebHookAppKey, auditService, cars.Withdrawals{
+		Mailer: mailer,
+		SMS:    smsSender,
+		Cutoff: cfg.Listings.WithdrawalCutoff,
+	}, cars.Photos{
+		Storage: photoStorage,
+		URLTTL:  cfg.Photos.URLTTL,
+	}, cars.Watchlist{
+		Mailer:       mailer,
+		SMS:          smsSender,
+		EndingWithin: cfg.Watchlist.EndingWithin,
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Watchlist.SweepInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := eventService.NotifyWatchers(context.Background()); err != nil {
+				log.Printf("notifying watchers: %v", err)
+			}
+		}
+	}()
+
+	accountTokens, err := auth.NewAccountTokens(cfg.Auth.AccountTokenSecret)
+	if err != nil {
+		return err
+	}
+
+	authService, err := auth.NewService(repo, signer, cfg.Auth.RefreshTokenTTL, auth.LoginThrottle{
+		MaxAttempts: cfg.Auth.LoginMaxAttempts,
+		Lockout:     cfg.Auth.LoginLockout,
+	}, auth.AccountEmails{
+		Mailer:           mailer,
+		Tokens:           accountTokens,
+		AppBaseURL:       cfg.Email.AppBaseURL,
+		VerifyEmailTTL:   cfg.Auth.VerifyEmailTTL,
+		PasswordResetTTL: cfg.Auth.PasswordResetTTL,
+		MaxPerHour:       cfg.Auth.AccountEmailsLimit,
+	}, auth.PhoneOTPs{
+		Sender:             smsSender,
+		DefaultCountryCode: cfg.SMS.DefaultCountryCode,
+		TTL:                cfg.Auth.OTPTTL,
+		MaxAttempts:        cfg.Auth.OTPMaxAttempts,
+		MaxPerHour:         cfg.Auth.OTPPerHour,
+	}, auditService)
+	if err != nil {
+		return err
+	}
+
+	apiKeyService, err := apikeys.NewService(repo, auditService)
+	if err != nil {
+		return err
+	}
+
+	sellerService, err := sellers.NewService(repo, auditService)
+	if err != nil {
+		return err
+	}
+
+	searchService, err := searches.NewService(repo, searches.Alerts{
+		Mailer: mailer,
+		SMS:    smsSender,
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.SearchAlerts.SweepInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := searchService.SendAlerts(context.Background()); err != nil {
+				log.Printf("sending search alerts: %v", err)
+			}
+		}
+	}()
+
+	catalogService, err := catalog.NewService(repo, auditService)
+	if err != nil {
+		return err
+	}
+
+	vehicleService, err := vehicles.NewService(repo, auditService)
 	if err != nil {
 		return err
 	}
 
+	// The taxonomy is seeded on the first start after its migration, and the cars listed before it mapped onto it.
+	seeded, err := vehicleService.Seed(context.Background())
+	if err != nil {
+		return fmt.Errorf("seeding the vehicle taxonomy: %w", err)
+	}
+
+	if seeded {
+		remap, err := vehicleService.RemapCars(context.Background())
+		if err != nil {
+			return fmt.Errorf("mapping cars onto the vehicle taxonomy: %w", err)
+		}
+
+		log.Printf("vehicle taxonomy seeded: %d of %d cars mapped", remap.Remapped, remap.Checked)
+	}
+
+	limits, err := ratelimit.ParseLimits(cfg.RateLimit.Limits)
+	if err != nil {
+		return err
+	}
+
+	ipLimits, err := ratelimit.ParseLimits(cfg.RateLimit.IPLimits)
+	if err != nil {
+		return err
+	}
+
+	rateLimits := api.RateLimits{Limits: limits, IPLimits: ipLimits}
+
+	if cfg.RateLimit.TrustedProxies != "" {
+		rateLimits.TrustedProxies = strings.Split(cfg.RateLimit.TrustedProxies, ",")
+	}
+
+	switch cfg.RateLimit.Backend {
+	case "memory":
+		rateLimits.Store = ratelimit.NewMemoryStore()
+	case "postgres":
+		rateLimits.Store = ratelimit.NewPostgresStore(repo, ratelimit.LongestPeriod(limits, ipLimits))
+	case "off":
+	default:
+		//nolint:goerr113
+		return fmt.Errorf("unknown rate limit backend %q", cfg.RateLimit.Backend)
+	}
+
 	//nolintlint:funlen
-	listener, err := api.NewAPIListener(eventService, cfg.DisableAuthorization, cfg.AllowedOrigins)
+	listener, err := api.NewAPIListener(eventService, catalogService, vehicleService, authService, apiKeyService, auditService, sellerService, searchService, verifier, rateLimits, cfg.DisableAuthorization, cfg.AllowedOrigins)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,28 @@
+UPDATE "cars"
+SET "properties" = "properties" || jsonb_build_object(
+    'date_posted', to_char("date_posted" AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+    'bid_expiration_time', COALESCE(to_char("bid_expiration_time" AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), '')
+  );
+
+-- Values that could not be migrated are put back as they were. Databases migrated before 000004 recorded its issues
+-- may not have the table.
+DO $$
+BEGIN
+  IF to_regclass('"car_migration_issues"') IS NOT NULL THEN
+    UPDATE "cars"
+    SET "properties" = "properties" || jsonb_build_object("car_migration_issues"."field", "car_migration_issues"."value")
+    FROM "car_migration_issues"
+    WHERE "car_migration_issues"."car_id" = "cars"."id"
+      AND "car_migration_issues"."field" IN ('date_posted', 'bid_expiration_time');
+  END IF;
+END;
+$$;
+
+DROP TABLE IF EXISTS "car_migration_issues";
+
+DROP INDEX IF EXISTS "cars_bid_expiration_time_idx";
+DROP INDEX IF EXISTS "cars_date_posted_idx";
+
+ALTER TABLE "cars"
+  DROP COLUMN "bid_expiration_time",
+  DROP COLUMN "date_posted";
@@ -0,0 +1,60 @@
+ALTER TABLE "cars"
+  ADD COLUMN "date_posted" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ADD COLUMN "bid_expiration_time" TIMESTAMP WITH TIME ZONE;
+
+-- car_migration_issues lists the property values that could not be moved into the typed columns.
+CREATE TABLE
+  "car_migration_issues" (
+    "id" bigserial NOT NULL,
+    "car_id" uuid NOT NULL REFERENCES "cars" ("id") ON DELETE CASCADE,
+    "field" text NOT NULL,
+    "value" text NOT NULL,
+    "reason" text NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+-- Values without an explicit offset are interpreted in Africa/Douala. A well-formed value can still be out of range,
+-- e.g. 2024-13-45, so the cast is tried here and gives NULL instead of aborting the migration.
+CREATE FUNCTION pg_temp."car_timestamp"("value" text) RETURNS timestamptz AS $$
+BEGIN
+  IF "value" ~ '[0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?(Z|[+-][0-9]{2}(:?[0-9]{2})?)$' THEN
+    RETURN "value"::timestamptz;
+  END IF;
+
+  RETURN "value"::timestamp AT TIME ZONE 'Africa/Douala';
+EXCEPTION WHEN data_exception THEN
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Carry over the values that were kept as free-form strings in the properties blob. Only ISO 8601 dates are cast:
+-- Postgres also reads words like 'tomorrow' as timestamps.
+INSERT INTO "car_migration_issues" ("car_id", "field", "value", "reason")
+SELECT "id", "field", "value", CASE
+    WHEN "value" ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}([T ][0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?)?(Z|[+-][0-9]{2}(:?[0-9]{2})?)?$'
+      THEN 'not a valid date'
+    ELSE 'not an ISO 8601 date'
+  END
+FROM "cars", LATERAL (VALUES
+    ('date_posted', "properties"->>'date_posted'),
+    ('bid_expiration_time', "properties"->>'bid_expiration_time')
+  ) AS "dates" ("field", "value")
+WHERE trim(coalesce("value", '')) <> ''
+  AND ("value" !~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}([T ][0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?)?(Z|[+-][0-9]{2}(:?[0-9]{2})?)?$'
+    OR pg_temp."car_timestamp"("value") IS NULL);
+
+UPDATE "cars"
+SET "date_posted" = coalesce(pg_temp."car_timestamp"("properties"->>'date_posted'), "date_posted")
+WHERE "properties"->>'date_posted' ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}([T ][0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?)?(Z|[+-][0-9]{2}(:?[0-9]{2})?)?$';
+
+UPDATE "cars"
+SET "bid_expiration_time" = pg_temp."car_timestamp"("properties"->>'bid_expiration_time')
+WHERE "properties"->>'bid_expiration_time' ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}([T ][0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?)?(Z|[+-][0-9]{2}(:?[0-9]{2})?)?$';
+
+DROP FUNCTION pg_temp."car_timestamp";
+
+UPDATE "cars" SET "properties" = "properties" - 'id' - 'date_posted' - 'bid_expiration_time';
+
+CREATE INDEX "cars_date_posted_idx" ON "cars" ("date_posted" DESC);
+CREATE INDEX "cars_bid_expiration_time_idx" ON "cars" ("bid_expiration_time");
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS "users_user_email_key";
+
+ALTER TABLE "users"
+  ALTER COLUMN "user_id" DROP DEFAULT,
+  DROP COLUMN "created_at",
+  DROP COLUMN "locked_until",
+  DROP COLUMN "failed_login_attempts",
+  DROP COLUMN "password_hash";
@@ -0,0 +1,11 @@
+ALTER TABLE "users"
+  ALTER COLUMN "user_id" SET DEFAULT uuid_generate_v4 ()::text,
+  ADD COLUMN "password_hash" VARCHAR(255),
+  ADD COLUMN "failed_login_attempts" INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN "locked_until" TIMESTAMP WITH TIME ZONE,
+  ADD COLUMN "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE "users" SET "user_email" = lower(trim("user_email")) WHERE "user_email" IS NOT NULL;
+
+-- Fails when existing accounts share an email; those have to be merged by hand first.
+CREATE UNIQUE INDEX "users_user_email_key" ON "users" (lower("user_email"));
@@ -0,0 +1,28 @@
+CREATE TABLE
+  "sessions" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "user_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id") ON DELETE CASCADE,
+    "user_agent" TEXT,
+    "ip_address" VARCHAR(64),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "last_used_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "revoked_at" TIMESTAMP WITH TIME ZONE,
+    "revoked_reason" VARCHAR(64),
+    PRIMARY KEY ("id")
+  );
+
+CREATE INDEX "sessions_user_id_idx" ON "sessions" ("user_id");
+
+-- Refresh tokens are only stored as SHA-256 hashes. Every token of a session belongs to the same rotation family.
+CREATE TABLE
+  "refresh_tokens" (
+    "token_hash" CHAR(64) NOT NULL,
+    "session_id" uuid NOT NULL REFERENCES "sessions" ("id") ON DELETE CASCADE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "used_at" TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY ("token_hash")
+  );
+
+CREATE INDEX "refresh_tokens_session_id_idx" ON "refresh_tokens" ("session_id");
@@ -0,0 +1,6 @@
+ALTER TABLE "users"
+  ADD COLUMN "role" VARCHAR(16) NOT NULL DEFAULT 'buyer' CONSTRAINT "users_role_check" CHECK ("role" IN ('buyer', 'seller', 'admin'));
+
+-- Everyone who already listed a car keeps being able to do so.
+UPDATE "users" SET "role" = 'seller'
+WHERE "user_id" IN (SELECT DISTINCT "properties"->>'seller_id' FROM "cars");
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "bids_car_id_idx";
+DROP INDEX IF EXISTS "bids_user_id_idx";
+
+ALTER TABLE "bids" DROP COLUMN "user_id";
@@ -0,0 +1,9 @@
+ALTER TABLE "bids" ADD COLUMN "user_id" VARCHAR(255) REFERENCES "users" ("user_id") ON DELETE SET NULL;
+
+-- Bids used to be attributed by email only.
+UPDATE "bids" b SET "user_id" = u."user_id"
+FROM "users" u
+WHERE lower(u."user_email") = lower(b."email");
+
+CREATE INDEX "bids_user_id_idx" ON "bids" ("user_id");
+CREATE INDEX "bids_car_id_idx" ON "bids" ("car_id");
@@ -0,0 +1,28 @@
+CREATE TABLE
+  "organizations" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "name" VARCHAR(255) NOT NULL,
+    "owner_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id"),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("id")
+  );
+
+-- Only the SHA-256 hash of a key is stored. The prefix is part of the key and is used to look it up.
+CREATE TABLE
+  "api_keys" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "organization_id" uuid NOT NULL REFERENCES "organizations" ("id") ON DELETE CASCADE,
+    "name" VARCHAR(255) NOT NULL,
+    "prefix" VARCHAR(16) NOT NULL,
+    "key_hash" CHAR(64) NOT NULL,
+    "scopes" TEXT[] NOT NULL DEFAULT '{}',
+    "expires_at" TIMESTAMP WITH TIME ZONE,
+    "last_used_at" TIMESTAMP WITH TIME ZONE,
+    "created_by" VARCHAR(255) REFERENCES "users" ("user_id") ON DELETE SET NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "revoked_at" TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "api_keys_prefix_key" UNIQUE ("prefix")
+  );
+
+CREATE INDEX "api_keys_organization_id_idx" ON "api_keys" ("organization_id");
@@ -0,0 +1,15 @@
+ALTER TABLE "users" ADD COLUMN "email_verified_at" TIMESTAMP WITH TIME ZONE;
+
+-- Single-use tokens for email verification and password reset. The token itself is HMAC signed and never stored.
+CREATE TABLE
+  "account_tokens" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "user_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id") ON DELETE CASCADE,
+    "purpose" VARCHAR(32) NOT NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "used_at" TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY ("id")
+  );
+
+CREATE INDEX "account_tokens_user_id_purpose_idx" ON "account_tokens" ("user_id", "purpose", "created_at");
@@ -0,0 +1,14 @@
+-- Accounts created from a phone number have nothing else to log in with. They are not deleted here: give them an email
+-- address or delete them before rolling back.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM "users" WHERE "user_email" IS NULL AND "phone_number" IS NOT NULL) THEN
+    RAISE EXCEPTION 'users without an email address log in with their phone number and would be locked out';
+  END IF;
+END $$;
+
+DROP TABLE "phone_otps";
+
+ALTER TABLE "users"
+  DROP COLUMN "phone_verified_at",
+  DROP COLUMN "phone_number";
@@ -0,0 +1,20 @@
+-- Phone numbers are stored in E.164 form and only once they have been verified with a one-time code.
+ALTER TABLE "users"
+  ADD COLUMN "phone_number" VARCHAR(16),
+  ADD COLUMN "phone_verified_at" TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX "users_phone_number_key" ON "users" ("phone_number");
+
+CREATE TABLE
+  "phone_otps" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "phone_number" VARCHAR(16) NOT NULL,
+    "code_hash" CHAR(64) NOT NULL,
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "consumed_at" TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY ("id")
+  );
+
+CREATE INDEX "phone_otps_phone_number_idx" ON "phone_otps" ("phone_number", "created_at");
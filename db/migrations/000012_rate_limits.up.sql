@@ -0,0 +1,9 @@
+-- Token buckets shared by all replicas. Rows are deleted once idle, so the table stays small.
+CREATE UNLOGGED TABLE
+  "rate_limits" (
+    "key" VARCHAR(255) NOT NULL,
+    "tokens" DOUBLE PRECISION NOT NULL,
+    "allowed" BOOLEAN NOT NULL,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("key")
+  );
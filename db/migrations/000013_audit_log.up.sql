@@ -0,0 +1,34 @@
+CREATE TABLE
+  "audit_log" (
+    "id" BIGSERIAL NOT NULL,
+    "occurred_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "actor_id" VARCHAR(255),
+    "actor_role" VARCHAR(16),
+    "api_key_id" VARCHAR(255),
+    "ip_address" VARCHAR(64),
+    "request_id" VARCHAR(128),
+    "action" VARCHAR(64) NOT NULL,
+    "entity_type" VARCHAR(32) NOT NULL,
+    "entity_id" VARCHAR(255) NOT NULL,
+    "before" jsonb,
+    "after" jsonb,
+    "diff" jsonb NOT NULL DEFAULT '{}',
+    PRIMARY KEY ("id")
+  );
+
+CREATE INDEX "audit_log_entity_idx" ON "audit_log" ("entity_type", "entity_id", "occurred_at");
+
+CREATE INDEX "audit_log_actor_idx" ON "audit_log" ("actor_id", "occurred_at");
+
+-- The log is append-only: rows can not be changed or removed, not even by the application's own role.
+CREATE FUNCTION "audit_log_append_only" () RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$;
+
+CREATE TRIGGER "audit_log_no_update_or_delete" BEFORE UPDATE OR DELETE ON "audit_log"
+  FOR EACH ROW EXECUTE FUNCTION "audit_log_append_only" ();
+
+CREATE TRIGGER "audit_log_no_truncate" BEFORE TRUNCATE ON "audit_log"
+  FOR EACH STATEMENT EXECUTE FUNCTION "audit_log_append_only" ();
@@ -0,0 +1,5 @@
+DROP TABLE "kyc_documents";
+
+DROP TABLE "seller_verifications";
+
+ALTER TABLE "users" DROP COLUMN "seller_status";
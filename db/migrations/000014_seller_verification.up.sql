@@ -0,0 +1,39 @@
+ALTER TABLE "users"
+  ADD COLUMN "seller_status" VARCHAR(16) NOT NULL DEFAULT 'unverified'
+    CHECK ("seller_status" IN ('unverified', 'pending', 'verified', 'rejected'));
+
+-- A seller's request to be verified. Only one request per user can be pending at a time.
+CREATE TABLE
+  "seller_verifications" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "user_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id") ON DELETE CASCADE,
+    "status" VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK ("status" IN ('pending', 'approved', 'rejected')),
+    "submitted_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "reviewed_by" VARCHAR(255) REFERENCES "users" ("user_id") ON DELETE SET NULL,
+    "reviewed_at" TIMESTAMP WITH TIME ZONE,
+    "reason" TEXT,
+    PRIMARY KEY ("id")
+  );
+
+CREATE UNIQUE INDEX "seller_verifications_pending_key" ON "seller_verifications" ("user_id") WHERE "status" = 'pending';
+
+CREATE INDEX "seller_verifications_status_idx" ON "seller_verifications" ("status", "submitted_at");
+
+-- Identity and vehicle ownership documents. Documents are attached to a verification request when it is submitted.
+CREATE TABLE
+  "kyc_documents" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "user_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id") ON DELETE CASCADE,
+    "verification_id" uuid REFERENCES "seller_verifications" ("id") ON DELETE SET NULL,
+    "kind" VARCHAR(32) NOT NULL,
+    "file_name" VARCHAR(255) NOT NULL,
+    "content_type" VARCHAR(64) NOT NULL,
+    "size" INTEGER NOT NULL,
+    "content" bytea NOT NULL,
+    "uploaded_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("id")
+  );
+
+CREATE INDEX "kyc_documents_user_id_idx" ON "kyc_documents" ("user_id");
+
+CREATE INDEX "kyc_documents_verification_id_idx" ON "kyc_documents" ("verification_id");
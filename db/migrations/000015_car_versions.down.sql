@@ -0,0 +1 @@
+ALTER TABLE "cars" DROP COLUMN "version";
@@ -0,0 +1,2 @@
+-- version is bumped by every change to a car and by every bid on it, and is the car's ETag.
+ALTER TABLE "cars" ADD COLUMN "version" integer NOT NULL DEFAULT 1;
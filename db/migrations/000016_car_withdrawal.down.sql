@@ -0,0 +1,6 @@
+DROP INDEX "cars_listed_idx";
+
+ALTER TABLE "cars"
+  DROP COLUMN "withdrawal_reason",
+  DROP COLUMN "withdrawn_by",
+  DROP COLUMN "withdrawn_at";
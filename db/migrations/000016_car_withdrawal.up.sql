@@ -0,0 +1,7 @@
+-- Withdrawn cars are hidden from listings but kept with their bids.
+ALTER TABLE "cars"
+  ADD COLUMN "withdrawn_at" timestamptz,
+  ADD COLUMN "withdrawn_by" text,
+  ADD COLUMN "withdrawal_reason" text;
+
+CREATE INDEX "cars_listed_idx" ON "cars" ("date_posted" DESC) WHERE "withdrawn_at" IS NULL;
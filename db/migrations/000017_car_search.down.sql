@@ -0,0 +1,3 @@
+DROP INDEX "cars_search_idx";
+
+ALTER TABLE "cars" DROP COLUMN "search";
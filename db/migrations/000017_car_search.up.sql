@@ -0,0 +1,11 @@
+-- The simple configuration does not stem, so make and model names match as typed in French and English.
+ALTER TABLE "cars" ADD COLUMN "search" tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', coalesce("properties"->>'car_name', '')), 'A') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'car_model', '')), 'A') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'engine_type', '')), 'B') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'fuel_type', '')), 'B') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'city_id', '')), 'C') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'description', '')), 'D')
+) STORED;
+
+CREATE INDEX "cars_search_idx" ON "cars" USING GIN ("search");
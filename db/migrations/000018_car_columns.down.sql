@@ -0,0 +1,58 @@
+DROP TRIGGER "cars_search_vector" ON "cars";
+
+DROP FUNCTION "cars_search_vector";
+
+ALTER TABLE "cars" DROP COLUMN "search";
+
+ALTER TABLE "cars" ADD COLUMN "properties" jsonb NOT NULL DEFAULT '{}';
+
+UPDATE "cars" SET "properties" = "extras" || jsonb_strip_nulls(jsonb_build_object(
+  'seller_id', "seller_id",
+  'car_name', "car_name",
+  'car_model', "car_model",
+  'year', "year",
+  'engine_type', "engine_type",
+  'fuel_type', "fuel_type",
+  'mileage', "mileage"::text,
+  'biding_price', "biding_price"::text,
+  'city_id', "city_id",
+  'category', "category",
+  'photo_url', "photo_url",
+  'description', "description"
+));
+
+ALTER TABLE "cars" ALTER COLUMN "properties" DROP DEFAULT;
+
+-- The table belongs to 000004, which also records the dates it could not migrate.
+DELETE FROM "car_migration_issues" WHERE "field" NOT IN ('date_posted', 'bid_expiration_time');
+
+ALTER TABLE "cars"
+  DROP COLUMN "updated_at",
+  DROP COLUMN "extras",
+  DROP COLUMN "description",
+  DROP COLUMN "photo_url",
+  DROP COLUMN "category",
+  DROP COLUMN "city_id",
+  DROP COLUMN "biding_price",
+  DROP COLUMN "mileage",
+  DROP COLUMN "fuel_type",
+  DROP COLUMN "engine_type",
+  DROP COLUMN "year",
+  DROP COLUMN "car_model",
+  DROP COLUMN "car_name",
+  DROP COLUMN "seller_id";
+
+DROP TABLE "cities";
+
+DROP TYPE "fuel_type";
+
+ALTER TABLE "cars" ADD COLUMN "search" tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', coalesce("properties"->>'car_name', '')), 'A') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'car_model', '')), 'A') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'engine_type', '')), 'B') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'fuel_type', '')), 'B') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'city_id', '')), 'C') ||
+  setweight(to_tsvector('simple', coalesce("properties"->>'description', '')), 'D')
+) STORED;
+
+CREATE INDEX "cars_search_idx" ON "cars" USING GIN ("search");
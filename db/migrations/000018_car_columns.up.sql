@@ -0,0 +1,167 @@
+CREATE TYPE "fuel_type" AS ENUM ('petrol', 'diesel', 'hybrid', 'electric', 'lpg');
+
+CREATE TABLE
+  "cities" (
+    -- id is a lower case slug of the name.
+    "id" text NOT NULL,
+    "name" text NOT NULL,
+    "region" text NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+CREATE UNIQUE INDEX "cities_name_key" ON "cities" (lower("name"));
+
+INSERT INTO "cities" ("id", "name", "region") VALUES
+  ('douala', 'Douala', 'Littoral'),
+  ('yaounde', 'Yaoundé', 'Centre'),
+  ('bafoussam', 'Bafoussam', 'West'),
+  ('bamenda', 'Bamenda', 'North-West'),
+  ('garoua', 'Garoua', 'North'),
+  ('maroua', 'Maroua', 'Far North'),
+  ('ngaoundere', 'Ngaoundéré', 'Adamawa'),
+  ('bertoua', 'Bertoua', 'East'),
+  ('ebolowa', 'Ebolowa', 'South'),
+  ('kribi', 'Kribi', 'South'),
+  ('buea', 'Buea', 'South-West'),
+  ('limbe', 'Limbe', 'South-West');
+
+-- Cities that listings refer to but that are not known yet are kept under their own name.
+INSERT INTO "cities" ("id", "name")
+SELECT DISTINCT ON (lower(trim("city"))) lower(regexp_replace(trim("city"), '\s+', '-', 'g')), trim("city")
+FROM (SELECT "properties"->>'city_id' AS "city" FROM "cars") AS "listed"
+WHERE trim(coalesce("city", '')) <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM "cities"
+    WHERE "cities"."id" = lower(regexp_replace(trim("city"), '\s+', '-', 'g')) OR lower("cities"."name") = lower(trim("city"))
+  )
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE "cars"
+  ADD COLUMN "seller_id" varchar(255) REFERENCES "users" ("user_id") ON DELETE SET NULL,
+  ADD COLUMN "car_name" text NOT NULL DEFAULT '',
+  ADD COLUMN "car_model" text NOT NULL DEFAULT '',
+  ADD COLUMN "year" smallint CHECK ("year" BETWEEN 1900 AND 2100),
+  ADD COLUMN "engine_type" text NOT NULL DEFAULT '',
+  ADD COLUMN "fuel_type" "fuel_type",
+  ADD COLUMN "mileage" integer CHECK ("mileage" >= 0),
+  ADD COLUMN "biding_price" bigint CHECK ("biding_price" >= 0),
+  ADD COLUMN "city_id" text REFERENCES "cities" ("id"),
+  ADD COLUMN "category" text NOT NULL DEFAULT '',
+  ADD COLUMN "photo_url" text NOT NULL DEFAULT '',
+  ADD COLUMN "description" text NOT NULL DEFAULT '',
+  -- extras holds open-ended attributes that have no column.
+  ADD COLUMN "extras" jsonb NOT NULL DEFAULT '{}',
+  ADD COLUMN "updated_at" timestamptz NOT NULL DEFAULT now();
+
+-- car_migration_issues lists the property values that could not be moved into the typed columns. Databases migrated
+-- before 000004 recorded its issues do not have it yet.
+CREATE TABLE IF NOT EXISTS
+  "car_migration_issues" (
+    "id" bigserial NOT NULL,
+    "car_id" uuid NOT NULL REFERENCES "cars" ("id") ON DELETE CASCADE,
+    "field" text NOT NULL,
+    "value" text NOT NULL,
+    "reason" text NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+-- Numbers were typed by hand: strip units, spaces and thousands separators before casting.
+WITH "normalized" AS (
+  SELECT
+    "id",
+    NULLIF(trim("properties"->>'seller_id'), '') AS "seller",
+    regexp_replace(lower(coalesce("properties"->>'mileage', '')), '\s|,|kms?', '', 'g') AS "mileage",
+    regexp_replace(lower(coalesce("properties"->>'biding_price', '')), '\s|,|fcfa|xaf|cfa', '', 'g') AS "price",
+    trim(coalesce("properties"->>'year', '')) AS "year",
+    lower(trim(coalesce("properties"->>'fuel_type', ''))) AS "fuel",
+    lower(trim(coalesce("properties"->>'city_id', ''))) AS "city"
+  FROM "cars"
+)
+UPDATE "cars" SET
+  "seller_id" = (SELECT "user_id" FROM "users" WHERE "user_id" = "normalized"."seller"),
+  "car_name" = coalesce("properties"->>'car_name', ''),
+  "car_model" = coalesce("properties"->>'car_model', ''),
+  "engine_type" = coalesce("properties"->>'engine_type', ''),
+  "category" = coalesce("properties"->>'category', ''),
+  "photo_url" = coalesce("properties"->>'photo_url', ''),
+  "description" = coalesce("properties"->>'description', ''),
+  "year" = CASE WHEN "normalized"."year" ~ '^\d{4}$' AND "normalized"."year"::integer BETWEEN 1900 AND 2100
+    THEN "normalized"."year"::smallint END,
+  "mileage" = CASE
+    WHEN "normalized"."mileage" ~ '^\d{1,9}$' THEN "normalized"."mileage"::integer
+    WHEN "normalized"."mileage" ~ '^\d{1,3}(\.\d{3}){1,2}$' THEN replace("normalized"."mileage", '.', '')::integer
+  END,
+  "biding_price" = CASE
+    WHEN "normalized"."price" ~ '^\d{1,15}$' THEN "normalized"."price"::bigint
+    WHEN "normalized"."price" ~ '^\d{1,3}(\.\d{3}){1,4}$' THEN replace("normalized"."price", '.', '')::bigint
+  END,
+  "fuel_type" = CASE
+    WHEN "normalized"."fuel" IN ('petrol', 'gasoline', 'gas', 'essence', 'super') THEN 'petrol'
+    WHEN "normalized"."fuel" IN ('diesel', 'gasoil', 'gazole') THEN 'diesel'
+    WHEN "normalized"."fuel" IN ('hybrid', 'hybride') THEN 'hybrid'
+    WHEN "normalized"."fuel" IN ('electric', 'electrique', 'électrique', 'ev') THEN 'electric'
+    WHEN "normalized"."fuel" IN ('lpg', 'gpl') THEN 'lpg'
+  END::"fuel_type",
+  "city_id" = (
+    SELECT "cities"."id" FROM "cities"
+    WHERE "cities"."id" = regexp_replace("normalized"."city", '\s+', '-', 'g') OR lower("cities"."name") = "normalized"."city"
+    LIMIT 1
+  ),
+  "extras" = "properties" - ARRAY[
+    'id', 'seller_id', 'car_name', 'date_posted', 'biding_price', 'bid_expiration_time', 'city_id', 'engine_type',
+    'car_model', 'number_of_bids', 'mileage', 'fuel_type', 'photo_url', 'category', 'description', 'year', 'version',
+    'withdrawn_at', 'withdrawn_by', 'withdrawal_reason', 'updated_at', 'extras'
+  ]
+FROM "normalized"
+WHERE "normalized"."id" = "cars"."id";
+
+INSERT INTO "car_migration_issues" ("car_id", "field", "value", "reason")
+SELECT "id", 'seller_id', "properties"->>'seller_id', 'no user has this id'
+FROM "cars" WHERE "seller_id" IS NULL AND trim(coalesce("properties"->>'seller_id', '')) <> ''
+UNION ALL
+SELECT "id", 'mileage', "properties"->>'mileage', 'not a whole number of kilometres'
+FROM "cars" WHERE "mileage" IS NULL AND trim(coalesce("properties"->>'mileage', '')) <> ''
+UNION ALL
+SELECT "id", 'biding_price', "properties"->>'biding_price', 'not a whole amount of francs'
+FROM "cars" WHERE "biding_price" IS NULL AND trim(coalesce("properties"->>'biding_price', '')) <> ''
+UNION ALL
+SELECT "id", 'year', "properties"->>'year', 'not a year between 1900 and 2100'
+FROM "cars" WHERE "year" IS NULL AND trim(coalesce("properties"->>'year', '')) <> ''
+UNION ALL
+SELECT "id", 'fuel_type', "properties"->>'fuel_type', 'unknown fuel type'
+FROM "cars" WHERE "fuel_type" IS NULL AND trim(coalesce("properties"->>'fuel_type', '')) <> '';
+
+-- The search vector can not be generated from the fuel_type enum, so it is kept up to date by a trigger.
+ALTER TABLE "cars" DROP COLUMN "search";
+
+ALTER TABLE "cars" DROP COLUMN "properties";
+
+ALTER TABLE "cars" ADD COLUMN "search" tsvector NOT NULL DEFAULT '';
+
+CREATE FUNCTION "cars_search_vector"() RETURNS trigger AS $$
+BEGIN
+  NEW."search" :=
+    setweight(to_tsvector('simple', NEW."car_name"), 'A') ||
+    setweight(to_tsvector('simple', NEW."car_model"), 'A') ||
+    setweight(to_tsvector('simple', NEW."engine_type"), 'B') ||
+    setweight(to_tsvector('simple', coalesce(NEW."fuel_type"::text, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(NEW."city_id", '')), 'C') ||
+    setweight(to_tsvector('simple', NEW."description"), 'D');
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "cars_search_vector"
+BEFORE INSERT OR UPDATE ON "cars"
+FOR EACH ROW EXECUTE FUNCTION "cars_search_vector"();
+
+UPDATE "cars" SET "search" = DEFAULT;
+
+CREATE INDEX "cars_search_idx" ON "cars" USING GIN ("search");
+CREATE INDEX "cars_seller_id_idx" ON "cars" ("seller_id");
+CREATE INDEX "cars_city_id_idx" ON "cars" ("city_id");
+CREATE INDEX "cars_biding_price_idx" ON "cars" ("biding_price");
+CREATE INDEX "cars_mileage_idx" ON "cars" ("mileage");
+CREATE INDEX "cars_year_idx" ON "cars" ("year");
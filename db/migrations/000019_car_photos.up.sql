@@ -0,0 +1,20 @@
+CREATE TABLE
+  "car_photos" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "car_id" uuid NOT NULL REFERENCES "cars" ("id") ON DELETE CASCADE,
+    "position" integer NOT NULL,
+    "is_primary" boolean NOT NULL DEFAULT false,
+    "content_type" text NOT NULL,
+    "width" integer NOT NULL,
+    "height" integer NOT NULL,
+    "size" integer NOT NULL,
+    "storage_key" text NOT NULL,
+    "thumbnail_key" text NOT NULL,
+    "uploaded_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+CREATE INDEX "car_photos_car_id_idx" ON "car_photos" ("car_id", "position");
+
+-- A car has at most one primary photo.
+CREATE UNIQUE INDEX "car_photos_primary_key" ON "car_photos" ("car_id") WHERE "is_primary";
@@ -0,0 +1,20 @@
+CREATE OR REPLACE FUNCTION "cars_search_vector"() RETURNS trigger AS $$
+BEGIN
+  NEW."search" :=
+    setweight(to_tsvector('simple', NEW."car_name"), 'A') ||
+    setweight(to_tsvector('simple', NEW."car_model"), 'A') ||
+    setweight(to_tsvector('simple', NEW."engine_type"), 'B') ||
+    setweight(to_tsvector('simple', coalesce(NEW."fuel_type"::text, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(NEW."city_id", '')), 'C') ||
+    setweight(to_tsvector('simple', NEW."description"), 'D');
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX "cars_active_vin_key";
+
+ALTER TABLE "cars"
+  DROP COLUMN "make",
+  DROP COLUMN "vin";
+
+UPDATE "cars" SET "search" = DEFAULT;
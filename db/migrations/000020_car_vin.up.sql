@@ -0,0 +1,21 @@
+ALTER TABLE "cars"
+  ADD COLUMN "vin" varchar(17) CHECK ("vin" ~ '^[A-HJ-NPR-Z0-9]{17}$'),
+  ADD COLUMN "make" text NOT NULL DEFAULT '';
+
+-- A VIN can only be listed once at a time. Withdrawn listings keep their VIN.
+CREATE UNIQUE INDEX "cars_active_vin_key" ON "cars" ("vin") WHERE "withdrawn_at" IS NULL;
+
+CREATE OR REPLACE FUNCTION "cars_search_vector"() RETURNS trigger AS $$
+BEGIN
+  NEW."search" :=
+    setweight(to_tsvector('simple', coalesce(NEW."vin", '')), 'A') ||
+    setweight(to_tsvector('simple', NEW."make"), 'A') ||
+    setweight(to_tsvector('simple', NEW."car_name"), 'A') ||
+    setweight(to_tsvector('simple', NEW."car_model"), 'A') ||
+    setweight(to_tsvector('simple', NEW."engine_type"), 'B') ||
+    setweight(to_tsvector('simple', coalesce(NEW."fuel_type"::text, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(NEW."city_id", '')), 'C') ||
+    setweight(to_tsvector('simple', NEW."description"), 'D');
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,11 @@
+DROP INDEX "cars_category_idx";
+
+ALTER TABLE "cars" DROP CONSTRAINT "cars_category_fkey";
+
+UPDATE "cars" SET "category" = '' WHERE "category" IS NULL;
+
+ALTER TABLE "cars"
+  ALTER COLUMN "category" SET DEFAULT '',
+  ALTER COLUMN "category" SET NOT NULL;
+
+DROP TABLE "categories";
@@ -0,0 +1,54 @@
+CREATE TABLE
+  "categories" (
+    -- id is a lower case slug of the name.
+    "id" text NOT NULL,
+    "name" text NOT NULL,
+    "description" text NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+CREATE UNIQUE INDEX "categories_name_key" ON "categories" (lower("name"));
+
+INSERT INTO "categories" ("id", "name") VALUES
+  ('sedan', 'Sedan'),
+  ('hatchback', 'Hatchback'),
+  ('suv', 'SUV'),
+  ('pickup', 'Pickup'),
+  ('station-wagon', 'Station wagon'),
+  ('coupe', 'Coupe'),
+  ('convertible', 'Convertible'),
+  ('minivan', 'Minivan'),
+  ('van', 'Van'),
+  ('truck', 'Truck'),
+  ('bus', 'Bus'),
+  ('motorcycle', 'Motorcycle');
+
+-- Categories that listings use but that are not known yet are kept under their own name.
+INSERT INTO "categories" ("id", "name")
+SELECT DISTINCT ON (lower(trim("category"))) trim(regexp_replace(lower(trim("category")), '[^a-z0-9]+', '-', 'g'), '-'),
+  trim("category")
+FROM "cars"
+WHERE trim(regexp_replace(lower(trim("category")), '[^a-z0-9]+', '-', 'g'), '-') <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM "categories"
+    WHERE "categories"."id" = trim(regexp_replace(lower(trim("cars"."category")), '[^a-z0-9]+', '-', 'g'), '-')
+      OR lower("categories"."name") = lower(trim("cars"."category"))
+  )
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE "cars"
+  ALTER COLUMN "category" DROP NOT NULL,
+  ALTER COLUMN "category" DROP DEFAULT;
+
+UPDATE "cars" SET "category" = (
+  SELECT "categories"."id" FROM "categories"
+  WHERE "categories"."id" = trim(regexp_replace(lower(trim("cars"."category")), '[^a-z0-9]+', '-', 'g'), '-')
+    OR lower("categories"."name") = lower(trim("cars"."category"))
+  ORDER BY "categories"."id" = trim(regexp_replace(lower(trim("cars"."category")), '[^a-z0-9]+', '-', 'g'), '-') DESC
+  LIMIT 1
+);
+
+ALTER TABLE "cars" ADD CONSTRAINT "cars_category_fkey" FOREIGN KEY ("category") REFERENCES "categories" ("id");
+
+CREATE INDEX "cars_category_idx" ON "cars" ("category");
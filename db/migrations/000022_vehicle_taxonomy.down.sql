@@ -0,0 +1,17 @@
+DROP INDEX "cars_model_id_idx";
+
+DROP INDEX "cars_make_id_idx";
+
+ALTER TABLE "cars"
+  DROP COLUMN "trim_id",
+  DROP COLUMN "model_id",
+  DROP COLUMN "make_id",
+  DROP COLUMN "trim";
+
+DROP TABLE "vehicle_aliases";
+
+DROP TABLE "vehicle_trims";
+
+DROP TABLE "vehicle_models";
+
+DROP TABLE "vehicle_makes";
@@ -0,0 +1,52 @@
+-- The taxonomy is seeded by the api from its embedded dataset the first time it starts with empty tables.
+CREATE TABLE
+  "vehicle_makes" (
+    "id" text NOT NULL,
+    "name" text NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+CREATE UNIQUE INDEX "vehicle_makes_name_key" ON "vehicle_makes" (lower("name"));
+
+CREATE TABLE
+  "vehicle_models" (
+    "id" text NOT NULL,
+    "make_id" text NOT NULL REFERENCES "vehicle_makes" ("id"),
+    "name" text NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+CREATE UNIQUE INDEX "vehicle_models_name_key" ON "vehicle_models" ("make_id", lower("name"));
+
+CREATE TABLE
+  "vehicle_trims" (
+    "id" text NOT NULL,
+    "model_id" text NOT NULL REFERENCES "vehicle_models" ("id"),
+    "name" text NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+CREATE UNIQUE INDEX "vehicle_trims_name_key" ON "vehicle_trims" ("model_id", lower("name"));
+
+-- vehicle_aliases keeps the names of merged entries so that listings using them still match.
+CREATE TABLE
+  "vehicle_aliases" (
+    "kind" text NOT NULL CHECK ("kind" IN ('make', 'model', 'trim')),
+    "target_id" text NOT NULL,
+    "name" text NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("kind", "target_id", "name")
+  );
+
+ALTER TABLE "cars"
+  ADD COLUMN "trim" text NOT NULL DEFAULT '',
+  ADD COLUMN "make_id" text REFERENCES "vehicle_makes" ("id"),
+  ADD COLUMN "model_id" text REFERENCES "vehicle_models" ("id"),
+  ADD COLUMN "trim_id" text REFERENCES "vehicle_trims" ("id");
+
+CREATE INDEX "cars_make_id_idx" ON "cars" ("make_id");
+
+CREATE INDEX "cars_model_id_idx" ON "cars" ("model_id");
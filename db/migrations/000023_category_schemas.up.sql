@@ -0,0 +1,34 @@
+-- attribute_schema is a JSON Schema that the extras of the cars in the category must match.
+ALTER TABLE "categories" ADD COLUMN "attribute_schema" jsonb;
+
+UPDATE "categories" SET "attribute_schema" = '{
+  "type": "object",
+  "properties": {
+    "seats": {"type": "integer", "minimum": 1, "maximum": 9, "title": "Seats"},
+    "doors": {"type": "integer", "minimum": 2, "maximum": 5, "title": "Doors"}
+  }
+}' WHERE "id" IN ('sedan', 'hatchback', 'suv', 'station-wagon', 'coupe', 'convertible', 'minivan');
+
+UPDATE "categories" SET "attribute_schema" = '{
+  "type": "object",
+  "properties": {
+    "axles": {"type": "integer", "minimum": 2, "maximum": 10, "title": "Axles"},
+    "payload_kg": {"type": "integer", "minimum": 0, "title": "Payload (kg)"},
+    "seats": {"type": "integer", "minimum": 1, "maximum": 9, "title": "Seats"}
+  }
+}' WHERE "id" IN ('pickup', 'truck', 'van');
+
+UPDATE "categories" SET "attribute_schema" = '{
+  "type": "object",
+  "properties": {
+    "seats": {"type": "integer", "minimum": 8, "maximum": 100, "title": "Seats"},
+    "axles": {"type": "integer", "minimum": 2, "maximum": 4, "title": "Axles"}
+  }
+}' WHERE "id" = 'bus';
+
+UPDATE "categories" SET "attribute_schema" = '{
+  "type": "object",
+  "properties": {
+    "engine_cc": {"type": "integer", "minimum": 50, "maximum": 2500, "title": "Engine (cc)"}
+  }
+}' WHERE "id" = 'motorcycle';
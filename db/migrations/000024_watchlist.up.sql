@@ -0,0 +1,13 @@
+CREATE TABLE
+  "watchlist" (
+    "user_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id") ON DELETE CASCADE,
+    "car_id" uuid NOT NULL REFERENCES "cars" ("id") ON DELETE CASCADE,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    -- ending_notified_for and closed_for are the deadlines the watcher was last told the auction is ending and has
+    -- ended at, so that an auction extended after a notice is announced again.
+    "ending_notified_for" timestamptz,
+    "closed_for" timestamptz,
+    PRIMARY KEY ("user_id", "car_id")
+  );
+
+CREATE INDEX "watchlist_car_id_idx" ON "watchlist" ("car_id");
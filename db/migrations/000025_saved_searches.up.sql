@@ -0,0 +1,43 @@
+-- The filters of saved searches have the columns and the normalization of models.CarFilter, so that new listings are
+-- matched with the same conditions as GET /cars.
+CREATE TABLE
+  "saved_searches" (
+    "id" uuid NOT NULL DEFAULT uuid_generate_v4 (),
+    "user_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id") ON DELETE CASCADE,
+    "name" text NOT NULL,
+    "frequency" text NOT NULL DEFAULT 'instant' CHECK ("frequency" IN ('instant', 'daily')),
+    "city_id" text NOT NULL DEFAULT '',
+    "category" text NOT NULL DEFAULT '',
+    "min_price" bigint,
+    "max_price" bigint,
+    "min_mileage" bigint,
+    "max_mileage" bigint,
+    "min_year" bigint,
+    "max_year" bigint,
+    "fuel_types" text[] NOT NULL DEFAULT '{}',
+    "engine_types" text[] NOT NULL DEFAULT '{}',
+    "models" text[] NOT NULL DEFAULT '{}',
+    "statuses" text[] NOT NULL DEFAULT '{}',
+    "vin" text NOT NULL DEFAULT '',
+    "make_id" text NOT NULL DEFAULT '',
+    "model_id" text NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    "updated_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+  );
+
+CREATE UNIQUE INDEX "saved_searches_name_key" ON "saved_searches" ("user_id", lower("name"));
+
+-- A car is announced once to each user, whichever of their searches it matches.
+CREATE TABLE
+  "search_alerts" (
+    "user_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id") ON DELETE CASCADE,
+    "car_id" uuid NOT NULL REFERENCES "cars" ("id") ON DELETE CASCADE,
+    "search_id" uuid NOT NULL REFERENCES "saved_searches" ("id") ON DELETE CASCADE,
+    "frequency" text NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    "sent_at" timestamptz,
+    PRIMARY KEY ("user_id", "car_id")
+  );
+
+CREATE INDEX "search_alerts_pending_idx" ON "search_alerts" ("frequency", "user_id", "created_at") WHERE "sent_at" IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX "cars_vin_idx";
+
+CREATE UNIQUE INDEX "cars_active_vin_key" ON "cars" ("vin") WHERE "withdrawn_at" IS NULL;
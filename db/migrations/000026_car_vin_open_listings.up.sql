@@ -0,0 +1,6 @@
+-- A VIN can only be listed once at a time, but ended auctions must not hold it forever. Whether an auction has ended
+-- depends on the time, which an index predicate can not use, so the repository checks open listings under an advisory
+-- lock on the VIN instead.
+DROP INDEX "cars_active_vin_key";
+
+CREATE INDEX "cars_vin_idx" ON "cars" ("vin") WHERE "withdrawn_at" IS NULL;
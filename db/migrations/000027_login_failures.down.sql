@@ -0,0 +1,5 @@
+ALTER TABLE "users"
+  ADD COLUMN "failed_login_attempts" INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN "locked_until" TIMESTAMP WITH TIME ZONE;
+
+DROP TABLE "login_failures";
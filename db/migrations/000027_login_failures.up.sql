@@ -0,0 +1,15 @@
+-- Failed logins are counted per account and client, so that guessing the password of an account from one address does
+-- not lock its owner out everywhere else.
+CREATE TABLE
+  "login_failures" (
+    "user_id" VARCHAR(255) NOT NULL REFERENCES "users" ("user_id") ON DELETE CASCADE,
+    "ip_address" VARCHAR(64) NOT NULL,
+    "failed_attempts" INTEGER NOT NULL DEFAULT 0,
+    "locked_until" TIMESTAMP WITH TIME ZONE,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("user_id", "ip_address")
+  );
+
+ALTER TABLE "users"
+  DROP COLUMN "locked_until",
+  DROP COLUMN "failed_login_attempts";
@@ -1,69 +1,195 @@
 package api
 
 import (
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
 	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/apikeys"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/audit"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/auth"
 	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/catalog"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/searches"
+	sellerservice "github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/sellers"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/vehicles"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxSchemaSize   = 64 << 10
 )
 
 //nolint:gocyclo, funlen
-func NewAPIListener(carService cars.Service, disableAuthorization bool, allowedOrigins string) (*gin.Engine, error) {
+func NewAPIListener(carService cars.Service, catalogService catalog.Service, vehicleService vehicles.Service, authService auth.Service, apiKeyService apikeys.Service, auditService audit.Service, sellerService sellerservice.Service, searchService searches.Service, verifier auth.TokenVerifier, rateLimits RateLimits, disableAuthorization bool, allowedOrigins string) (*gin.Engine, error) {
 	router := gin.Default()
+
+	// SetTrustedProxies(nil) makes gin ignore X-Forwarded-For, which it otherwise believes from any client.
+	if err := router.SetTrustedProxies(rateLimits.TrustedProxies); err != nil {
+		return nil, err
+	}
+
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{allowedOrigins}
-	config.AllowHeaders = []string{"Origin", "authorization", "content-type"}
+	config.AllowHeaders = []string{"Origin", "authorization", "content-type", "x-api-key", "x-request-id", "if-match"}
+	config.ExposeHeaders = []string{"ETag", "X-Request-ID", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"}
 	config.AllowCredentials = true
 
 	router.Use(cors.New(config))
 
-	if !disableAuthorization {
-		router.Use(AuthorizeRequest)
+	router.Use(RequestID())
+
+	// Requests are limited by IP before their credentials are checked, so that guessing passwords, tokens and API keys
+	// is limited too, and then by user or API key.
+	if rateLimits.Store != nil {
+		router.Use(RateLimitClients(rateLimits.Store, rateLimits.IPLimits))
+	}
+
+	router.Use(AuthorizeRequest(verifier, authService, apiKeyService, !disableAuthorization))
+
+	if rateLimits.Store != nil {
+		router.Use(RateLimitCallers(rateLimits.Store, rateLimits.Limits))
 	}
 
 	// get all cars
 	router.GET("/cars", func(ctx *gin.Context) {
-		// params
-		cityID := ctx.Query("city_id")
-		categoryID := ctx.Query("category_id")
-
-		// convert params types
-		startKey, err := strconv.ParseUint(ctx.Query("start_key"), 10, 64)
+		filter, err := carFilter(ctx)
 		if err != nil {
 			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "invalide start key" + err.Error(),
+				Error: err.Error(),
+			})
+			return
+		}
+
+		cars, err := carService.GetAllCars(ctx, filter)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
 			})
 			return
 		}
-		//nolint
-		count, err := strconv.ParseUint(ctx.Query("count"), 10, 64)
+		ctx.JSON(http.StatusOK, cars)
+	})
+
+	// search cars.
+	router.GET("/cars/search", func(ctx *gin.Context) {
+		filter, err := carFilter(ctx)
 		if err != nil {
 			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "invalide  count" + err.Error(),
+				Error: err.Error(),
+			})
+			return
+		}
+
+		results, err := carService.SearchCars(ctx, ctx.Query("q"), filter)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
 			})
 			return
 		}
 
-		if count == 0 {
+		ctx.JSON(http.StatusOK, results)
+	})
+	router.GET("/cars/:id/bids", func(ctx *gin.Context) {
+		page, err := pageRequest(ctx)
+		if err != nil {
 			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "count must be greater Zero!",
+				Error: err.Error(),
 			})
 			return
 		}
 
-		cars, err := carService.GetAllCars(ctx, cityID, categoryID, uint(startKey), uint(count))
+		bids, err := carService.ListCarBids(ctx, ctx.Param("id"), page)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
 				Error: err.Error(),
 			})
 			return
 		}
-		ctx.JSON(http.StatusOK, cars)
+
+		ctx.JSON(http.StatusOK, bids)
+	})
+
+	// decode a VIN to prefill a listing.
+	router.GET("/vins/:vin", func(ctx *gin.Context) {
+		info, err := carService.DecodeVIN(ctx, ctx.Param("vin"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, info)
+	})
+
+	// list cities with their number of open auctions.
+	router.GET("/cities", func(ctx *gin.Context) {
+		cities, err := catalogService.ListCities(ctx)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, cities)
+	})
+
+	// list categories with their number of open auctions.
+	router.GET("/categories", func(ctx *gin.Context) {
+		categories, err := catalogService.ListCategories(ctx)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, categories)
+	})
+
+	// get a category with the schema of its attributes.
+	router.GET("/categories/:id", func(ctx *gin.Context) {
+		category, err := catalogService.GetCategory(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, category)
+	})
+
+	// suggest makes, models of a make or trims of a model for the listing form.
+	router.GET("/vehicles/autocomplete", func(ctx *gin.Context) {
+		suggestions, err := vehicleService.Autocomplete(ctx, models.VehicleQuery{
+			Query:   ctx.Query("q"),
+			MakeID:  ctx.Query("make_id"),
+			ModelID: ctx.Query("model_id"),
+		})
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, suggestions)
 	})
+
 	// get car by id.
 	router.GET("/cars/:id", func(ctx *gin.Context) {
 		carID := ctx.Param("id")
@@ -76,14 +202,199 @@ func NewAPIListener(carService cars.Service, disableAuthorization bool, allowedO
 		}
 		car, err := carService.GetCarsByID(ctx, carID)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+		ctx.Header("ETag", car.ETag())
+		ctx.JSON(http.StatusOK, car)
+	})
+
+	// update a car with a JSON merge patch.
+	router.PATCH("/cars/:id", func(ctx *gin.Context) {
+		if !isMergePatch(ctx.ContentType()) {
+			ctx.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+				Error: "car updates must be sent as " + mergePatchContentType,
+			})
+			return
+		}
+
+		version, err := ifMatchVersion(ctx.GetHeader("If-Match"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		patch, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPatchSize))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid merge patch: " + err.Error(),
+			})
+			return
+		}
+
+		car, err := carService.PatchCar(ctx, ctx.Param("id"), patch, version)
+		if err != nil {
+			ctx.JSON(errorStatus(err), errorResponse(err))
+			return
+		}
+
+		ctx.Header("ETag", car.ETag())
+		ctx.JSON(http.StatusOK, car)
+	})
+
+	// withdraw a car.
+	router.DELETE("/cars/:id", func(ctx *gin.Context) {
+		var req models.WithdrawCarRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid withdrawal: " + err.Error(),
+			})
+			return
+		}
+
+		car, err := carService.WithdrawCar(ctx, ctx.Param("id"), req.Reason)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
 				Error: err.Error(),
 			})
 			return
 		}
+
+		ctx.Header("ETag", car.ETag())
 		ctx.JSON(http.StatusOK, car)
 	})
 
+	// upload photos of a car.
+	router.POST("/cars/:id/photos", func(ctx *gin.Context) {
+		// Leave room for the multipart envelope and the other form fields.
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, models.MaxPhotosPerUpload*models.MaxPhotoSize+1<<20)
+
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "photos must be uploaded in the photos form field: " + err.Error(),
+			})
+			return
+		}
+
+		files := form.File["photos"]
+		if len(files) > models.MaxPhotosPerUpload {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "at most " + strconv.Itoa(models.MaxPhotosPerUpload) + " photos can be uploaded at once",
+			})
+			return
+		}
+
+		photos := make([]io.Reader, 0, len(files))
+
+		for _, file := range files {
+			if file.Size > models.MaxPhotoSize {
+				ctx.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+					Error: models.ErrPhotoTooLarge.Error(),
+				})
+				return
+			}
+
+			content, err := file.Open()
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error: "invalid photo upload: " + err.Error(),
+				})
+				return
+			}
+			defer content.Close()
+
+			photos = append(photos, content)
+		}
+
+		created, err := carService.UploadPhotos(ctx, ctx.Param("id"), photos, ctx.PostForm("primary") == "true")
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, created)
+	})
+
+	router.GET("/cars/:id/photos", func(ctx *gin.Context) {
+		photos, err := carService.ListPhotos(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, photos)
+	})
+
+	router.PUT("/cars/:id/photos/order", func(ctx *gin.Context) {
+		var req models.ReorderPhotosRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid photo order: " + err.Error(),
+			})
+			return
+		}
+
+		photos, err := carService.ReorderPhotos(ctx, ctx.Param("id"), req.PhotoIDs)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, photos)
+	})
+
+	router.POST("/cars/:id/photos/:photo_id/primary", func(ctx *gin.Context) {
+		photo, err := carService.SetPrimaryPhoto(ctx, ctx.Param("id"), ctx.Param("photo_id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, photo)
+	})
+
+	router.DELETE("/cars/:id/photos/:photo_id", func(ctx *gin.Context) {
+		if err := carService.DeletePhoto(ctx, ctx.Param("id"), ctx.Param("photo_id")); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	})
+
+	// serve photos through the signed URLs of local storage.
+	router.GET("/photos/*key", func(ctx *gin.Context) {
+		key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+		content, contentType, err := carService.OpenPhoto(ctx, key, ctx.Query("expires"), ctx.Query("signature"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Header("X-Content-Type-Options", "nosniff")
+		ctx.Data(http.StatusOK, contentType, content)
+	})
+
 	// register new car.
 	router.POST("/register/car", func(ctx *gin.Context) {
 		var newCar models.Cars
@@ -98,9 +409,7 @@ func NewAPIListener(carService cars.Service, disableAuthorization bool, allowedO
 		car, err := carService.RegisterCar(ctx, newCar)
 
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error: err.Error(),
-			})
+			ctx.JSON(errorStatus(err), errorResponse(err))
 			return
 		}
 
@@ -120,7 +429,7 @@ func NewAPIListener(carService cars.Service, disableAuthorization bool, allowedO
 
 		car, err := carService.PlaceBid(ctx, bids)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
 				Error: err.Error(),
 			})
 			return
@@ -130,79 +439,959 @@ func NewAPIListener(carService cars.Service, disableAuthorization bool, allowedO
 
 	})
 
-	router.POST("/user", func(ctx *gin.Context) {
+	router.POST("/auth/signup", func(ctx *gin.Context) {
+		var req authmodels.SignupRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid signup request: " + err.Error(),
+			})
+			return
+		}
 
+		tokens, err := authService.Signup(ctx, req, clientInfo(ctx))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
 
-		var user models.Users
+		ctx.JSON(http.StatusCreated, tokens)
+	})
 
-		if err := ctx.ShouldBindBodyWith(&user, binding.JSON); err != nil {
-			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error: "internal server error please try again: " + err.Error(),
+	router.POST("/auth/login", func(ctx *gin.Context) {
+		var req authmodels.LoginRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid login request: " + err.Error(),
 			})
 			return
 		}
 
-		users, err := carService.CreateUser(ctx, user)
+		tokens, err := authService.Login(ctx, req, clientInfo(ctx))
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
 				Error: err.Error(),
 			})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, users)
-
+		ctx.JSON(http.StatusOK, tokens)
 	})
 
-	router.GET("/user/:id", func(ctx *gin.Context) {
-		userID := ctx.Param("id")
+	router.POST("/auth/refresh", func(ctx *gin.Context) {
+		var req authmodels.RefreshRequest
 
-		if userID == "" {
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
 			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "car id is a required param",
+				Error: "invalid refresh request: " + err.Error(),
 			})
 			return
 		}
 
-		user, err := carService.GetUserByID(ctx, userID)
+		tokens, err := authService.Refresh(ctx, req.RefreshToken)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
 				Error: err.Error(),
 			})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, user)
-
+		ctx.JSON(http.StatusOK, tokens)
 	})
 
+	router.POST("/auth/logout", func(ctx *gin.Context) {
+		if err := authService.Logout(ctx); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
 
+		ctx.Status(http.StatusNoContent)
+	})
 
-	router.GET("/bid/:id", func(ctx *gin.Context) {
-		bidID := ctx.Param("id")
-
-		if bidID == "" {
-			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "bid id is a required param",
+	router.POST("/auth/logout-everywhere", func(ctx *gin.Context) {
+		if err := authService.LogoutEverywhere(ctx); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
 			})
 			return
 		}
 
-		bid, err := carService.GetBidByID(ctx, bidID)
+		ctx.Status(http.StatusNoContent)
+	})
+
+	router.GET("/auth/sessions", func(ctx *gin.Context) {
+		sessions, err := authService.ListSessions(ctx)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
 				Error: err.Error(),
 			})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, bid)
+		ctx.JSON(http.StatusOK, sessions)
+	})
+
+	router.DELETE("/auth/sessions/:id", func(ctx *gin.Context) {
+		if err := authService.RevokeSession(ctx, ctx.Param("id")); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
 
+		ctx.Status(http.StatusNoContent)
 	})
 
-	router.GET("/webhook/campay/payments", func(ctx *gin.Context) {
+	router.POST("/auth/verify-email/request", func(ctx *gin.Context) {
+		if err := authService.RequestEmailVerification(ctx); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
 
+		ctx.Status(http.StatusAccepted)
 	})
 
-	return router, nil
+	router.POST("/auth/verify-email/confirm", func(ctx *gin.Context) {
+		var req authmodels.AccountTokenRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid verification request: " + err.Error(),
+			})
+			return
+		}
+
+		user, err := authService.ConfirmEmail(ctx, req.Token)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, user)
+	})
+
+	router.POST("/auth/password-reset/request", func(ctx *gin.Context) {
+		var req authmodels.PasswordResetRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid password reset request: " + err.Error(),
+			})
+			return
+		}
+
+		if err := authService.RequestPasswordReset(ctx, req.Email); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusAccepted)
+	})
+
+	router.POST("/auth/password-reset/confirm", func(ctx *gin.Context) {
+		var req authmodels.PasswordResetConfirmRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid password reset request: " + err.Error(),
+			})
+			return
+		}
+
+		if err := authService.ResetPassword(ctx, req.Token, req.Password); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	})
+
+	router.POST("/auth/phone/request", func(ctx *gin.Context) {
+		var req authmodels.PhoneOTPRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid code request: " + err.Error(),
+			})
+			return
+		}
+
+		if err := authService.RequestPhoneOTP(ctx, req.PhoneNumber); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusAccepted)
+	})
+
+	router.POST("/auth/phone/login", func(ctx *gin.Context) {
+		var req authmodels.PhoneVerifyRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid login request: " + err.Error(),
+			})
+			return
+		}
+
+		tokens, err := authService.LoginWithPhone(ctx, req, clientInfo(ctx))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, tokens)
+	})
+
+	router.POST("/auth/phone/link", func(ctx *gin.Context) {
+		var req authmodels.PhoneVerifyRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid phone verification request: " + err.Error(),
+			})
+			return
+		}
+
+		user, err := authService.LinkPhone(ctx, req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, user)
+	})
+
+	router.POST("/user", func(ctx *gin.Context) {
+
+		var user models.Users
+
+		if err := ctx.ShouldBindBodyWith(&user, binding.JSON); err != nil {
+			ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "internal server error please try again: " + err.Error(),
+			})
+			return
+		}
+
+		users, err := carService.CreateUser(ctx, user)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, users)
+
+	})
+
+	router.GET("/users/:id/watchlist", func(ctx *gin.Context) {
+		watched, err := carService.ListWatchlist(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, watched)
+	})
+
+	router.POST("/users/:id/watchlist/:car_id", func(ctx *gin.Context) {
+		watched, err := carService.WatchCar(ctx, ctx.Param("id"), ctx.Param("car_id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, watched)
+	})
+
+	router.DELETE("/users/:id/watchlist/:car_id", func(ctx *gin.Context) {
+		if err := carService.UnwatchCar(ctx, ctx.Param("id"), ctx.Param("car_id")); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	})
+
+	router.GET("/users/:id/searches", func(ctx *gin.Context) {
+		saved, err := searchService.ListSavedSearches(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, saved)
+	})
+
+	router.POST("/users/:id/searches", func(ctx *gin.Context) {
+		var req models.SavedSearchRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		saved, err := searchService.CreateSavedSearch(ctx, ctx.Param("id"), req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, saved)
+	})
+
+	router.PUT("/users/:id/searches/:search_id", func(ctx *gin.Context) {
+		var req models.SavedSearchRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		saved, err := searchService.UpdateSavedSearch(ctx, ctx.Param("id"), ctx.Param("search_id"), req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, saved)
+	})
+
+	router.DELETE("/users/:id/searches/:search_id", func(ctx *gin.Context) {
+		if err := searchService.DeleteSavedSearch(ctx, ctx.Param("id"), ctx.Param("search_id")); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	})
+
+	router.GET("/user/:id", func(ctx *gin.Context) {
+		userID := ctx.Param("id")
+
+		if userID == "" {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "car id is a required param",
+			})
+			return
+		}
+
+		user, err := carService.GetUserByID(ctx, userID)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, user)
+
+	})
+
+	router.GET("/bid/:id", func(ctx *gin.Context) {
+		bidID := ctx.Param("id")
+
+		if bidID == "" {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "bid id is a required param",
+			})
+			return
+		}
+
+		bid, err := carService.GetBidByID(ctx, bidID)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, bid)
+
+	})
+
+	router.POST("/bid/:id/payment", func(ctx *gin.Context) {
+		payment, err := carService.PayBid(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusAccepted, payment)
+	})
+
+	router.GET("/admin/users", func(ctx *gin.Context) {
+		page, err := pageRequest(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		users, err := carService.ListUsers(ctx, ctx.Query("role"), page)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, users)
+	})
+
+	router.PATCH("/admin/users/:id/role", func(ctx *gin.Context) {
+		var req authmodels.SetRoleRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid role request: " + err.Error(),
+			})
+			return
+		}
+
+		user, err := authService.SetUserRole(ctx, ctx.Param("id"), req.Role)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, user)
+	})
+
+	router.POST("/admin/cars/:id/restore", func(ctx *gin.Context) {
+		car, err := carService.RestoreCar(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Header("ETag", car.ETag())
+		ctx.JSON(http.StatusOK, car)
+	})
+
+	router.POST("/admin/cities", func(ctx *gin.Context) {
+		var req models.CityRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid city: " + err.Error(),
+			})
+			return
+		}
+
+		city, err := catalogService.CreateCity(ctx, req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, city)
+	})
+
+	router.PUT("/admin/cities/:id", func(ctx *gin.Context) {
+		var req models.CityRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid city: " + err.Error(),
+			})
+			return
+		}
+
+		city, err := catalogService.UpdateCity(ctx, ctx.Param("id"), req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, city)
+	})
+
+	router.DELETE("/admin/cities/:id", func(ctx *gin.Context) {
+		if err := catalogService.DeleteCity(ctx, ctx.Param("id")); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	})
+
+	router.POST("/admin/categories", func(ctx *gin.Context) {
+		var req models.CategoryRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid category: " + err.Error(),
+			})
+			return
+		}
+
+		category, err := catalogService.CreateCategory(ctx, req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, category)
+	})
+
+	router.PUT("/admin/categories/:id", func(ctx *gin.Context) {
+		var req models.CategoryRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid category: " + err.Error(),
+			})
+			return
+		}
+
+		category, err := catalogService.UpdateCategory(ctx, ctx.Param("id"), req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, category)
+	})
+
+	router.DELETE("/admin/categories/:id", func(ctx *gin.Context) {
+		if err := catalogService.DeleteCategory(ctx, ctx.Param("id")); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	})
+
+	// set the JSON Schema that the extras of cars in a category must match.
+	router.PUT("/admin/categories/:id/schema", func(ctx *gin.Context) {
+		schema, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSchemaSize))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid attribute schema: " + err.Error(),
+			})
+			return
+		}
+
+		category, err := catalogService.SetCategorySchema(ctx, ctx.Param("id"), schema)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, category)
+	})
+
+	router.DELETE("/admin/categories/:id/schema", func(ctx *gin.Context) {
+		category, err := catalogService.SetCategorySchema(ctx, ctx.Param("id"), nil)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, category)
+	})
+
+	router.POST("/admin/vehicles", func(ctx *gin.Context) {
+		var req models.VehicleRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid vehicle: " + err.Error(),
+			})
+			return
+		}
+
+		vehicle, err := vehicleService.CreateVehicle(ctx, req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, vehicle)
+	})
+
+	// merge a duplicate make, model or trim and remap its cars.
+	router.POST("/admin/vehicles/merge", func(ctx *gin.Context) {
+		var req models.MergeVehiclesRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid merge: " + err.Error(),
+			})
+			return
+		}
+
+		merge, err := vehicleService.MergeVehicles(ctx, req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, merge)
+	})
+
+	// map the cars that are not mapped onto the taxonomy yet.
+	router.POST("/admin/vehicles/remap", func(ctx *gin.Context) {
+		remap, err := vehicleService.RemapCars(ctx)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, remap)
+	})
+
+	router.POST("/admin/organizations", func(ctx *gin.Context) {
+		var req authmodels.CreateOrganizationRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid organization: " + err.Error(),
+			})
+			return
+		}
+
+		org, err := apiKeyService.CreateOrganization(ctx, req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, org)
+	})
+
+	router.GET("/admin/organizations", func(ctx *gin.Context) {
+		orgs, err := apiKeyService.ListOrganizations(ctx)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, orgs)
+	})
+
+	router.POST("/admin/organizations/:id/api-keys", func(ctx *gin.Context) {
+		var req authmodels.CreateAPIKeyRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid api key: " + err.Error(),
+			})
+			return
+		}
+
+		key, err := apiKeyService.CreateAPIKey(ctx, ctx.Param("id"), req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, key)
+	})
+
+	router.GET("/admin/organizations/:id/api-keys", func(ctx *gin.Context) {
+		keys, err := apiKeyService.ListAPIKeys(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, keys)
+	})
+
+	router.GET("/admin/api-keys/:id", func(ctx *gin.Context) {
+		key, err := apiKeyService.GetAPIKey(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, key)
+	})
+
+	router.PATCH("/admin/api-keys/:id", func(ctx *gin.Context) {
+		var req authmodels.UpdateAPIKeyRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid api key update: " + err.Error(),
+			})
+			return
+		}
+
+		key, err := apiKeyService.UpdateAPIKey(ctx, ctx.Param("id"), req)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, key)
+	})
+
+	router.DELETE("/admin/api-keys/:id", func(ctx *gin.Context) {
+		if err := apiKeyService.RevokeAPIKey(ctx, ctx.Param("id")); err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	})
+
+	router.GET("/admin/audit", func(ctx *gin.Context) {
+		page, err := pageRequest(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		filter := auditmodels.Filter{
+			EntityType:  ctx.Query("entity_type"),
+			EntityID:    ctx.Query("entity_id"),
+			ActorID:     ctx.Query("actor_id"),
+			PageRequest: page,
+		}
+
+		entries, err := auditService.ListEntries(ctx, filter)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, entries)
+	})
+
+	router.POST("/sellers/verification/documents", func(ctx *gin.Context) {
+		// Leave room for the multipart envelope and the other form fields.
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, sellermodels.MaxDocumentSize+1<<20)
+
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "a document must be uploaded in the file form field: " + err.Error(),
+			})
+			return
+		}
+
+		if file.Size > sellermodels.MaxDocumentSize {
+			ctx.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+				Error: sellermodels.ErrDocumentTooLarge.Error(),
+			})
+			return
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid document upload: " + err.Error(),
+			})
+			return
+		}
+		defer content.Close()
+
+		doc, err := sellerService.UploadDocument(ctx, sellermodels.DocumentKind(ctx.PostForm("kind")), file.Filename, content)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, doc)
+	})
+
+	router.POST("/sellers/verification", func(ctx *gin.Context) {
+		verification, err := sellerService.SubmitVerification(ctx)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, verification)
+	})
+
+	router.GET("/sellers/verification", func(ctx *gin.Context) {
+		status, err := sellerService.GetVerificationStatus(ctx)
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, status)
+	})
+
+	router.GET("/admin/seller-verifications", func(ctx *gin.Context) {
+		verifications, err := sellerService.ListVerifications(ctx, ctx.DefaultQuery("status", sellermodels.VerificationPending))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, verifications)
+	})
+
+	router.GET("/admin/seller-verifications/:id", func(ctx *gin.Context) {
+		verification, err := sellerService.GetVerification(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, verification)
+	})
+
+	router.POST("/admin/seller-verifications/:id/approve", func(ctx *gin.Context) {
+		verification, err := sellerService.ApproveVerification(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, verification)
+	})
+
+	router.POST("/admin/seller-verifications/:id/reject", func(ctx *gin.Context) {
+		var req sellermodels.ReviewRequest
+
+		if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid review: " + err.Error(),
+			})
+			return
+		}
+
+		verification, err := sellerService.RejectVerification(ctx, ctx.Param("id"), strings.TrimSpace(req.Reason))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, verification)
+	})
+
+	router.GET("/admin/kyc-documents/:id", func(ctx *gin.Context) {
+		doc, content, err := sellerService.GetDocument(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(errorStatus(err), models.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": doc.FileName}))
+		ctx.Header("X-Content-Type-Options", "nosniff")
+		ctx.Data(http.StatusOK, doc.ContentType, content)
+	})
+
+	// Payment statuses are not stored yet, so CamPay notifications change nothing and are not audited; only payment
+	// requests are. Recording the status transitions, and auditing them, is left to the work that persists payments.
+	router.GET("/webhook/campay/payments", func(ctx *gin.Context) {
+
+	})
+
+	return router, nil
+}
+
+func clientInfo(ctx *gin.Context) authmodels.ClientInfo {
+	return authmodels.ClientInfo{
+		UserAgent: ctx.Request.UserAgent(),
+		IPAddress: ctx.ClientIP(),
+	}
 }
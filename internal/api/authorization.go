@@ -1,138 +1,182 @@
 package api
 
 import (
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
-	"regexp"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
-	"github.com/golang-jwt/jwt"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/auth"
+	"github.com/rs/zerolog/log"
 )
 
-var PublicPathRegex = regexp.MustCompile("^/(events|current_city|webhook|health_check|categories|tickets|event)")
+// SessionChecker reports whether the session an access token was issued for is still active.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
 
-type AccessTokenCustomClaims struct {
-	UserID string `json:"user_id"`
-	jwt.StandardClaims
+// APIKeyAuthenticator resolves the principal of a partner API key.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*authmodels.Principal, error)
 }
 
 type authHeader struct {
 	AccessToken string `header:"Authorization"`
+	APIKey      string `header:"X-API-Key"`
 }
 
-// AuthorizeRequest is a middleware that authorizes http requests given based on an JWT in the Authorization header.
-// Note: This middleware does NOT do authentication. The token and it's claims are assumed to be valid.
-// This middleware will check that the `user_id` claim in the JWT matches the `user_id` in the request body, URL query parameters, or
-// the path parameters, depending on the request type.
-//
-//nolint:funlen
-func AuthorizeRequest(ctx *gin.Context) {
-	// Skip authorization for public paths
-	if ok := PublicPathRegex.Match([]byte(ctx.FullPath())); ok {
-		ctx.Next()
+// AuthorizeRequest returns a middleware that authorizes http requests against the policies in routePolicies.
+// Callers authenticate with a JWT in the Authorization header or with a partner key in the X-API-Key header; both are
+// optional on public routes. When present, a JWT's signature, lifetime, issuer and audience are checked by verifier and
+// tokens bound to a revoked session are refused, and API keys must be known, unexpired and unrevoked; any failure is
+// answered with 401, and a session that can not be checked with 500. The authenticated authmodels.Principal is stored
+// in the context.
+// When enforcePolicies is false callers are still authenticated, but every route is treated as public.
+func AuthorizeRequest(verifier auth.TokenVerifier, sessions SessionChecker, apiKeys APIKeyAuthenticator, enforcePolicies bool) gin.HandlerFunc {
+	policies := routePolicies
+	if !enforcePolicies {
+		policies = nil
+	}
 
-		return
+	return func(ctx *gin.Context) {
+		authorizeRequest(ctx, verifier, sessions, apiKeys, policies)
 	}
+}
 
-	if ctx.Request.Method == http.MethodOptions {
+//nolint:funlen, gocyclo
+func authorizeRequest(ctx *gin.Context, verifier auth.TokenVerifier, sessions SessionChecker, apiKeys APIKeyAuthenticator, policies map[string]Policy) {
+	// Unmatched routes are left to the router's 404 handling.
+	if ctx.Request.Method == http.MethodOptions || ctx.FullPath() == "" {
 		ctx.Next()
 
 		return
 	}
 
-	header := authHeader{}
+	policy, ok := public, true
+	if policies != nil {
+		policy, ok = policies[routeKey(ctx.Request.Method, ctx.FullPath())]
+	}
 
-	err := ctx.ShouldBindHeader(&header)
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{
-			"error":  err,
-			"reason": "Authorization header is missing",
+	if !ok {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error":  "forbidden",
+			"reason": "no access policy is declared for this route",
 		})
-
 		ctx.Abort()
 
 		return
 	}
 
-	accessTokenStr := strings.TrimPrefix(header.AccessToken, "Bearer ")
+	header := authHeader{}
+	_ = ctx.ShouldBindHeader(&header)
+
+	principal := &authmodels.Principal{Role: authmodels.RoleAnonymous}
+
+	if header.AccessToken != "" || header.APIKey != "" {
+		var (
+			reason string
+			err    error
+		)
+
+		if header.APIKey != "" {
+			principal, reason, err = authenticateAPIKey(ctx, apiKeys, header.APIKey)
+		} else {
+			principal, reason, err = authenticate(ctx, verifier, sessions, strings.TrimPrefix(header.AccessToken, "Bearer "))
+		}
+
+		if err != nil {
+			log.Error().Err(err).Msg("authenticating request")
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": http.StatusText(http.StatusInternalServerError),
+			})
+			ctx.Abort()
+
+			return
+		}
+
+		if principal == nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error":  "unauthorized",
+				"reason": reason,
+			})
+			ctx.Abort()
+
+			return
+		}
+
+		ctx.Set(authmodels.PrincipalKey, principal)
+	}
+
+	if !policy.allows(principal, ctx.Param) {
+		status := http.StatusForbidden
+		if principal.Role == authmodels.RoleAnonymous {
+			status = http.StatusUnauthorized
+		}
 
-	claims, _ := ParseAccessToken(accessTokenStr)
-	if claims.UserID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":  err,
-			"reason": "user_id must be set in JWT claims",
+		ctx.JSON(status, gin.H{
+			"error": http.StatusText(status),
 		})
 		ctx.Abort()
 
 		return
 	}
 
-	authorized := false
-
-	switch ctx.Request.Method {
-	case http.MethodPost:
-		authorized, err = authorizePos(ctx, claims.UserID)
-	case http.MethodGet:
-		fallthrough
-	case http.MethodPatch:
-		authorized, err = authorizeGetAndPatch(ctx, claims.UserID)
-	default:
-		//nolint:goerr113
-		err = fmt.Errorf("http method not allowed")
-	}
+	ctx.Next()
+}
 
+// authenticate verifies an access token and returns its principal, or nil and the reason it was refused. The error is
+// set when the session of the token could not be checked.
+func authenticate(ctx context.Context, verifier auth.TokenVerifier, sessions SessionChecker, accessToken string) (*authmodels.Principal, string, error) {
+	claims, err := verifier.ParseAccessToken(accessToken)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":  err,
-			"reason": "Authorization header is invalid",
-		})
-		ctx.Abort()
-
-		return
+		return nil, err.Error(), nil
 	}
 
-	if !authorized {
-		ctx.JSON(http.StatusUnauthorized, gin.H{
-			"error": "unauthorized",
-		})
-		ctx.Abort()
+	if claims.SessionID != "" {
+		active, err := sessions.IsSessionActive(ctx, claims.SessionID)
+		if err != nil {
+			return nil, "", err
+		}
 
-		return
+		if !active {
+			return nil, "session has been revoked", nil
+		}
 	}
 
-	ctx.Next()
-}
-
-func ParseAccessToken(tokenString string) (*AccessTokenCustomClaims, error) {
-	//nolint:exhaustruct
-	claims := AccessTokenCustomClaims{}
+	role := authmodels.Role(claims.Role)
+	if role == "" {
+		role = authmodels.RoleBuyer
+	}
 
-	_, _ = jwt.ParseWithClaims(tokenString, &claims, nil)
+	if !role.Valid() {
+		return nil, "unknown role in JWT claims", nil
+	}
 
-	return &claims, nil
+	return &authmodels.Principal{
+		UserID:    claims.UserID,
+		SessionID: claims.SessionID,
+		Role:      role,
+	}, "", nil
 }
 
-func authorizePos(c *gin.Context, userID string) (bool, error) {
-	var body struct {
-		UserID string `json:"user_id"`
+// authenticateAPIKey resolves a partner API key, or returns nil and the reason it was refused. The error is set when
+// the key could not be checked.
+func authenticateAPIKey(ctx context.Context, apiKeys APIKeyAuthenticator, key string) (*authmodels.Principal, string, error) {
+	principal, err := apiKeys.AuthenticateAPIKey(ctx, key)
+	if errors.Is(err, authmodels.ErrInvalidAPIKey) || errors.Is(err, sql.ErrNoRows) {
+		return nil, authmodels.ErrInvalidAPIKey.Error(), nil
 	}
 
-	err := c.ShouldBindBodyWith(&body, binding.JSON)
 	if err != nil {
-		return false, err
+		return nil, "", err
 	}
 
-	return userID == body.UserID, nil
-}
-
-//nolint:unparam
-func authorizeGetAndPatch(c *gin.Context, expectedUserID string) (bool, error) {
-	userIDActual := c.Query("user_id")
-	if userIDActual == "" {
-		userIDActual = c.Param("user_id")
+	if !principal.Role.Valid() {
+		return nil, "api key owner has an unknown role", nil
 	}
 
-	return userIDActual == expectedUserID, nil
+	return principal, "", nil
 }
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/storage"
+)
+
+// errorStatus maps service errors onto HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidBidExpiration),
+		errors.Is(err, models.ErrInvalidPatch),
+		errors.Is(err, models.ErrImmutableField),
+		errors.Is(err, models.ErrWithdrawalReasonRequired),
+		errors.Is(err, models.ErrInvalidSearch),
+		errors.Is(err, models.ErrInvalidFilter),
+		errors.Is(err, models.ErrInvalidCursor),
+		errors.Is(err, models.ErrInvalidCar),
+		errors.Is(err, models.ErrInvalidFuelType),
+		errors.Is(err, models.ErrUnknownCity),
+		errors.Is(err, models.ErrUnknownCategory),
+		errors.Is(err, models.ErrInvalidCatalogEntry),
+		errors.Is(err, models.ErrInvalidVehicleKind),
+		errors.Is(err, models.ErrInvalidVehicle),
+		errors.Is(err, models.ErrInvalidMerge),
+		errors.Is(err, models.ErrInvalidAttributeSchema),
+		errors.Is(err, models.ErrInvalidSavedSearch),
+		errors.Is(err, models.ErrInvalidVIN),
+		errors.Is(err, models.ErrNoPhotos),
+		errors.Is(err, models.ErrPhotoDimensions),
+		errors.Is(err, models.ErrInvalidPhotoOrder),
+		errors.Is(err, authmodels.ErrInvalidEmail),
+		errors.Is(err, authmodels.ErrWeakPassword),
+		errors.Is(err, authmodels.ErrInvalidRole),
+		errors.Is(err, authmodels.ErrInvalidScope),
+		errors.Is(err, authmodels.ErrInvalidAccountToken),
+		errors.Is(err, authmodels.ErrInvalidPhone),
+		errors.Is(err, sellermodels.ErrInvalidDocumentKind),
+		errors.Is(err, sellermodels.ErrMissingDocuments),
+		errors.Is(err, sellermodels.ErrReasonRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, authmodels.ErrInvalidCredentials),
+		errors.Is(err, authmodels.ErrInvalidRefreshToken),
+		errors.Is(err, authmodels.ErrRefreshTokenReused),
+		errors.Is(err, authmodels.ErrUnauthenticated),
+		errors.Is(err, authmodels.ErrInvalidOTP):
+		return http.StatusUnauthorized
+	case errors.Is(err, authmodels.ErrSessionNotFound),
+		errors.Is(err, authmodels.ErrAPIKeyNotFound),
+		errors.Is(err, sellermodels.ErrVerificationNotFound),
+		errors.Is(err, storage.ErrNotFound),
+		errors.Is(err, storage.ErrInvalidKey),
+		errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrForbidden),
+		errors.Is(err, models.ErrOwnCarBid),
+		errors.Is(err, models.ErrAccountNotVerified),
+		errors.Is(err, models.ErrPhoneNotVerified),
+		errors.Is(err, sellermodels.ErrSellerNotVerified),
+		errors.Is(err, storage.ErrInvalidSignature):
+		return http.StatusForbidden
+	case errors.Is(err, authmodels.ErrEmailTaken),
+		errors.Is(err, models.ErrFieldLocked),
+		errors.Is(err, models.ErrBidExpirationLocked),
+		errors.Is(err, models.ErrCarWithdrawn),
+		errors.Is(err, models.ErrAuctionClosed),
+		errors.Is(err, models.ErrCarNotWithdrawn),
+		errors.Is(err, models.ErrWithdrawalClosed),
+		errors.Is(err, models.ErrTooManyPhotos),
+		errors.Is(err, models.ErrDuplicateVIN),
+		errors.Is(err, models.ErrCityExists),
+		errors.Is(err, models.ErrCategoryExists),
+		errors.Is(err, models.ErrCityInUse),
+		errors.Is(err, models.ErrCategoryInUse),
+		errors.Is(err, models.ErrVehicleExists),
+		errors.Is(err, models.ErrSavedSearchExists),
+		errors.Is(err, models.ErrTooManySavedSearches),
+		errors.Is(err, authmodels.ErrPhoneTaken),
+		errors.Is(err, sellermodels.ErrVerificationPending),
+		errors.Is(err, sellermodels.ErrVerificationNotPending),
+		errors.Is(err, sellermodels.ErrAlreadyVerified):
+		return http.StatusConflict
+	case errors.Is(err, authmodels.ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, models.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, models.ErrInvalidAttributes):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, sellermodels.ErrDocumentTooLarge),
+		errors.Is(err, models.ErrPhotoTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, sellermodels.ErrUnsupportedDocumentType),
+		errors.Is(err, models.ErrUnsupportedPhotoType):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorResponse returns the body of an error response, with the invalid fields of validation errors.
+func errorResponse(err error) models.ErrorResponse {
+	response := models.ErrorResponse{Error: err.Error()}
+
+	var attributesErr *models.AttributesError
+	if errors.As(err, &attributesErr) {
+		response.Fields = attributesErr.Fields
+	}
+
+	return response
+}
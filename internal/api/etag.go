@@ -0,0 +1,47 @@
+package api
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// mergePatchContentType is the media type of RFC 7396 JSON merge patches.
+const mergePatchContentType = "application/merge-patch+json"
+
+// maxPatchSize is the largest merge patch body accepted.
+const maxPatchSize = 1 << 20
+
+// ifMatchVersion returns the car version named by an If-Match header, or zero if the header is empty or "*".
+// Weak and malformed entity tags never match and yield models.ErrVersionConflict.
+func ifMatchVersion(header string) (int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	unquoted, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return 0, models.ErrVersionConflict
+	}
+
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, models.ErrVersionConflict
+	}
+
+	return version, nil
+}
+
+// isMergePatch reports whether contentType is a merge patch. Plain JSON is accepted too, for clients that can not set
+// the media type.
+func isMergePatch(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == mergePatchContentType || mediaType == "application/json"
+}
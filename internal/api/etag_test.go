@@ -0,0 +1,43 @@
+package api
+
+import (
+	"testing"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int
+		wantErr error
+	}{
+		{"", 0, nil},
+		{"*", 0, nil},
+		{`"3"`, 3, nil},
+		{` "12" `, 12, nil},
+		{models.Cars{Version: 7}.ETag(), 7, nil},
+		{`W/"3"`, 0, models.ErrVersionConflict},
+		{`3`, 0, models.ErrVersionConflict},
+		{`"0"`, 0, models.ErrVersionConflict},
+		{`"abc"`, 0, models.ErrVersionConflict},
+		{`"3", "4"`, 0, models.ErrVersionConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := ifMatchVersion(tt.header)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsMergePatch(t *testing.T) {
+	assert.True(t, isMergePatch("application/merge-patch+json"))
+	assert.True(t, isMergePatch("application/merge-patch+json; charset=utf-8"))
+	assert.True(t, isMergePatch("application/json"))
+	assert.False(t, isMergePatch("application/json-patch+json"))
+	assert.False(t, isMergePatch(""))
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// carFilter reads a models.CarFilter from the query string. Multi-value filters may be repeated or comma separated.
+//
+//nolint:cyclop
+func carFilter(ctx *gin.Context) (models.CarFilter, error) {
+	filter := models.CarFilter{
+		CityID:      ctx.Query("city_id"),
+		Category:    ctx.Query("category_id"),
+		VIN:         ctx.Query("vin"),
+		MakeID:      ctx.Query("make_id"),
+		ModelID:     ctx.Query("model_id"),
+		FuelTypes:   queryList(ctx, "fuel_type"),
+		EngineTypes: queryList(ctx, "engine_type"),
+		Models:      queryList(ctx, "model"),
+		Sort:        models.CarSort(ctx.Query("sort")),
+	}
+
+	page, err := pageRequest(ctx)
+	if err != nil {
+		return filter, err
+	}
+
+	filter.PageRequest = page
+
+	for _, status := range queryList(ctx, "status") {
+		filter.Statuses = append(filter.Statuses, models.AuctionStatus(status))
+	}
+
+	ranges := []struct {
+		param string
+		value **int64
+	}{
+		{"min_price", &filter.MinPrice},
+		{"max_price", &filter.MaxPrice},
+		{"min_mileage", &filter.MinMileage},
+		{"max_mileage", &filter.MaxMileage},
+		{"min_year", &filter.MinYear},
+		{"max_year", &filter.MaxYear},
+	}
+
+	for _, r := range ranges {
+		param := ctx.Query(r.param)
+		if param == "" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("%w: %s must be a whole number", models.ErrInvalidFilter, r.param)
+		}
+
+		*r.value = &n
+	}
+
+	return filter, nil
+}
+
+// pageRequest reads the cursor, count and include_total query parameters.
+func pageRequest(ctx *gin.Context) (models.PageRequest, error) {
+	page := models.PageRequest{
+		Cursor: ctx.Query("cursor"),
+		Count:  defaultPageSize,
+	}
+
+	if param := ctx.Query("count"); param != "" {
+		n, err := strconv.ParseUint(param, 10, 64)
+		if err != nil || n == 0 || n > maxPageSize {
+			return page, fmt.Errorf("%w: count must be between 1 and %d", models.ErrInvalidFilter, maxPageSize)
+		}
+
+		page.Count = uint(n)
+	}
+
+	if param := ctx.Query("include_total"); param != "" {
+		withTotal, err := strconv.ParseBool(param)
+		if err != nil {
+			return page, fmt.Errorf("%w: include_total must be true or false", models.ErrInvalidFilter)
+		}
+
+		page.WithTotal = withTotal
+	}
+
+	return page, nil
+}
+
+// queryList returns the non-empty values of a query parameter that may be repeated or comma separated.
+func queryList(ctx *gin.Context, param string) []string {
+	var values []string
+
+	for _, value := range ctx.QueryArray(param) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+)
+
+// Policy declares who may call a route.
+type Policy struct {
+	// Roles lists the roles allowed to call the route. RoleAnonymous makes the route public.
+	Roles []authmodels.Role
+	// SelfParam names a path parameter that must equal the caller's user id. Admins are exempt.
+	SelfParam string
+	// Scope is the API key scope required to call the route with an API key.
+	// API keys can only call routes that declare a scope, or public routes.
+	Scope string
+}
+
+var (
+	public        = Policy{Roles: []authmodels.Role{authmodels.RoleAnonymous, authmodels.RoleBuyer, authmodels.RoleSeller, authmodels.RoleAdmin}}
+	authenticated = Policy{Roles: []authmodels.Role{authmodels.RoleBuyer, authmodels.RoleSeller, authmodels.RoleAdmin}}
+	sellers       = Policy{Roles: []authmodels.Role{authmodels.RoleSeller, authmodels.RoleAdmin}}
+	admins        = Policy{Roles: []authmodels.Role{authmodels.RoleAdmin}}
+)
+
+// self restricts authenticated to callers whose user id is the path parameter param.
+func self(param string) Policy {
+	return Policy{Roles: authenticated.Roles, SelfParam: param}
+}
+
+// routePolicies is the access policy of every route, keyed by method and route path.
+// Routes without an entry are refused.
+var routePolicies = map[string]Policy{
+	"GET /cars":     public.withScope(authmodels.ScopeCarsRead),
+	"GET /cars/:id": public.withScope(authmodels.ScopeCarsRead),
+
+	"GET /cars/search":   public.withScope(authmodels.ScopeCarsRead),
+	"GET /vins/:vin":     public.withScope(authmodels.ScopeCarsRead),
+	"GET /cars/:id/bids": public.withScope(authmodels.ScopeBidsRead),
+
+	"GET /cities":                public.withScope(authmodels.ScopeCarsRead),
+	"GET /categories":            public.withScope(authmodels.ScopeCarsRead),
+	"GET /categories/:id":        public.withScope(authmodels.ScopeCarsRead),
+	"GET /vehicles/autocomplete": public.withScope(authmodels.ScopeCarsRead),
+
+	"PATCH /cars/:id":  sellers.withScope(authmodels.ScopeCarsWrite),
+	"DELETE /cars/:id": sellers.withScope(authmodels.ScopeCarsWrite),
+
+	"POST /cars/:id/photos":                   sellers.withScope(authmodels.ScopeCarsWrite),
+	"GET /cars/:id/photos":                    public.withScope(authmodels.ScopeCarsRead),
+	"PUT /cars/:id/photos/order":              sellers.withScope(authmodels.ScopeCarsWrite),
+	"POST /cars/:id/photos/:photo_id/primary": sellers.withScope(authmodels.ScopeCarsWrite),
+	"DELETE /cars/:id/photos/:photo_id":       sellers.withScope(authmodels.ScopeCarsWrite),
+	"GET /photos/*key":                        public,
+
+	"POST /register/car": sellers.withScope(authmodels.ScopeCarsWrite),
+	"POST /bid":          authenticated.withScope(authmodels.ScopeBidsWrite),
+	"GET /bid/:id":       authenticated.withScope(authmodels.ScopeBidsRead),
+
+	"POST /bid/:id/payment": authenticated,
+
+	"POST /auth/signup":            public,
+	"POST /auth/login":             public,
+	"POST /auth/refresh":           public,
+	"POST /auth/logout":            authenticated,
+	"POST /auth/logout-everywhere": authenticated,
+	"GET /auth/sessions":           authenticated,
+	"DELETE /auth/sessions/:id":    authenticated,
+
+	"POST /auth/verify-email/request":   authenticated,
+	"POST /auth/verify-email/confirm":   public,
+	"POST /auth/password-reset/request": public,
+	"POST /auth/password-reset/confirm": public,
+
+	"POST /auth/phone/request": public,
+	"POST /auth/phone/login":   public,
+	"POST /auth/phone/link":    authenticated,
+
+	"POST /user":    authenticated,
+	"GET /user/:id": authenticated,
+
+	"GET /users/:id/watchlist":            self("id"),
+	"POST /users/:id/watchlist/:car_id":   self("id"),
+	"DELETE /users/:id/watchlist/:car_id": self("id"),
+
+	"GET /users/:id/searches":               self("id"),
+	"POST /users/:id/searches":              self("id"),
+	"PUT /users/:id/searches/:search_id":    self("id"),
+	"DELETE /users/:id/searches/:search_id": self("id"),
+
+	"GET /admin/users":            admins,
+	"PATCH /admin/users/:id/role": admins,
+
+	"POST /admin/cars/:id/restore": admins,
+
+	"POST /admin/cities":           admins,
+	"PUT /admin/cities/:id":        admins,
+	"DELETE /admin/cities/:id":     admins,
+	"POST /admin/categories":       admins,
+	"PUT /admin/categories/:id":    admins,
+	"DELETE /admin/categories/:id": admins,
+
+	"PUT /admin/categories/:id/schema":    admins,
+	"DELETE /admin/categories/:id/schema": admins,
+
+	"POST /admin/vehicles":       admins,
+	"POST /admin/vehicles/merge": admins,
+	"POST /admin/vehicles/remap": admins,
+
+	"POST /admin/organizations":              admins,
+	"GET /admin/organizations":               admins,
+	"POST /admin/organizations/:id/api-keys": admins,
+	"GET /admin/organizations/:id/api-keys":  admins,
+	"GET /admin/api-keys/:id":                admins,
+	"PATCH /admin/api-keys/:id":              admins,
+	"DELETE /admin/api-keys/:id":             admins,
+
+	"GET /admin/audit": admins,
+
+	"POST /sellers/verification/documents": authenticated,
+	"POST /sellers/verification":           authenticated,
+	"GET /sellers/verification":            authenticated,
+
+	"GET /admin/seller-verifications":              admins,
+	"GET /admin/seller-verifications/:id":          admins,
+	"POST /admin/seller-verifications/:id/approve": admins,
+	"POST /admin/seller-verifications/:id/reject":  admins,
+	"GET /admin/kyc-documents/:id":                 admins,
+
+	"GET /webhook/campay/payments": public,
+}
+
+// withScope returns a copy of p that API keys granted scope may call.
+func (p Policy) withScope(scope string) Policy {
+	p.Scope = scope
+
+	return p
+}
+
+func routeKey(method string, path string) string {
+	return method + " " + path
+}
+
+func (p Policy) allows(principal *authmodels.Principal, param func(string) string) bool {
+	allowed := false
+
+	for _, role := range p.Roles {
+		if role == principal.Role {
+			allowed = true
+
+			break
+		}
+	}
+
+	if !allowed {
+		return false
+	}
+
+	if principal.IsAPIKey() {
+		if p.Scope == "" {
+			return p.isPublic()
+		}
+
+		if !principal.HasScope(p.Scope) {
+			return false
+		}
+	}
+
+	if p.SelfParam != "" && !principal.IsAdmin() {
+		return param(p.SelfParam) == principal.UserID
+	}
+
+	return true
+}
+
+func (p Policy) isPublic() bool {
+	for _, role := range p.Roles {
+		if role == authmodels.RoleAnonymous {
+			return true
+		}
+	}
+
+	return false
+}
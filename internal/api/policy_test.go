@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSessions map[string]bool
+
+// IsSessionActive fails for the session "unavailable", as when the database is down.
+func (f fakeSessions) IsSessionActive(_ context.Context, sessionID string) (bool, error) {
+	if sessionID == "unavailable" {
+		return false, errors.New("connection refused")
+	}
+
+	return f[sessionID], nil
+}
+
+type fakeAPIKeys struct{}
+
+// AuthenticateAPIKey accepts keys of the form <role>:<scope>. It fails for the key "unavailable", as when the
+// database is down.
+func (fakeAPIKeys) AuthenticateAPIKey(_ context.Context, key string) (*authmodels.Principal, error) {
+	if key == "unavailable" {
+		return nil, errors.New("connection refused")
+	}
+
+	role, scope, found := strings.Cut(key, ":")
+	if !found {
+		return nil, authmodels.ErrInvalidAPIKey
+	}
+
+	return &authmodels.Principal{UserID: "owner", Role: authmodels.Role(role), APIKeyID: "key", Scopes: []string{scope}}, nil
+}
+
+func testRouter(t *testing.T) (*gin.Engine, *auth.Signer) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	keyConfig := auth.KeyConfig{HMACSecrets: "test-secret"}
+
+	keys, err := auth.LoadKeySet(keyConfig)
+	require.NoError(t, err)
+
+	verifier, err := auth.NewVerifier(keys, "", "")
+	require.NoError(t, err)
+
+	signer, err := auth.LoadSigner(keyConfig, "", "", time.Minute)
+	require.NoError(t, err)
+
+	policies := map[string]Policy{
+		"GET /cars":                   public,
+		"POST /register/car":          sellers.withScope(authmodels.ScopeCarsWrite),
+		"GET /user/:id":               self("id"),
+		"PATCH /admin/users/:id/role": admins,
+	}
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		authorizeRequest(ctx, verifier, fakeSessions{"active": true}, fakeAPIKeys{}, policies)
+	})
+
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	router.GET("/cars", ok)
+	router.POST("/register/car", ok)
+	router.GET("/user/:id", ok)
+	router.PATCH("/admin/users/:id/role", ok)
+	router.DELETE("/undeclared", ok)
+
+	return router, signer
+}
+
+func TestAuthorizeRequest_Policies(t *testing.T) {
+	router, signer := testRouter(t)
+
+	token := func(userID string, sessionID string, role string) string {
+		signed, err := signer.IssueAccessToken(userID, sessionID, role)
+		require.NoError(t, err)
+
+		return "Bearer " + signed
+	}
+
+	// external is a token from another issuer sharing the key, carrying that issuer's OIDC session id.
+	external, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "u1", "role": "seller", "sid": "idp-session-1", "exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte("test-secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		apiKey string
+		want   int
+	}{
+		{"anonymous can browse cars", http.MethodGet, "/cars", "", "", http.StatusOK},
+		{"invalid token on a public route", http.MethodGet, "/cars", "Bearer forged", "", http.StatusUnauthorized},
+		{"anonymous cannot register cars", http.MethodPost, "/register/car", "", "", http.StatusUnauthorized},
+		{"buyer cannot register cars", http.MethodPost, "/register/car", token("u1", "active", "buyer"), "", http.StatusForbidden},
+		{"seller can register cars", http.MethodPost, "/register/car", token("u1", "active", "seller"), "", http.StatusOK},
+		{"revoked session", http.MethodPost, "/register/car", token("u1", "revoked", "seller"), "", http.StatusUnauthorized},
+		{"session check failure", http.MethodGet, "/cars", token("u1", "unavailable", "seller"), "", http.StatusInternalServerError},
+		{"external sid is not one of our sessions", http.MethodPost, "/register/car", "Bearer " + external, "", http.StatusOK},
+		{"tokens without a role are buyers", http.MethodPost, "/register/car", token("u1", "active", ""), "", http.StatusForbidden},
+		{"unknown role", http.MethodGet, "/cars", token("u1", "active", "superuser"), "", http.StatusUnauthorized},
+		{"user can read own profile", http.MethodGet, "/user/u1", token("u1", "active", "buyer"), "", http.StatusOK},
+		{"user cannot read another profile", http.MethodGet, "/user/u2", token("u1", "active", "buyer"), "", http.StatusForbidden},
+		{"admin can read any profile", http.MethodGet, "/user/u2", token("a1", "active", "admin"), "", http.StatusOK},
+		{"seller cannot change roles", http.MethodPatch, "/admin/users/u2/role", token("u1", "active", "seller"), "", http.StatusForbidden},
+		{"admin can change roles", http.MethodPatch, "/admin/users/u2/role", token("a1", "active", "admin"), "", http.StatusOK},
+		{"routes without a policy are refused", http.MethodDelete, "/undeclared", token("a1", "active", "admin"), "", http.StatusForbidden},
+		{"unknown routes are not found", http.MethodGet, "/nowhere", "", "", http.StatusNotFound},
+		{"api key with the route scope", http.MethodPost, "/register/car", "", "seller:cars:write", http.StatusOK},
+		{"api key without the route scope", http.MethodPost, "/register/car", "", "seller:bids:write", http.StatusForbidden},
+		{"api key on a public route", http.MethodGet, "/cars", "", "seller:bids:write", http.StatusOK},
+		{"api keys cannot call unscoped routes", http.MethodPatch, "/admin/users/u2/role", "", "admin:cars:write", http.StatusForbidden},
+		{"unknown api key", http.MethodGet, "/cars", "", "garbage", http.StatusUnauthorized},
+		{"api key check failure", http.MethodGet, "/cars", "", "unavailable", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestRoutePolicies_CoverEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router, err := NewAPIListener(nil, nil, nil, nil, nil, nil, nil, nil, nil, RateLimits{}, true, "*")
+	require.NoError(t, err)
+
+	for _, route := range router.Routes() {
+		_, ok := routePolicies[routeKey(route.Method, route.Path)]
+		assert.Truef(t, ok, "route %s %s has no access policy", route.Method, route.Path)
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/ratelimit"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultRateGroup limits the routes that are not listed in routeRateGroups.
+const DefaultRateGroup = "default"
+
+// RateLimits configures the rate limiting middlewares. Rate limiting is disabled when Store is nil.
+type RateLimits struct {
+	Store ratelimit.Store
+	// Limits apply to every authenticated user and API key, and IPLimits to every client IP. Both are keyed by rate
+	// group; groups without a limit are not limited. IPLimits are usually looser, as users behind a shared NAT or
+	// mobile carrier share an IP.
+	Limits   map[string]ratelimit.Limit
+	IPLimits map[string]ratelimit.Limit
+	// TrustedProxies are the addresses whose X-Forwarded-For header is believed when resolving client IPs.
+	// No proxy is trusted when it is empty, and clients are identified by the address they connect from.
+	TrustedProxies []string
+}
+
+// routeRateGroups assigns routes to rate groups, keyed by method and route path.
+var routeRateGroups = map[string]string{
+	"POST /bid":             "bids",
+	"POST /bid/:id/payment": "bids",
+
+	"POST /register/car": "listings",
+	"PATCH /cars/:id":    "listings",
+	"DELETE /cars/:id":   "listings",
+
+	"POST /cars/:id/photos": "listings",
+
+	"POST /sellers/verification/documents": "kyc",
+	"POST /sellers/verification":           "kyc",
+
+	"POST /auth/signup":                 "auth",
+	"POST /auth/login":                  "auth",
+	"POST /auth/refresh":                "auth",
+	"POST /auth/verify-email/request":   "auth",
+	"POST /auth/verify-email/confirm":   "auth",
+	"POST /auth/password-reset/request": "auth",
+	"POST /auth/password-reset/confirm": "auth",
+	"POST /auth/phone/request":          "auth",
+	"POST /auth/phone/login":            "auth",
+	"POST /auth/phone/link":             "auth",
+}
+
+// RateLimitClients returns a middleware that gives every client IP a token bucket per rate group. It runs before
+// AuthorizeRequest, so that requests with made up credentials are limited like any other.
+func RateLimitClients(store ratelimit.Store, limits map[string]ratelimit.Limit) gin.HandlerFunc {
+	return rateLimit(store, limits, func(ctx *gin.Context) (string, bool) {
+		return "ip:" + ctx.ClientIP(), true
+	})
+}
+
+// RateLimitCallers returns a middleware that gives every authenticated user and API key a token bucket per rate
+// group, wherever their requests come from. It runs after AuthorizeRequest; anonymous requests are only limited by
+// RateLimitClients.
+func RateLimitCallers(store ratelimit.Store, limits map[string]ratelimit.Limit) gin.HandlerFunc {
+	return rateLimit(store, limits, rateLimitCaller)
+}
+
+func rateLimitCaller(ctx *gin.Context) (string, bool) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+
+	switch {
+	case ok && principal.IsAPIKey():
+		return "key:" + principal.APIKeyID, true
+	case ok && principal.UserID != "":
+		return "user:" + principal.UserID, true
+	default:
+		return "", false
+	}
+}
+
+// rateLimit takes a token from the bucket of the caller identified by caller in the rate group of the route.
+// Refused requests are answered with 429 and a Retry-After header. Limited responses carry the RateLimit-* headers of
+// the bucket with the fewest tokens left. Requests are let through when the store fails.
+func rateLimit(store ratelimit.Store, limits map[string]ratelimit.Limit, caller func(ctx *gin.Context) (string, bool)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodOptions || ctx.FullPath() == "" {
+			ctx.Next()
+
+			return
+		}
+
+		group, ok := routeRateGroups[routeKey(ctx.Request.Method, ctx.FullPath())]
+		if !ok {
+			group = DefaultRateGroup
+		}
+
+		limit, ok := limits[group]
+		if !ok {
+			ctx.Next()
+
+			return
+		}
+
+		key, ok := caller(ctx)
+		if !ok {
+			ctx.Next()
+
+			return
+		}
+
+		res, err := store.Take(ctx, group+":"+key, limit)
+		if err != nil {
+			log.Error().Err(err).Str("group", group).Msg("rate limit store failed, letting request through")
+			ctx.Next()
+
+			return
+		}
+
+		header := ctx.Writer.Header()
+		if remaining, err := strconv.Atoi(header.Get("RateLimit-Remaining")); err != nil || res.Remaining < remaining {
+			header.Set("RateLimit-Policy", strconv.Itoa(limit.Count)+";w="+strconv.Itoa(int(limit.Period.Seconds())))
+			header.Set("RateLimit-Limit", strconv.Itoa(limit.Count))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+		}
+
+		if !res.Allowed {
+			header.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Error: authmodels.ErrTooManyRequests.Error(),
+			})
+
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
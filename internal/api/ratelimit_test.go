@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// rateLimitRouter limits POST /bid by IP, then by the user named in the X-User header.
+func rateLimitRouter(ipLimit int, userLimit int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	store := ratelimit.NewMemoryStore()
+
+	router := gin.New()
+	router.Use(RateLimitClients(store, map[string]ratelimit.Limit{"bids": {Count: ipLimit, Period: time.Minute}}))
+	router.Use(func(ctx *gin.Context) {
+		if userID := ctx.GetHeader("X-User"); userID != "" {
+			ctx.Set(authmodels.PrincipalKey, &authmodels.Principal{UserID: userID, Role: authmodels.RoleBuyer})
+		}
+	})
+	router.Use(RateLimitCallers(store, map[string]ratelimit.Limit{"bids": {Count: userLimit, Period: time.Minute}}))
+	router.POST("/bid", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	return router
+}
+
+func bid(router *gin.Engine, ip string, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/bid", nil)
+	req.RemoteAddr = ip + ":1234"
+
+	if userID != "" {
+		req.Header.Set("X-User", userID)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestRateLimit_Headers(t *testing.T) {
+	router := rateLimitRouter(2, 10)
+
+	rec := bid(router, "192.0.2.1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2;w=60", rec.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, rec.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, bid(router, "192.0.2.1", "").Code)
+
+	rec = bid(router, "192.0.2.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, bid(router, "192.0.2.2", "").Code, "other IPs have their own bucket")
+}
+
+func TestRateLimit_Users(t *testing.T) {
+	router := rateLimitRouter(10, 2)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, bid(router, "192.0.2.1", "u1").Code)
+	}
+
+	rec := bid(router, "192.0.2.1", "u1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"), "the headers describe the exhausted bucket")
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, bid(router, "192.0.2.1", "u2").Code, "users sharing an IP have their own bucket")
+	assert.Equal(t, http.StatusTooManyRequests, bid(router, "198.51.100.7", "u1").Code,
+		"users are limited whichever IP they use")
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// RequestID returns a middleware that tags every request with an id and stores it, together with the client IP, in the
+// context under auditmodels.RequestIDKey and auditmodels.ClientIPKey. A well-formed X-Request-ID header set by the
+// client or a proxy is kept; otherwise a random id is generated. The id is echoed in the X-Request-ID response header.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		ctx.Set(auditmodels.RequestIDKey, requestID)
+		ctx.Set(auditmodels.ClientIPKey, ctx.ClientIP())
+		ctx.Header(requestIDHeader, requestID)
+
+		ctx.Next()
+	}
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	raw := make([]byte, 16)
+	_, _ = rand.Read(raw)
+
+	return hex.EncodeToString(raw)
+}
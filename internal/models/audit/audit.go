@@ -0,0 +1,179 @@
+package auditmodels
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/jmoiron/sqlx/types"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// Context keys set by the request id middleware.
+const (
+	// RequestIDKey holds the id of the current request. The payments service logs it as correlationID.
+	RequestIDKey = "correlationID"
+	ClientIPKey  = "clientIP"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionCarRegistered         = "car.registered"
+	ActionCarUpdated            = "car.updated"
+	ActionCarWithdrawn          = "car.withdrawn"
+	ActionCarRestored           = "car.restored"
+	ActionCarPhotoAdded         = "car_photo.added"
+	ActionCarPhotoDeleted       = "car_photo.deleted"
+	ActionCarPhotoPrimary       = "car_photo.made_primary"
+	ActionCarPhotosReordered    = "car.photos_reordered"
+	ActionBidPlaced             = "bid.placed"
+	ActionPaymentRequested      = "payment.requested"
+	ActionUserCreated           = "user.created"
+	ActionUserRoleChanged       = "user.role_changed"
+	ActionEmailVerified         = "user.email_verified"
+	ActionPhoneLinked           = "user.phone_linked"
+	ActionPasswordReset         = "user.password_reset"
+	ActionOrganizationCreated   = "organization.created"
+	ActionAPIKeyCreated         = "api_key.created"
+	ActionAPIKeyUpdated         = "api_key.updated"
+	ActionAPIKeyRevoked         = "api_key.revoked"
+	ActionKYCDocumentUploaded   = "kyc_document.uploaded"
+	ActionVerificationSubmitted = "seller_verification.submitted"
+	ActionVerificationApproved  = "seller_verification.approved"
+	ActionVerificationRejected  = "seller_verification.rejected"
+	ActionCityCreated           = "city.created"
+	ActionCityUpdated           = "city.updated"
+	ActionCityDeleted           = "city.deleted"
+	ActionCategoryCreated       = "category.created"
+	ActionCategoryUpdated       = "category.updated"
+	ActionCategoryDeleted       = "category.deleted"
+	ActionCategorySchemaChanged = "category.schema_changed"
+	ActionVehicleCreated        = "vehicle.created"
+	ActionVehiclesMerged        = "vehicle.merged"
+)
+
+// Entity types recorded in the audit log.
+const (
+	EntityCar                = "car"
+	EntityCarPhoto           = "car_photo"
+	EntityBid                = "bid"
+	EntityUser               = "user"
+	EntityOrganization       = "organization"
+	EntityAPIKey             = "api_key"
+	EntityKYCDocument        = "kyc_document"
+	EntitySellerVerification = "seller_verification"
+	EntityCity               = "city"
+	EntityCategory           = "category"
+	EntityVehicleMake        = "vehicle_make"
+	EntityVehicleModel       = "vehicle_model"
+	EntityVehicleTrim        = "vehicle_trim"
+)
+
+// Change is a state-changing action to record. Before and After are marshalled to JSON; either may be nil.
+type Change struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     interface{}
+	After      interface{}
+}
+
+// Entry is a row of the append-only audit log.
+type Entry struct {
+	ID         int64          `json:"id" db:"id"`
+	OccurredAt models.Time    `json:"occurred_at" db:"occurred_at"`
+	ActorID    string         `json:"actor_id,omitempty" db:"actor_id"`
+	ActorRole  string         `json:"actor_role,omitempty" db:"actor_role"`
+	APIKeyID   string         `json:"api_key_id,omitempty" db:"api_key_id"`
+	IPAddress  string         `json:"ip_address,omitempty" db:"ip_address"`
+	RequestID  string         `json:"request_id,omitempty" db:"request_id"`
+	Action     string         `json:"action" db:"action"`
+	EntityType string         `json:"entity_type" db:"entity_type"`
+	EntityID   string         `json:"entity_id" db:"entity_id"`
+	Before     types.JSONText `json:"before" db:"before"`
+	After      types.JSONText `json:"after" db:"after"`
+	// Diff maps every changed top-level field to its before and after values.
+	Diff types.JSONText `json:"diff" db:"diff"`
+}
+
+// Filter selects a page of audit entries. Empty fields match everything.
+type Filter struct {
+	EntityType string
+	EntityID   string
+	ActorID    string
+	models.PageRequest
+}
+
+// NewEntry builds the audit log entry of change, attributed to the principal, request id and client ip in ctx.
+func NewEntry(ctx context.Context, change Change) (*Entry, error) {
+	before, err := json.Marshal(change.Before)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := json.Marshal(change.After)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := Entry{
+		Action:     change.Action,
+		EntityType: change.EntityType,
+		EntityID:   change.EntityID,
+		Before:     types.JSONText(before),
+		After:      types.JSONText(after),
+		Diff:       types.JSONText(changes),
+	}
+
+	entry.RequestID, _ = ctx.Value(RequestIDKey).(string)
+	entry.IPAddress, _ = ctx.Value(ClientIPKey).(string)
+
+	if principal, ok := authmodels.PrincipalFromContext(ctx); ok {
+		entry.ActorID = principal.UserID
+		entry.ActorRole = string(principal.Role)
+		entry.APIKeyID = principal.APIKeyID
+	}
+
+	return &entry, nil
+}
+
+// fieldChange is the value of a field before and after a change.
+type fieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// diff compares the top-level fields of two JSON objects. null stands for an object without fields.
+func diff(before []byte, after []byte) ([]byte, error) {
+	var beforeFields, afterFields map[string]interface{}
+
+	if err := json.Unmarshal(before, &beforeFields); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(after, &afterFields); err != nil {
+		return nil, err
+	}
+
+	changes := map[string]fieldChange{}
+
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = fieldChange{Before: value, After: afterFields[field]}
+		}
+	}
+
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = fieldChange{Before: nil, After: value}
+		}
+	}
+
+	return json.Marshal(changes)
+}
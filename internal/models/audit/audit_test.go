@@ -0,0 +1,30 @@
+package auditmodels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{"created", `null`, `{"id":"c1","price":"100"}`, `{"id":{"before":null,"after":"c1"},"price":{"before":null,"after":"100"}}`},
+		{"updated", `{"id":"c1","price":"100","tags":["a"]}`, `{"id":"c1","price":"120","tags":["a"]}`, `{"price":{"before":"100","after":"120"}}`},
+		{"field removed", `{"id":"c1","color":"red"}`, `{"id":"c1"}`, `{"color":{"before":"red","after":null}}`},
+		{"unchanged", `{"id":"c1"}`, `{"id":"c1"}`, `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diff([]byte(tt.before), []byte(tt.after))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package authmodels
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid, expired or revoked api key")
+	ErrInvalidScope   = errors.New("invalid api key scope")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// Scopes that can be granted to an API key.
+const (
+	ScopeCarsRead  = "cars:read"
+	ScopeCarsWrite = "cars:write"
+	ScopeBidsRead  = "bids:read"
+	ScopeBidsWrite = "bids:write"
+)
+
+// ValidScopes lists every grantable scope.
+var ValidScopes = []string{ScopeCarsRead, ScopeCarsWrite, ScopeBidsRead, ScopeBidsWrite}
+
+// Organization is a partner, such as a dealership, that integrates through API keys.
+// API keys act on behalf of the organization's owner.
+type Organization struct {
+	ID        string      `json:"id" db:"id"`
+	Name      string      `json:"name" db:"name"`
+	OwnerID   string      `json:"owner_id" db:"owner_id"`
+	CreatedAt models.Time `json:"created_at" db:"created_at"`
+}
+
+type APIKey struct {
+	ID             string         `json:"id" db:"id"`
+	OrganizationID string         `json:"organization_id" db:"organization_id"`
+	Name           string         `json:"name" db:"name"`
+	Prefix         string         `json:"prefix" db:"prefix"`
+	Scopes         pq.StringArray `json:"scopes" db:"scopes"`
+	ExpiresAt      models.Time    `json:"expires_at" db:"expires_at"`
+	LastUsedAt     models.Time    `json:"last_used_at" db:"last_used_at"`
+	CreatedBy      *string        `json:"created_by" db:"created_by"`
+	CreatedAt      models.Time    `json:"created_at" db:"created_at"`
+	RevokedAt      models.Time    `json:"revoked_at" db:"revoked_at"`
+}
+
+// APIKeyCredentials is an API key together with what is needed to authenticate it.
+type APIKeyCredentials struct {
+	APIKey
+	KeyHash   string `db:"key_hash"`
+	OwnerID   string `db:"owner_id"`
+	OwnerRole string `db:"owner_role"`
+}
+
+type CreateOrganizationRequest struct {
+	Name    string `json:"name"`
+	OwnerID string `json:"owner_id"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string      `json:"name"`
+	Scopes    []string    `json:"scopes"`
+	ExpiresAt models.Time `json:"expires_at"`
+}
+
+type UpdateAPIKeyRequest struct {
+	Name      *string      `json:"name"`
+	Scopes    []string     `json:"scopes"`
+	ExpiresAt *models.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is returned once when a key is issued. The plain key can not be retrieved again.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,183 @@
+package authmodels
+
+import (
+	"context"
+	"errors"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+var (
+	ErrEmailTaken          = errors.New("an account with this email already exists")
+	ErrInvalidEmail        = errors.New("invalid email address")
+	ErrWeakPassword        = errors.New("password must be between 8 and 72 characters")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again.
+	// The whole session is revoked because the token has most likely been stolen.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrUnauthenticated    = errors.New("request is not authenticated")
+	ErrInvalidRole        = errors.New("invalid role")
+	// ErrInvalidAccountToken is returned for email verification and password reset tokens that are forged,
+	// expired or already used.
+	ErrInvalidAccountToken = errors.New("invalid or expired token")
+	ErrTooManyRequests     = errors.New("too many requests, try again later")
+	ErrInvalidPhone        = errors.New("invalid phone number")
+	// ErrInvalidOTP is returned for one-time codes that are wrong, expired or have run out of attempts.
+	ErrInvalidOTP = errors.New("invalid or expired code")
+	ErrPhoneTaken = errors.New("an account with this phone number already exists")
+)
+
+// Purposes of single-use account tokens.
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// Role is the access level of a caller. Roles are carried in the `role` claim of access tokens.
+type Role string
+
+const (
+	RoleAnonymous Role = "anonymous"
+	RoleBuyer     Role = "buyer"
+	RoleSeller    Role = "seller"
+	RoleAdmin     Role = "admin"
+)
+
+// Valid reports whether r can be assigned to an account.
+func (r Role) Valid() bool {
+	return r == RoleBuyer || r == RoleSeller || r == RoleAdmin
+}
+
+// PrincipalKey is the context key under which the authenticated Principal is stored.
+const PrincipalKey = "principal"
+
+// Principal is the authenticated caller of a request.
+// Principals authenticated by an API key act for the key's organization owner, limited to the key's scopes.
+type Principal struct {
+	UserID         string
+	SessionID      string
+	Role           Role
+	APIKeyID       string
+	OrganizationID string
+	Scopes         []string
+}
+
+// IsAdmin reports whether the principal has the admin role.
+func (p *Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// IsAPIKey reports whether the principal was authenticated with an API key.
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != ""
+}
+
+// HasScope reports whether an API key principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrincipalFromContext returns the Principal set by the authorization middleware.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(PrincipalKey).(*Principal)
+
+	return principal, ok && principal != nil
+}
+
+// WithPrincipal returns a copy of ctx carrying principal, for callers outside an http request.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	//nolint:staticcheck
+	return context.WithValue(ctx, PrincipalKey, principal)
+}
+
+// ClientInfo describes the client a session is opened from.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+type SignupRequest struct {
+	UserName string `json:"user_name"`
+	Email    string `json:"user_email"`
+	Password string `json:"password"`
+	// Role is either buyer (the default) or seller.
+	Role Role `json:"role"`
+}
+
+type SetRoleRequest struct {
+	Role Role `json:"role"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"user_email"`
+	Password string `json:"password"`
+}
+
+type AccountTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"user_email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type PhoneOTPRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+type PhoneVerifyRequest struct {
+	PhoneNumber string `json:"phone_number"`
+	Code        string `json:"code"`
+	// UserName is used when the phone number has no account yet.
+	UserName string `json:"user_name"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken  string        `json:"access_token"`
+	TokenType    string        `json:"token_type"`
+	ExpiresIn    int64         `json:"expires_in"`
+	RefreshToken string        `json:"refresh_token"`
+	User         *models.Users `json:"user,omitempty"`
+}
+
+// Session is a login of a user. All refresh tokens rotated from the same login share a session.
+type Session struct {
+	ID         string      `json:"id" db:"id"`
+	UserID     string      `json:"user_id" db:"user_id"`
+	UserAgent  *string     `json:"user_agent" db:"user_agent"`
+	IPAddress  *string     `json:"ip_address" db:"ip_address"`
+	CreatedAt  models.Time `json:"created_at" db:"created_at"`
+	LastUsedAt models.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt  models.Time `json:"expires_at" db:"expires_at"`
+	Current    bool        `json:"current" db:"-"`
+}
+
+// PhoneOTP is an outstanding one-time code sent to a phone number.
+type PhoneOTP struct {
+	ID       string `db:"id"`
+	CodeHash string `db:"code_hash"`
+	Attempts int    `db:"attempts"`
+}
+
+// Credentials are the login fields of a user account.
+type Credentials struct {
+	UserID       string  `db:"user_id"`
+	PasswordHash *string `db:"password_hash"`
+}
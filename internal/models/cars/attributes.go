@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidAttributes is wrapped by AttributesError.
+	ErrInvalidAttributes = errors.New("extras do not match the attributes of the category")
+	// ErrInvalidAttributeSchema is returned for attribute schemas that are not JSON Schemas of objects.
+	ErrInvalidAttributeSchema = errors.New("invalid attribute schema")
+)
+
+// FieldError is a field of a request that is not valid.
+type FieldError struct {
+	// Field is the path of the field, such as extras.axles.
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// AttributesError lists the extras of a car that do not match the attribute schema of its category.
+type AttributesError struct {
+	Category string
+	Fields   []FieldError
+}
+
+func (e *AttributesError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = field.Field + ": " + field.Message
+	}
+
+	return fmt.Sprintf("%s %s: %s", ErrInvalidAttributes, e.Category, strings.Join(fields, "; "))
+}
+
+func (e *AttributesError) Unwrap() error {
+	return ErrInvalidAttributes
+}
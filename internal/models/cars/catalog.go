@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+var (
+	// ErrUnknownCategory is returned for cars in a category that is not known.
+	ErrUnknownCategory = errors.New("category is not a known category")
+	// ErrInvalidCatalogEntry is returned for cities and categories without a name or with a malformed id.
+	ErrInvalidCatalogEntry = errors.New("invalid catalog entry")
+	ErrCityExists          = errors.New("a city with this id or name already exists")
+	ErrCategoryExists      = errors.New("a category with this id or name already exists")
+	// ErrCityInUse and ErrCategoryInUse are returned when deleting a city or a category that cars refer to.
+	ErrCityInUse     = errors.New("the city has cars and can not be deleted")
+	ErrCategoryInUse = errors.New("the category has cars and can not be deleted")
+)
+
+// City is a city cars can be listed in.
+type City struct {
+	ID        string `json:"id" db:"id"`
+	Name      string `json:"name" db:"name"`
+	Region    string `json:"region" db:"region"`
+	CreatedAt Time   `json:"created_at" db:"created_at"`
+	// Listings is the number of open auctions in the city.
+	Listings int `json:"listings" db:"listings"`
+}
+
+// Category is a kind of vehicle, such as sedan or pickup.
+type Category struct {
+	ID          string `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	// AttributeSchema is the JSON Schema the extras of cars in the category must match, if any.
+	AttributeSchema *types.JSONText `json:"attribute_schema,omitempty" db:"attribute_schema"`
+	CreatedAt       Time            `json:"created_at" db:"created_at"`
+	// Listings is the number of open auctions in the category.
+	Listings int `json:"listings" db:"listings"`
+}
+
+// CityRequest creates or replaces a city. The id of a new city defaults to a slug of its name.
+type CityRequest struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+// CategoryRequest creates or replaces a category. The id of a new category defaults to a slug of its name.
+type CategoryRequest struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// unaccent maps the accented letters of French names onto plain letters.
+var unaccent = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "ö", "o", "ù", "u", "û", "u", "ü", "u", "ÿ", "y",
+)
+
+// Slug returns the catalog id of name: lower case letters and digits separated by dashes.
+func Slug(name string) string {
+	return strings.Trim(slugSeparators.ReplaceAllString(unaccent.Replace(strings.ToLower(name)), "-"), "-")
+}
+
+// catalogEntry trims a catalog request and checks that it has a name and a valid id, defaulting the id to a slug of
+// the name.
+func catalogEntry(id string, name string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", fmt.Errorf("%w: name is required", ErrInvalidCatalogEntry)
+	}
+
+	if id = strings.TrimSpace(id); id == "" {
+		id = Slug(name)
+	}
+
+	if !slugPattern.MatchString(id) {
+		return "", "", fmt.Errorf("%w: id must be lower case letters and digits separated by dashes", ErrInvalidCatalogEntry)
+	}
+
+	return id, name, nil
+}
+
+// City validates r and returns the city it describes.
+func (r CityRequest) City() (City, error) {
+	id, name, err := catalogEntry(r.ID, r.Name)
+	if err != nil {
+		return City{}, err
+	}
+
+	return City{ID: id, Name: name, Region: strings.TrimSpace(r.Region)}, nil
+}
+
+// Category validates r and returns the category it describes.
+func (r CategoryRequest) Category() (Category, error) {
+	id, name, err := catalogEntry(r.ID, r.Name)
+	if err != nil {
+		return Category{}, err
+	}
+
+	return Category{ID: id, Name: name, Description: strings.TrimSpace(r.Description)}, nil
+}
@@ -1,5 +1,54 @@
 package models
 
+import "errors"
+
+var (
+	// ErrInvalidBidExpiration is returned when an auction deadline is missing or not in the future.
+	ErrInvalidBidExpiration = errors.New("bid_expiration_time must be a future RFC 3339 timestamp")
+	// ErrForbidden is returned when the authenticated user does not own the resource they act on.
+	ErrForbidden = errors.New("you are not allowed to access this resource")
+	ErrOwnCarBid = errors.New("sellers cannot bid on their own cars")
+	// ErrAccountNotVerified is returned when a user who has confirmed neither an email address nor a phone number
+	// tries to bid or list a car.
+	ErrAccountNotVerified = errors.New("verify your email address or phone number before bidding or listing cars")
+	// ErrPhoneNotVerified is returned when a user without a verified phone number asks to pay through mobile money.
+	ErrPhoneNotVerified = errors.New("verify a phone number before paying with mobile money")
+	// ErrVersionConflict is returned when a car has changed since the version the caller based their update on.
+	ErrVersionConflict = errors.New("the car has changed since it was read; fetch it again and retry")
+	// ErrInvalidPatch is returned for merge patches that are not a JSON object or do not describe a valid car.
+	ErrInvalidPatch = errors.New("invalid merge patch")
+	// ErrImmutableField is returned when an update tries to change a field that is set by the server.
+	ErrImmutableField = errors.New("field can not be changed")
+	// ErrFieldLocked is returned when an update changes a price-affecting field of a car that has bids.
+	ErrFieldLocked = errors.New("field can not be changed once bidding has started")
+	// ErrBidExpirationLocked is returned when the deadline of a car with bids is brought forward.
+	ErrBidExpirationLocked = errors.New("bid_expiration_time can only be extended once bidding has started")
+	// ErrCarWithdrawn is returned when acting on a car that has been withdrawn.
+	ErrCarWithdrawn = errors.New("the car has been withdrawn")
+	// ErrAuctionClosed is returned when bidding on a car whose auction has ended.
+	ErrAuctionClosed = errors.New("the auction of this car has ended")
+	// ErrCarNotWithdrawn is returned when restoring a car that is listed.
+	ErrCarNotWithdrawn = errors.New("the car has not been withdrawn")
+	// ErrInvalidFuelType is returned for fuel types that are not known.
+	ErrInvalidFuelType = errors.New("fuel_type must be petrol, diesel, hybrid, electric or lpg")
+	// ErrInvalidCar is returned for cars with out of range or malformed values.
+	ErrInvalidCar = errors.New("invalid car")
+	// ErrInvalidVIN is returned for VINs that are malformed or fail the ISO 3779 check digit.
+	ErrInvalidVIN = errors.New("invalid vin")
+	// ErrDuplicateVIN is returned when a car with the same VIN is already listed in an open auction.
+	ErrDuplicateVIN = errors.New("a car with this vin is already listed")
+	// ErrUnknownCity is returned for cars in a city that is not known.
+	ErrUnknownCity = errors.New("city_id is not a known city")
+	// ErrInvalidSearch is returned for search queries that are empty or too long.
+	ErrInvalidSearch = errors.New("the search query must be between 1 and 200 characters")
+	// ErrWithdrawalReasonRequired is returned when a car is withdrawn without a reason.
+	ErrWithdrawalReasonRequired = errors.New("a reason is required to withdraw a car")
+	// ErrWithdrawalClosed is returned when a seller withdraws a car too close to the end of its auction.
+	ErrWithdrawalClosed = errors.New("the car can no longer be withdrawn this close to the end of its auction")
+)
+
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Fields lists the invalid fields of requests refused with a validation error.
+	Fields []FieldError `json:"fields,omitempty"`
 }
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidFilter is returned for car filters with unknown options or empty ranges.
+var ErrInvalidFilter = errors.New("invalid car filter")
+
+// CarSort orders car listings.
+type CarSort string
+
+const (
+	SortNewest        CarSort = "newest"
+	SortEndingSoonest CarSort = "ending_soonest"
+	SortPriceAsc      CarSort = "price_asc"
+	SortPriceDesc     CarSort = "price_desc"
+	SortMostBids      CarSort = "most_bids"
+)
+
+// Valid reports whether s is a known sort. The empty sort lists the newest cars first.
+func (s CarSort) Valid() bool {
+	switch s {
+	case "", SortNewest, SortEndingSoonest, SortPriceAsc, SortPriceDesc, SortMostBids:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuctionStatus tells whether a car can still be bid on.
+type AuctionStatus string
+
+const (
+	AuctionOpen  AuctionStatus = "open"
+	AuctionEnded AuctionStatus = "ended"
+)
+
+// CarFilter selects and orders car listings. Empty fields do not filter; multi-value fields match any of their values,
+// ignoring case. Withdrawn cars are never listed. Filters are encoded in JSON with the names of the GET /cars query
+// parameters.
+type CarFilter struct {
+	CityID   string `json:"city_id,omitempty"`
+	Category string `json:"category_id,omitempty"`
+	// VIN is matched exactly once it has been normalized.
+	VIN string `json:"vin,omitempty"`
+	// MakeID and ModelID match the taxonomy ids cars were mapped onto.
+	MakeID  string `json:"make_id,omitempty"`
+	ModelID string `json:"model_id,omitempty"`
+
+	MinPrice   *int64 `json:"min_price,omitempty"`
+	MaxPrice   *int64 `json:"max_price,omitempty"`
+	MinMileage *int64 `json:"min_mileage,omitempty"`
+	MaxMileage *int64 `json:"max_mileage,omitempty"`
+	MinYear    *int64 `json:"min_year,omitempty"`
+	MaxYear    *int64 `json:"max_year,omitempty"`
+
+	FuelTypes   []string        `json:"fuel_type,omitempty"`
+	EngineTypes []string        `json:"engine_type,omitempty"`
+	Models      []string        `json:"model,omitempty"`
+	Statuses    []AuctionStatus `json:"status,omitempty"`
+
+	// Sort is ignored by searches, which are ordered by relevance.
+	Sort        CarSort `json:"-"`
+	PageRequest `json:"-"`
+}
+
+// Validate checks the options and ranges of f.
+func (f CarFilter) Validate() error {
+	if !f.Sort.Valid() {
+		return fmt.Errorf("%w: unknown sort %q", ErrInvalidFilter, f.Sort)
+	}
+
+	for _, status := range f.Statuses {
+		if status != AuctionOpen && status != AuctionEnded {
+			return fmt.Errorf("%w: unknown auction status %q", ErrInvalidFilter, status)
+		}
+	}
+
+	ranges := []struct {
+		name     string
+		min, max *int64
+	}{
+		{"price", f.MinPrice, f.MaxPrice},
+		{"mileage", f.MinMileage, f.MaxMileage},
+		{"year", f.MinYear, f.MaxYear},
+	}
+
+	for _, r := range ranges {
+		if (r.min != nil && *r.min < 0) || (r.max != nil && *r.max < 0) {
+			return fmt.Errorf("%w: min_%s and max_%s can not be negative", ErrInvalidFilter, r.name, r.name)
+		}
+
+		if r.min != nil && r.max != nil && *r.min > *r.max {
+			return fmt.Errorf("%w: min_%s is greater than max_%s", ErrInvalidFilter, r.name, r.name)
+		}
+	}
+
+	return nil
+}
@@ -1,54 +1,235 @@
 package models
 
 import (
-	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx/types"
 )
 
 type Cars struct {
-	ID                string `json:"id"`
-	SellerID          string `json:"seller_id"`
-	CarName           string `json:"car_name"`
-	DatePosted        string `json:"date_posted"`
-	BidingPrice       string `json:"biding_price"`
-	BidExpirationTime string `json:"bid_expiration_time"`
-	CityID            string `json:"city_id"`
-	EngineType        string `json:"engine_type"`
-	CarModel          string `json:"car_model"`
-	NumberOfBids          string `json:"number_of_bids"`
-	Mileage           string `json:"mileage"`
-	FuelType          string `json:"fuel_type"`
-	CarphotoUrl       string `json:"photo_url"`
-	Category          string `json:"category"`
-	Description       string `json:"description"`
+	ID                string `json:"id" db:"id"`
+	SellerID          string `json:"seller_id" db:"seller_id"`
+	CarName           string `json:"car_name" db:"car_name"`
+	DatePosted        Time   `json:"date_posted" db:"date_posted"`
+	BidingPrice       int64  `json:"biding_price" db:"biding_price"`
+	BidExpirationTime Time   `json:"bid_expiration_time" db:"bid_expiration_time"`
+	CityID            string `json:"city_id" db:"city_id"`
+	EngineType        string `json:"engine_type" db:"engine_type"`
+	// Make and Year are decoded from the VIN when the seller leaves them out.
+	Make     string `json:"make" db:"make"`
+	CarModel string `json:"car_model" db:"car_model"`
+	Trim     string `json:"trim,omitempty" db:"trim"`
+	// MakeID, ModelID and TrimID are the taxonomy entries matched with Make, CarModel and Trim. They are set when the
+	// car is saved and can not be chosen directly.
+	MakeID  string `json:"make_id,omitempty" db:"make_id"`
+	ModelID string `json:"model_id,omitempty" db:"model_id"`
+	TrimID  string `json:"trim_id,omitempty" db:"trim_id"`
+	Year    int    `json:"year,omitempty" db:"year"`
+	// VIN is unique among the cars listed in open auctions.
+	VIN string `json:"vin,omitempty" db:"vin"`
+	// NumberOfBids is counted from the bids on the car.
+	NumberOfBids int      `json:"number_of_bids" db:"number_of_bids"`
+	Mileage      int      `json:"mileage" db:"mileage"`
+	FuelType     FuelType `json:"fuel_type" db:"fuel_type"`
+	// CarphotoUrl is a link supplied by the seller. Uploaded photos are listed in Photos.
+	CarphotoUrl string `json:"photo_url" db:"photo_url"`
+	// ThumbnailKey names the thumbnail of the primary photo in blob storage. ThumbnailURL is signed and expires.
+	ThumbnailKey string     `json:"-" db:"thumbnail_key"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty" db:"-"`
+	Photos       []CarPhoto `json:"photos,omitempty" db:"-"`
+	Category     string     `json:"category" db:"category"`
+	Description  string     `json:"description" db:"description"`
+	// Extras holds open-ended attributes as a JSON object.
+	Extras    types.JSONText `json:"extras,omitempty" db:"extras"`
+	UpdatedAt Time           `json:"updated_at" db:"updated_at"`
+	// Version is incremented by every change to the car and by every bid on it.
+	Version int `json:"version" db:"version"`
+	// WithdrawnAt is set when the seller or an admin takes the car down. Withdrawn cars are not listed and can not be
+	// bid on.
+	WithdrawnAt      Time   `json:"withdrawn_at" db:"withdrawn_at"`
+	WithdrawnBy      string `json:"withdrawn_by,omitempty" db:"withdrawn_by"`
+	WithdrawalReason string `json:"withdrawal_reason,omitempty" db:"withdrawal_reason"`
+}
+
+// FuelType is the fuel a car runs on.
+type FuelType string
+
+const (
+	FuelPetrol   FuelType = "petrol"
+	FuelDiesel   FuelType = "diesel"
+	FuelHybrid   FuelType = "hybrid"
+	FuelElectric FuelType = "electric"
+	FuelLPG      FuelType = "lpg"
+)
+
+// fuelTypeAliases maps the names sellers use in English and French onto fuel types.
+var fuelTypeAliases = map[string]FuelType{
+	"petrol": FuelPetrol, "gasoline": FuelPetrol, "gas": FuelPetrol, "essence": FuelPetrol, "super": FuelPetrol,
+	"diesel": FuelDiesel, "gasoil": FuelDiesel, "gazole": FuelDiesel,
+	"hybrid": FuelHybrid, "hybride": FuelHybrid,
+	"electric": FuelElectric, "electrique": FuelElectric, "électrique": FuelElectric, "ev": FuelElectric,
+	"lpg": FuelLPG, "gpl": FuelLPG,
 }
 
+// ParseFuelType returns the fuel type named name, ignoring case. The empty name is the unknown fuel type.
+func ParseFuelType(name string) (FuelType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", nil
+	}
+
+	fuel, ok := fuelTypeAliases[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidFuelType, name)
+	}
+
+	return fuel, nil
+}
+
+// Normalize checks the values of a car submitted by a seller and normalizes its fuel type.
+func (e *Cars) Normalize() error {
+	fuel, err := ParseFuelType(string(e.FuelType))
+	if err != nil {
+		return err
+	}
+
+	e.FuelType = fuel
+
+	if e.BidingPrice < 0 || e.Mileage < 0 {
+		return fmt.Errorf("%w: biding_price and mileage can not be negative", ErrInvalidCar)
+	}
+
+	if e.Year != 0 && (e.Year < 1900 || e.Year > 2100) {
+		return fmt.Errorf("%w: year must be between 1900 and 2100", ErrInvalidCar)
+	}
+
+	if len(e.Extras) > 0 {
+		extras := map[string]json.RawMessage{}
+		if err := json.Unmarshal(e.Extras, &extras); err != nil {
+			return fmt.Errorf("%w: extras must be a JSON object", ErrInvalidCar)
+		}
+
+		if len(extras) == 0 {
+			e.Extras = nil
+		}
+	}
+
+	return nil
+}
+
+// CarMigrationIssue is a property of a car that could not be moved into a typed column when car properties were
+// normalized.
+type CarMigrationIssue struct {
+	ID        int64  `json:"id" db:"id"`
+	CarID     string `json:"car_id" db:"car_id"`
+	Field     string `json:"field" db:"field"`
+	Value     string `json:"value" db:"value"`
+	Reason    string `json:"reason" db:"reason"`
+	CreatedAt Time   `json:"created_at" db:"created_at"`
+}
+
+// CarSearchResult is a car matching a full-text search.
+type CarSearchResult struct {
+	Car Cars `json:"car"`
+	// Rank orders the results; higher is more relevant.
+	Rank float64 `json:"rank"`
+	// Highlight is an HTML snippet of the listing with the matching words in <mark> elements.
+	Highlight string `json:"highlight"`
+}
+
+// Search highlights are delimited by these private use characters, which do not occur in listings, until they are
+// rendered as HTML.
+const (
+	HighlightStart = "\uE000"
+	HighlightStop  = "\uE001"
+)
+
+// MaxSearchLength is the longest search query accepted.
+const MaxSearchLength = 200
+
+// WithdrawCarRequest is the body of a car withdrawal.
+type WithdrawCarRequest struct {
+	Reason string `json:"reason"`
+}
 
 type Users struct {
-	User_id  string `json:"user_id" db:"user_id"`
-	UserName string `json:"user_name" db:"user_name"`
-	Email    string `json:"user_email" db:"user_email"`
+	User_id   string `json:"user_id" db:"user_id"`
+	UserName  string `json:"user_name" db:"user_name"`
+	Email     string `json:"user_email" db:"user_email"`
+	Role      string `json:"role" db:"role"`
+	CreatedAt Time   `json:"created_at" db:"created_at"`
+	// EmailVerifiedAt is null until the user confirms their email address.
+	EmailVerifiedAt Time `json:"email_verified_at" db:"email_verified_at"`
+	// PhoneNumber is an E.164 number that the user has confirmed with a one-time code.
+	PhoneNumber     string `json:"phone_number,omitempty" db:"phone_number"`
+	PhoneVerifiedAt Time   `json:"phone_verified_at" db:"phone_verified_at"`
+	// SellerStatus is unverified, pending, verified or rejected. Only verified sellers can publish listings.
+	SellerStatus string `json:"seller_status" db:"seller_status"`
 }
 type Bids struct {
 	BidID     string `json:"bid_id" db:"bid_id"`
 	CarID     string `json:"car_id" db:"car_id"`
-	CreatedAt string `json:"created_at" db:"created_at"`
+	UserID    string `json:"user_id,omitempty" db:"user_id"`
+	CreatedAt Time   `json:"created_at" db:"created_at"`
 	Amount    string `json:"bid_amount" db:"bid_amount"`
-	Email     string `json:"email" db:"email"`
-	UserName  string `json:"user_name" db:"user_name"`
+	Email     string `json:"email,omitempty" db:"email"`
+	UserName  string `json:"user_name,omitempty" db:"user_name"`
 }
 
-func (e Cars) Value() (driver.Value, error) {
-	return json.Marshal(e)
+// Public returns the bid without the bidder's identity.
+func (b Bids) Public() Bids {
+	return Bids{
+		BidID:     b.BidID,
+		CarID:     b.CarID,
+		CreatedAt: b.CreatedAt,
+		Amount:    b.Amount,
+	}
 }
 
-func (e *Cars) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		//nolint:goerr113
-		return fmt.Errorf("type assertion to []byte failed")
+// Public returns the user without contact details.
+func (u Users) Public() Users {
+	return Users{
+		User_id:      u.User_id,
+		UserName:     u.UserName,
+		Role:         u.Role,
+		CreatedAt:    u.CreatedAt,
+		SellerStatus: u.SellerStatus,
+	}
+}
+
+// ETag returns the entity tag of the car's current version.
+func (e Cars) ETag() string {
+	return fmt.Sprintf("%q", strconv.Itoa(e.Version))
+}
+
+// LockedFieldChanged returns the first field affecting the price of the car that differs between e and other. These
+// fields can not change once bidding has started.
+func (e Cars) LockedFieldChanged(other Cars) (string, bool) {
+	fields := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+	}{
+		{"biding_price", e.BidingPrice, other.BidingPrice},
+		{"car_name", e.CarName, other.CarName},
+		{"make", e.Make, other.Make},
+		{"car_model", e.CarModel, other.CarModel},
+		{"trim", e.Trim, other.Trim},
+		{"vin", e.VIN, other.VIN},
+		{"year", e.Year, other.Year},
+		{"engine_type", e.EngineType, other.EngineType},
+		{"fuel_type", e.FuelType, other.FuelType},
+		{"mileage", e.Mileage, other.Mileage},
+	}
+
+	for _, field := range fields {
+		if field.before != field.after {
+			return field.name, true
+		}
 	}
 
-	return json.Unmarshal(bytes, &e)
+	return "", false
 }
@@ -0,0 +1,25 @@
+package models
+
+import "errors"
+
+// ErrInvalidCursor is returned for page cursors that were not issued for the list being read.
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// PageRequest selects a page of a list.
+type PageRequest struct {
+	// Cursor is the NextCursor of the previous page, or empty for the first page.
+	Cursor string
+	// Count is the page size.
+	Count uint
+	// WithTotal asks for the number of items in the whole list.
+	WithTotal bool
+}
+
+// Page is a page of a list.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor reads the following page. It is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is only counted on request.
+	Total *int `json:"total,omitempty"`
+}
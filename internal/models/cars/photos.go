@@ -0,0 +1,51 @@
+package models
+
+import "errors"
+
+var (
+	ErrNoPhotos             = errors.New("upload at least one photo in the photos form field")
+	ErrPhotoTooLarge        = errors.New("photos must be at most 10 MB")
+	ErrUnsupportedPhotoType = errors.New("photos must be JPEG, PNG or GIF images")
+	// ErrPhotoDimensions is returned for images that are empty or too large to resize.
+	ErrPhotoDimensions = errors.New("photos must be at most 40 megapixels")
+	ErrTooManyPhotos   = errors.New("a car can have at most 20 photos")
+	// ErrInvalidPhotoOrder is returned when a new photo order does not list every photo of the car exactly once.
+	ErrInvalidPhotoOrder = errors.New("photo_ids must list every photo of the car once")
+)
+
+const (
+	// MaxPhotoSize is the largest photo that can be uploaded, in bytes.
+	MaxPhotoSize = 10 << 20
+	// MaxPhotoPixels bounds the memory used to decode a photo.
+	MaxPhotoPixels = 40_000_000
+	// MaxPhotosPerCar is the number of photos a car can have.
+	MaxPhotosPerCar = 20
+	// MaxPhotosPerUpload is the number of photos that can be sent in one upload.
+	MaxPhotosPerUpload = 5
+	// ThumbnailSize is the length of the longest side of photo thumbnails, in pixels.
+	ThumbnailSize = 320
+)
+
+// CarPhoto is a photo of a car. Photos are listed by position; the primary photo illustrates the car in listings.
+type CarPhoto struct {
+	ID          string `json:"id" db:"id"`
+	CarID       string `json:"car_id" db:"car_id"`
+	Position    int    `json:"position" db:"position"`
+	Primary     bool   `json:"primary" db:"is_primary"`
+	ContentType string `json:"content_type" db:"content_type"`
+	Width       int    `json:"width" db:"width"`
+	Height      int    `json:"height" db:"height"`
+	Size        int    `json:"size" db:"size"`
+	// StorageKey and ThumbnailKey name the photo and its thumbnail in blob storage.
+	StorageKey   string `json:"-" db:"storage_key"`
+	ThumbnailKey string `json:"-" db:"thumbnail_key"`
+	// URL and ThumbnailURL are signed and expire.
+	URL          string `json:"url" db:"-"`
+	ThumbnailURL string `json:"thumbnail_url" db:"-"`
+	UploadedAt   Time   `json:"uploaded_at" db:"uploaded_at"`
+}
+
+// ReorderPhotosRequest lists the ids of every photo of a car in their new order.
+type ReorderPhotosRequest struct {
+	PhotoIDs []string `json:"photo_ids"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrInvalidSavedSearch is returned for saved searches without a name or with an unknown alert frequency.
+	ErrInvalidSavedSearch = errors.New("invalid saved search")
+	// ErrSavedSearchExists is returned when a user already has a saved search with the same name.
+	ErrSavedSearchExists = errors.New("a saved search with this name already exists")
+	// ErrTooManySavedSearches is returned when a user with MaxSavedSearches saved searches saves another.
+	ErrTooManySavedSearches = errors.New("too many saved searches")
+)
+
+const (
+	// MaxSavedSearches is the number of searches a user can save.
+	MaxSavedSearches = 25
+	// maxSavedSearchName is the length of the longest saved search name, in characters.
+	maxSavedSearchName = 100
+)
+
+// AlertFrequency is how often a user is told about the new listings matching their saved searches.
+type AlertFrequency string
+
+const (
+	// AlertsInstant sends alerts as soon as a matching car is listed.
+	AlertsInstant AlertFrequency = "instant"
+	// AlertsDaily gathers the alerts of a day into a single message.
+	AlertsDaily AlertFrequency = "daily"
+)
+
+// SavedSearch is a car filter saved by a user to be alerted of the new listings it matches.
+type SavedSearch struct {
+	ID        string         `json:"id" db:"id"`
+	UserID    string         `json:"user_id" db:"user_id"`
+	Name      string         `json:"name" db:"name"`
+	Frequency AlertFrequency `json:"frequency" db:"frequency"`
+	Filters   CarFilter      `json:"filters" db:"-"`
+	CreatedAt Time           `json:"created_at" db:"created_at"`
+	UpdatedAt Time           `json:"updated_at" db:"updated_at"`
+}
+
+// SavedSearchRequest creates or replaces a saved search. The frequency defaults to instant alerts.
+type SavedSearchRequest struct {
+	Name      string         `json:"name"`
+	Frequency AlertFrequency `json:"frequency"`
+	Filters   CarFilter      `json:"filters"`
+}
+
+// SavedSearch validates r and returns the saved search of userID it describes. VINs in the filters are left to be
+// normalized by the caller.
+func (r SavedSearchRequest) SavedSearch(userID string) (SavedSearch, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxSavedSearchName {
+		return SavedSearch{}, fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidSavedSearch,
+			maxSavedSearchName)
+	}
+
+	frequency := r.Frequency
+	if frequency == "" {
+		frequency = AlertsInstant
+	}
+
+	if frequency != AlertsInstant && frequency != AlertsDaily {
+		return SavedSearch{}, fmt.Errorf("%w: frequency must be instant or daily", ErrInvalidSavedSearch)
+	}
+
+	if err := r.Filters.Validate(); err != nil {
+		return SavedSearch{}, err
+	}
+
+	return SavedSearch{UserID: userID, Name: name, Frequency: frequency, Filters: r.Filters}, nil
+}
+
+// SearchAlert tells a user about a new listing matching one of their saved searches. A car is only announced once to
+// each user, whichever of their searches it matches.
+type SearchAlert struct {
+	UserID            string `db:"user_id"`
+	CarID             string `db:"car_id"`
+	SearchName        string `db:"search_name"`
+	CarName           string `db:"car_name"`
+	BidingPrice       int64  `db:"biding_price"`
+	BidExpirationTime Time   `db:"bid_expiration_time"`
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	// tzdata is embedded so the Africa/Douala fallback works on images without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+// DefaultLocation is assumed for timestamps supplied without a UTC offset.
+var DefaultLocation = mustLoadLocation("Africa/Douala")
+
+// zonelessLayouts are accepted when the input carries no UTC offset.
+var zonelessLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Time is a UTC timestamp that is encoded as RFC 3339 in JSON and stored as timestamptz.
+type Time struct {
+	time.Time
+}
+
+// NewTime returns t as a Time normalized to UTC.
+func NewTime(t time.Time) Time {
+	return Time{Time: t.UTC()}
+}
+
+// ParseTime parses an RFC 3339 timestamp. Inputs without a UTC offset are interpreted in DefaultLocation.
+func ParseTime(value string) (Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return NewTime(t), nil
+	}
+
+	for _, layout := range zonelessLayouts {
+		if t, err := time.ParseInLocation(layout, value, DefaultLocation); err == nil {
+			return NewTime(t), nil
+		}
+	}
+
+	//nolint:goerr113
+	return Time{}, fmt.Errorf("invalid timestamp %q: expected RFC 3339", value)
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(t.UTC().Format(time.RFC3339))
+}
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = Time{}
+
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if value == "" {
+		*t = Time{}
+
+		return nil
+	}
+
+	parsed, err := ParseTime(value)
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+
+	return nil
+}
+
+// Value stores the zero Time as NULL.
+func (t Time) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+
+	return t.UTC(), nil
+}
+
+func (t *Time) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*t = Time{}
+	case time.Time:
+		*t = NewTime(v)
+	default:
+		//nolint:goerr113
+		return fmt.Errorf("cannot scan %T into Time", value)
+	}
+
+	return nil
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return loc
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidVehicleKind = errors.New("vehicle kind must be make, model or trim")
+	// ErrInvalidVehicle is returned for taxonomy entries without a name or without the make or model they belong to.
+	ErrInvalidVehicle = errors.New("invalid vehicle")
+	ErrVehicleExists  = errors.New("a vehicle with this id or name already exists")
+	// ErrInvalidMerge is returned for merges of an entry into itself, or of models or trims of different parents.
+	ErrInvalidMerge = errors.New("invalid vehicle merge")
+)
+
+// VehicleKind is a level of the make, model and trim taxonomy.
+type VehicleKind string
+
+const (
+	VehicleMake  VehicleKind = "make"
+	VehicleModel VehicleKind = "model"
+	VehicleTrim  VehicleKind = "trim"
+)
+
+// AutocompleteLimit is the most suggestions an autocomplete returns.
+const AutocompleteLimit = 10
+
+// ParseVehicleKind returns models.ErrInvalidVehicleKind unless kind is a VehicleKind.
+func ParseVehicleKind(kind string) (VehicleKind, error) {
+	switch k := VehicleKind(strings.ToLower(strings.TrimSpace(kind))); k {
+	case VehicleMake, VehicleModel, VehicleTrim:
+		return k, nil
+	default:
+		return "", ErrInvalidVehicleKind
+	}
+}
+
+// Parent returns the kind of the entries that entries of kind k belong to, if any.
+func (k VehicleKind) Parent() (VehicleKind, bool) {
+	switch k {
+	case VehicleModel:
+		return VehicleMake, true
+	case VehicleTrim:
+		return VehicleModel, true
+	default:
+		return "", false
+	}
+}
+
+// Vehicle is an entry of the taxonomy: a make, a model of a make or a trim of a model.
+type Vehicle struct {
+	Kind VehicleKind `json:"kind" db:"kind"`
+	ID   string      `json:"id" db:"id"`
+	// ParentID is the make of a model and the model of a trim.
+	ParentID string `json:"parent_id,omitempty" db:"parent_id"`
+	Name     string `json:"name" db:"name"`
+	// Aliases are the names of the entries merged into this one. They are matched like the name.
+	Aliases   []string `json:"aliases,omitempty" db:"-"`
+	CreatedAt Time     `json:"created_at" db:"created_at"`
+}
+
+// VehicleRequest adds an entry to the taxonomy. Its id is a slug of its name, prefixed by the id of its parent.
+type VehicleRequest struct {
+	Kind     string `json:"kind"`
+	ParentID string `json:"parent_id"`
+	Name     string `json:"name"`
+}
+
+// Vehicle validates r and returns the entry it describes.
+func (r VehicleRequest) Vehicle() (Vehicle, error) {
+	kind, err := ParseVehicleKind(r.Kind)
+	if err != nil {
+		return Vehicle{}, err
+	}
+
+	name := strings.TrimSpace(r.Name)
+	if Slug(name) == "" {
+		return Vehicle{}, fmt.Errorf("%w: name is required", ErrInvalidVehicle)
+	}
+
+	parentID := strings.TrimSpace(r.ParentID)
+
+	_, hasParent := kind.Parent()
+	if hasParent != (parentID != "") {
+		return Vehicle{}, fmt.Errorf("%w: models need the id of their make and trims the id of their model", ErrInvalidVehicle)
+	}
+
+	return Vehicle{Kind: kind, ID: VehicleID(parentID, name), ParentID: parentID, Name: name}, nil
+}
+
+// VehicleID returns the id of the entry called name under the entry parentID.
+func VehicleID(parentID string, name string) string {
+	return Slug(parentID + " " + name)
+}
+
+// VehicleQuery is an autocomplete query. Makes are suggested unless a make or a model is chosen, in which case its
+// models or its trims are.
+type VehicleQuery struct {
+	Query   string
+	MakeID  string
+	ModelID string
+}
+
+// MergeVehiclesRequest merges the entry FromID into IntoID. Cars of FromID are remapped onto IntoID and the name of
+// FromID is kept as an alias of IntoID.
+type MergeVehiclesRequest struct {
+	Kind   string `json:"kind"`
+	FromID string `json:"from_id"`
+	IntoID string `json:"into_id"`
+}
+
+// VehicleMerge is the outcome of a merge.
+type VehicleMerge struct {
+	Kind   VehicleKind `json:"kind"`
+	FromID string      `json:"from_id"`
+	IntoID string      `json:"into_id"`
+	// Cars is the number of cars remapped, including the cars of merged models and trims.
+	Cars int `json:"cars"`
+}
+
+// VehicleRemap is the outcome of mapping existing cars onto the taxonomy.
+type VehicleRemap struct {
+	Checked  int `json:"checked"`
+	Remapped int `json:"remapped"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// VINInfo is what can be decoded from a VIN without looking it up anywhere.
+type VINInfo struct {
+	VIN string `json:"vin"`
+	// WMI is the world manufacturer identifier, the first three characters of the VIN.
+	WMI string `json:"wmi"`
+	// Manufacturer and Make are empty when the WMI is not known.
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Make         string `json:"make,omitempty"`
+	// ModelYear is zero when the VIN does not encode one.
+	ModelYear int `json:"model_year,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// WatchedCar is a car on a user's watchlist with the state of its auction.
+type WatchedCar struct {
+	Cars
+	// CurrentPrice is the highest bid on the car, or its starting price while it has no bids.
+	CurrentPrice int64 `json:"current_price" db:"current_price"`
+	// SecondsLeft is the time left until the end of the auction, zero once it has ended.
+	SecondsLeft int64 `json:"seconds_left" db:"seconds_left"`
+	WatchedAt   Time  `json:"watched_at" db:"watched_at"`
+}
+
+// Watch is a user watching a car.
+type Watch struct {
+	UserID string `db:"user_id"`
+	CarID  string `db:"car_id"`
+}
@@ -0,0 +1,104 @@
+package sellermodels
+
+import (
+	"errors"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+var (
+	ErrInvalidDocumentKind     = errors.New("invalid document kind")
+	ErrUnsupportedDocumentType = errors.New("documents must be PDF, JPEG or PNG files")
+	ErrDocumentTooLarge        = errors.New("documents must be at most 10 MB")
+	// ErrMissingDocuments is returned when a verification request lacks an identity or a vehicle ownership document.
+	ErrMissingDocuments       = errors.New("upload an identity document and a vehicle ownership document before submitting")
+	ErrVerificationPending    = errors.New("a verification request is already being reviewed")
+	ErrVerificationNotPending = errors.New("this verification request has already been reviewed")
+	ErrAlreadyVerified        = errors.New("seller is already verified")
+	ErrReasonRequired         = errors.New("a reason is required to reject a verification request")
+	ErrVerificationNotFound   = errors.New("verification request not found")
+	// ErrSellerNotVerified is returned when a seller whose identity has not been verified tries to publish a listing.
+	ErrSellerNotVerified = errors.New("only verified sellers can publish listings")
+)
+
+// MaxDocumentSize is the largest document that can be uploaded, in bytes.
+const MaxDocumentSize = 10 << 20
+
+// Status is the seller verification status of a user.
+type Status string
+
+const (
+	StatusUnverified Status = "unverified"
+	StatusPending    Status = "pending"
+	StatusVerified   Status = "verified"
+	StatusRejected   Status = "rejected"
+)
+
+// Review outcomes of a verification request.
+const (
+	VerificationPending  = "pending"
+	VerificationApproved = "approved"
+	VerificationRejected = "rejected"
+)
+
+// DocumentKind is the type of a KYC document.
+type DocumentKind string
+
+const (
+	DocumentNationalID           DocumentKind = "national_id"
+	DocumentPassport             DocumentKind = "passport"
+	DocumentDriversLicense       DocumentKind = "drivers_license"
+	DocumentVehicleRegistration  DocumentKind = "vehicle_registration"
+	DocumentOwnershipCertificate DocumentKind = "ownership_certificate"
+)
+
+// IsIdentity reports whether k proves the seller's identity.
+func (k DocumentKind) IsIdentity() bool {
+	return k == DocumentNationalID || k == DocumentPassport || k == DocumentDriversLicense
+}
+
+// IsOwnership reports whether k proves ownership of a vehicle.
+func (k DocumentKind) IsOwnership() bool {
+	return k == DocumentVehicleRegistration || k == DocumentOwnershipCertificate
+}
+
+func (k DocumentKind) Valid() bool {
+	return k.IsIdentity() || k.IsOwnership()
+}
+
+// Document describes an uploaded KYC document. The content is only served to admins.
+type Document struct {
+	ID             string       `json:"id" db:"id"`
+	UserID         string       `json:"user_id" db:"user_id"`
+	VerificationID *string      `json:"verification_id" db:"verification_id"`
+	Kind           DocumentKind `json:"kind" db:"kind"`
+	FileName       string       `json:"file_name" db:"file_name"`
+	ContentType    string       `json:"content_type" db:"content_type"`
+	Size           int          `json:"size" db:"size"`
+	UploadedAt     models.Time  `json:"uploaded_at" db:"uploaded_at"`
+}
+
+// Verification is a seller's request to be verified and its review.
+type Verification struct {
+	ID          string      `json:"id" db:"id"`
+	UserID      string      `json:"user_id" db:"user_id"`
+	Status      string      `json:"status" db:"status"`
+	SubmittedAt models.Time `json:"submitted_at" db:"submitted_at"`
+	ReviewedBy  *string     `json:"reviewed_by" db:"reviewed_by"`
+	ReviewedAt  models.Time `json:"reviewed_at" db:"reviewed_at"`
+	Reason      *string     `json:"reason" db:"reason"`
+	Documents   []Document  `json:"documents,omitempty" db:"-"`
+}
+
+// SellerVerificationStatus is a user's verification status with their latest request and pending documents.
+type SellerVerificationStatus struct {
+	Status Status `json:"seller_status"`
+	// Latest is the most recent verification request, if any.
+	Latest *Verification `json:"latest_verification"`
+	// Documents are uploaded documents that have not been submitted yet.
+	Documents []Document `json:"documents"`
+}
+
+type ReviewRequest struct {
+	Reason string `json:"reason"`
+}
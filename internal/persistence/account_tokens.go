@@ -0,0 +1,85 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// CreateAccountToken records a single-use token for userID and returns its id.
+func (r *RepositoryPg) CreateAccountToken(ctx context.Context, userID string, purpose string, expiresAt time.Time) (string, error) {
+	var tokenID string
+
+	err := r.db.GetContext(ctx, &tokenID, `INSERT INTO account_tokens(user_id, purpose, expires_at) VALUES($1, $2, $3) RETURNING id`,
+		userID, purpose, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenID, nil
+}
+
+// CountAccountTokens counts the tokens issued to userID for purpose since the given time.
+func (r *RepositoryPg) CountAccountTokens(ctx context.Context, userID string, purpose string, since time.Time) (int, error) {
+	var count int
+
+	err := r.db.GetContext(ctx, &count, `SELECT count(*) FROM account_tokens WHERE user_id = $1 AND purpose = $2 AND created_at >= $3`,
+		userID, purpose, since)
+
+	return count, err
+}
+
+// ConsumeAccountToken marks an unused, unexpired token as used and returns its user id. Other outstanding tokens of the
+// user for the same purpose are invalidated as well.
+func (r *RepositoryPg) ConsumeAccountToken(ctx context.Context, tokenID string, purpose string) (string, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	var userID string
+
+	err = tx.GetContext(ctx, &userID, `UPDATE account_tokens SET used_at = now()
+		WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now() RETURNING user_id`, tokenID, purpose)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", authmodels.ErrInvalidAccountToken
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE account_tokens SET used_at = now() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		userID, purpose)
+	if err != nil {
+		return "", err
+	}
+
+	return userID, tx.Commit()
+}
+
+func (r *RepositoryPg) MarkEmailVerified(ctx context.Context, userID string) (*models.Users, error) {
+	user := models.Users{}
+
+	err := r.db.GetContext(ctx, &user, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, now())
+		WHERE user_id = $1 RETURNING `+userColumns, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdatePassword replaces the password of userID and lifts any login lockout.
+func (r *RepositoryPg) UpdatePassword(ctx context.Context, userID string, passwordHash string) error {
+	_, err := r.db.ExecContext(ctx, `WITH unlocked AS (DELETE FROM login_failures WHERE user_id = $1)
+		UPDATE users SET password_hash = $2 WHERE user_id = $1`, userID, passwordHash)
+
+	return err
+}
@@ -0,0 +1,157 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+)
+
+// apiKeyColumns is the column list selected into authmodels.APIKey.
+const apiKeyColumns = `id, organization_id, name, prefix, scopes, expires_at, last_used_at, created_by, created_at, revoked_at`
+
+func (r *RepositoryPg) CreateOrganization(ctx context.Context, org authmodels.Organization) (*authmodels.Organization, error) {
+	newOrg := authmodels.Organization{}
+
+	err := r.db.GetContext(ctx, &newOrg, `INSERT INTO organizations(name, owner_id) VALUES($1, $2) RETURNING id, name, owner_id, created_at`,
+		org.Name, org.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newOrg, nil
+}
+
+func (r *RepositoryPg) ListOrganizations(ctx context.Context) ([]authmodels.Organization, error) {
+	orgs := []authmodels.Organization{}
+
+	err := r.db.SelectContext(ctx, &orgs, `SELECT id, name, owner_id, created_at FROM organizations ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+// CreateAPIKey returns sql.ErrNoRows when the organization of key does not exist.
+func (r *RepositoryPg) CreateAPIKey(ctx context.Context, key authmodels.APIKey, keyHash string) (*authmodels.APIKey, error) {
+	newKey := authmodels.APIKey{}
+
+	err := r.db.GetContext(ctx, &newKey, `INSERT INTO api_keys(organization_id, name, prefix, key_hash, scopes, expires_at, created_by)
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING `+apiKeyColumns,
+		key.OrganizationID, key.Name, key.Prefix, keyHash, key.Scopes, key.ExpiresAt, key.CreatedBy)
+
+	var pqErr *pq.Error
+	if isInvalidText(err) ||
+		(errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation && pqErr.Constraint == "api_keys_organization_id_fkey") {
+		return nil, sql.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &newKey, nil
+}
+
+func (r *RepositoryPg) ListAPIKeys(ctx context.Context, organizationID string) ([]authmodels.APIKey, error) {
+	keys := []authmodels.APIKey{}
+
+	err := r.db.SelectContext(ctx, &keys, `SELECT `+apiKeyColumns+` FROM api_keys WHERE organization_id = $1 ORDER BY created_at DESC`,
+		organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *RepositoryPg) GetAPIKey(ctx context.Context, keyID string) (*authmodels.APIKey, error) {
+	key := authmodels.APIKey{}
+
+	err := r.db.GetContext(ctx, &key, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, keyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, authmodels.ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// UpdateAPIKey changes the fields of req that are set.
+func (r *RepositoryPg) UpdateAPIKey(ctx context.Context, keyID string, req authmodels.UpdateAPIKeyRequest) (*authmodels.APIKey, error) {
+	key := authmodels.APIKey{}
+
+	var scopes interface{}
+	if req.Scopes != nil {
+		scopes = pq.StringArray(req.Scopes)
+	}
+
+	err := r.db.GetContext(ctx, &key, `UPDATE api_keys SET
+			name = COALESCE($2, name),
+			scopes = COALESCE($3, scopes),
+			expires_at = CASE WHEN $4 THEN $5 ELSE expires_at END
+		WHERE id = $1 RETURNING `+apiKeyColumns,
+		keyID, req.Name, scopes, req.ExpiresAt != nil, req.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, authmodels.ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *RepositoryPg) RevokeAPIKey(ctx context.Context, keyID string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, keyID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return authmodels.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// GetAPIKeyCredentials looks a key up by its prefix, together with the organization owner it acts for.
+func (r *RepositoryPg) GetAPIKeyCredentials(ctx context.Context, prefix string) (*authmodels.APIKeyCredentials, error) {
+	credentials := authmodels.APIKeyCredentials{}
+
+	err := r.db.GetContext(ctx, &credentials, `SELECT k.id, k.organization_id, k.name, k.prefix, k.scopes, k.expires_at, k.last_used_at,
+			k.created_by, k.created_at, k.revoked_at, k.key_hash, o.owner_id, u.role AS owner_role
+		FROM api_keys k
+		JOIN organizations o ON o.id = k.organization_id
+		JOIN users u ON u.user_id = o.owner_id
+		WHERE k.prefix = $1`, prefix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, authmodels.ErrInvalidAPIKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &credentials, nil
+}
+
+// TouchAPIKey records that a key was used. Writes are skipped when the key was already used within the last minute.
+func (r *RepositoryPg) TouchAPIKey(ctx context.Context, keyID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`, keyID)
+
+	return err
+}
@@ -0,0 +1,84 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// auditColumns is the column list selected into auditmodels.Entry.
+const auditColumns = `id, occurred_at, COALESCE(actor_id, '') AS actor_id, COALESCE(actor_role, '') AS actor_role,
+	COALESCE(api_key_id, '') AS api_key_id, COALESCE(ip_address, '') AS ip_address, COALESCE(request_id, '') AS request_id,
+	action, entity_type, entity_id, COALESCE(before, 'null') AS before, COALESCE(after, 'null') AS after, diff`
+
+func (r *RepositoryPg) InsertAuditEntry(ctx context.Context, entry auditmodels.Entry) error {
+	return insertAuditEntry(ctx, r.db, entry)
+}
+
+// recordChange writes the audit entry of change in tx, so that the change is not committed without it.
+func recordChange(ctx context.Context, tx *sqlx.Tx, change auditmodels.Change) error {
+	entry, err := auditmodels.NewEntry(ctx, change)
+	if err != nil {
+		return err
+	}
+
+	return insertAuditEntry(ctx, tx, *entry)
+}
+
+func insertAuditEntry(ctx context.Context, db sqlx.ExecerContext, entry auditmodels.Entry) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO audit_log(actor_id, actor_role, api_key_id, ip_address, request_id,
+			action, entity_type, entity_id, before, after, diff)
+		VALUES(NULLIF($1, ''), NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8,
+			NULLIF($9::jsonb, 'null'::jsonb), NULLIF($10::jsonb, 'null'::jsonb), $11)`,
+		entry.ActorID, entry.ActorRole, entry.APIKeyID, entry.IPAddress, entry.RequestID,
+		entry.Action, entry.EntityType, entry.EntityID, entry.Before, entry.After, entry.Diff)
+
+	return err
+}
+
+// auditKeyset orders audit entries newest first.
+var auditKeyset = keyset{name: "audit", keys: []sortKey{
+	{expr: "occurred_at", cast: "timestamptz", desc: true},
+	{expr: "id", cast: "bigint", desc: true},
+}}
+
+// auditPageRow is an audit entry with the sort key values of its page cursor.
+type auditPageRow struct {
+	auditmodels.Entry
+	CursorKeys pq.StringArray `db:"cursor_keys"`
+}
+
+// ListAuditEntries returns a page of the entries matching filter, newest first.
+func (r *RepositoryPg) ListAuditEntries(ctx context.Context, filter auditmodels.Filter) (models.Page[auditmodels.Entry], error) {
+	after, args, err := auditKeyset.after(filter.Cursor, 4)
+	if err != nil {
+		return models.Page[auditmodels.Entry]{}, err
+	}
+
+	where := `($1 = '' OR entity_type = $1) AND ($2 = '' OR entity_id = $2) AND ($3 = '' OR actor_id = $3)`
+	args = append(append([]interface{}{filter.EntityType, filter.EntityID, filter.ActorID}, args...), filter.Count+1)
+	rows := []auditPageRow{}
+
+	err = r.db.SelectContext(ctx, &rows, `SELECT `+auditColumns+`, `+auditKeyset.cursorColumn()+` FROM audit_log
+		WHERE `+where+` AND `+after+` ORDER BY `+auditKeyset.orderBy()+fmt.Sprintf(` LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return models.Page[auditmodels.Entry]{}, cursorError(err, filter.Cursor)
+	}
+
+	entries := newPage(auditKeyset, rows, filter.Count,
+		func(row auditPageRow) auditmodels.Entry { return row.Entry },
+		func(row auditPageRow) pq.StringArray { return row.CursorKeys })
+
+	if filter.WithTotal {
+		total := 0
+		err = r.db.GetContext(ctx, &total, `SELECT count(*) FROM audit_log WHERE `+where,
+			filter.EntityType, filter.EntityID, filter.ActorID)
+		entries.Total = &total
+	}
+
+	return entries, err
+}
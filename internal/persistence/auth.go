@@ -0,0 +1,288 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// pgUniqueViolation is the postgres error code for a unique constraint violation.
+const pgUniqueViolation = "23505"
+
+// pgForeignKeyViolation is the postgres error code for a foreign key violation.
+const pgForeignKeyViolation = "23503"
+
+// pgInvalidTextRepresentation is the postgres error code for a parameter that does not parse, such as a malformed uuid.
+const pgInvalidTextRepresentation = "22P02"
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation && pqErr.Constraint == constraint
+}
+
+// isInvalidText reports whether err is the refusal of a parameter that does not parse, such as an id that is not a
+// uuid. No row has such an id.
+func isInvalidText(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == pgInvalidTextRepresentation
+}
+
+// CreateUserWithPassword creates an account signing in with an email and password. The creation is audited in the same
+// transaction.
+func (r *RepositoryPg) CreateUserWithPassword(ctx context.Context, user models.Users, passwordHash string) (*models.Users, error) {
+	return r.createUser(ctx, authmodels.ErrEmailTaken, "users_user_email_key",
+		`INSERT INTO users(user_name, user_email, role, password_hash) VALUES($1, $2, $3, $4) RETURNING `+userColumns,
+		user.UserName, user.Email, user.Role, passwordHash)
+}
+
+func (r *RepositoryPg) GetCredentialsByEmail(ctx context.Context, email string) (*authmodels.Credentials, error) {
+	credentials := authmodels.Credentials{}
+
+	err := r.db.GetContext(ctx, &credentials, `SELECT user_id, password_hash
+		FROM users WHERE lower(user_email) = lower($1)`, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credentials, nil
+}
+
+// IsLoginLocked reports whether logins to userID from ipAddress are locked.
+func (r *RepositoryPg) IsLoginLocked(ctx context.Context, userID string, ipAddress string) (bool, error) {
+	var locked bool
+
+	err := r.db.GetContext(ctx, &locked, `SELECT EXISTS (SELECT 1 FROM login_failures
+		WHERE user_id = $1 AND ip_address = $2 AND locked_until > now())`, userID, ipAddress)
+
+	return locked, err
+}
+
+// RecordFailedLogin counts a failed login to userID from ipAddress and locks logins from that address for lockout once
+// maxAttempts is reached. Failures older than lockout are forgotten.
+func (r *RepositoryPg) RecordFailedLogin(ctx context.Context, userID string, ipAddress string, maxAttempts int, lockout time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO login_failures AS f (user_id, ip_address, failed_attempts, locked_until)
+		VALUES ($1, $2, CASE WHEN $3::int <= 1 THEN 0 ELSE 1 END,
+			CASE WHEN $3::int <= 1 THEN now() + make_interval(secs => $4) END)
+		ON CONFLICT (user_id, ip_address) DO UPDATE SET
+			failed_attempts = CASE WHEN f.updated_at < now() - make_interval(secs => $4) THEN 1 ELSE f.failed_attempts + 1 END,
+			updated_at = now()`, userID, ipAddress, maxAttempts, lockout.Seconds())
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `UPDATE login_failures SET failed_attempts = 0, locked_until = now() + make_interval(secs => $3)
+		WHERE user_id = $1 AND ip_address = $2 AND failed_attempts >= $4`, userID, ipAddress, lockout.Seconds(), maxAttempts)
+
+	return err
+}
+
+// ResetFailedLogins forgets the failed logins to userID from ipAddress.
+func (r *RepositoryPg) ResetFailedLogins(ctx context.Context, userID string, ipAddress string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_failures WHERE user_id = $1 AND ip_address = $2`, userID, ipAddress)
+
+	return err
+}
+
+// sessionColumns is the column list selected into authmodels.Session.
+const sessionColumns = `id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at`
+
+// CreateSession opens a session for userID together with its first refresh token.
+func (r *RepositoryPg) CreateSession(ctx context.Context, userID string, client authmodels.ClientInfo, refreshTokenHash string, expiresAt time.Time) (*authmodels.Session, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	session := authmodels.Session{}
+
+	err = tx.GetContext(ctx, &session, `INSERT INTO sessions(user_id, user_agent, ip_address, expires_at) VALUES($1, $2, $3, $4) RETURNING `+sessionColumns,
+		userID, nullString(client.UserAgent), nullString(client.IPAddress), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO refresh_tokens(token_hash, session_id, expires_at) VALUES($1, $2, $3)`,
+		refreshTokenHash, session.ID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, tx.Commit()
+}
+
+// RotateRefreshToken exchanges the refresh token oldHash for newHash.
+// Presenting a token that was already rotated revokes its whole session and returns ErrRefreshTokenReused.
+func (r *RepositoryPg) RotateRefreshToken(ctx context.Context, oldHash string, newHash string, expiresAt time.Time) (*authmodels.Session, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	var token struct {
+		SessionID string     `db:"session_id"`
+		ExpiresAt time.Time  `db:"expires_at"`
+		UsedAt    *time.Time `db:"used_at"`
+		RevokedAt *time.Time `db:"revoked_at"`
+	}
+
+	err = tx.GetContext(ctx, &token, `SELECT rt.session_id, rt.expires_at, rt.used_at, s.revoked_at
+		FROM refresh_tokens rt JOIN sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1 FOR UPDATE`, oldHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, authmodels.ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if token.RevokedAt != nil {
+		return nil, authmodels.ErrInvalidRefreshToken
+	}
+
+	if token.UsedAt != nil {
+		_, err = tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = now(), revoked_reason = 'refresh_token_reuse' WHERE id = $1`, token.SessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+
+		return nil, authmodels.ErrRefreshTokenReused
+	}
+
+	if token.ExpiresAt.Before(time.Now()) {
+		return nil, authmodels.ErrInvalidRefreshToken
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = now() WHERE token_hash = $1`, oldHash)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO refresh_tokens(token_hash, session_id, expires_at) VALUES($1, $2, $3)`,
+		newHash, token.SessionID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	session := authmodels.Session{}
+
+	err = tx.GetContext(ctx, &session, `UPDATE sessions SET last_used_at = now(), expires_at = $2 WHERE id = $1 RETURNING `+sessionColumns,
+		token.SessionID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, tx.Commit()
+}
+
+// RevokeSession revokes a session of userID.
+func (r *RepositoryPg) RevokeSession(ctx context.Context, userID string, sessionID string, reason string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE sessions SET revoked_at = now(), revoked_reason = $3
+		WHERE id = $2 AND user_id = $1 AND revoked_at IS NULL`, userID, sessionID, reason)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return authmodels.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of userID.
+func (r *RepositoryPg) RevokeUserSessions(ctx context.Context, userID string, reason string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE sessions SET revoked_at = now(), revoked_reason = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID, reason)
+
+	return err
+}
+
+func (r *RepositoryPg) ListActiveSessions(ctx context.Context, userID string) ([]authmodels.Session, error) {
+	sessions := []authmodels.Session{}
+
+	err := r.db.SelectContext(ctx, &sessions, `SELECT `+sessionColumns+` FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now() ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *RepositoryPg) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	var active bool
+
+	err := r.db.GetContext(ctx, &active, `SELECT revoked_at IS NULL AND expires_at > now() FROM sessions WHERE id = $1`, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return active, err
+}
+
+// UpdateUserRole gives the user userID role. The change is audited in the same transaction.
+func (r *RepositoryPg) UpdateUserRole(ctx context.Context, userID string, role authmodels.Role) (*models.Users, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	before := models.Users{}
+
+	err = tx.GetContext(ctx, &before, `SELECT `+userColumns+` FROM users WHERE user_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.Users{}
+
+	err = tx.GetContext(ctx, &user, `UPDATE users SET role = $2 WHERE user_id = $1 RETURNING `+userColumns, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordChange(ctx, tx, auditmodels.Change{
+		Action:     auditmodels.ActionUserRoleChanged,
+		EntityType: auditmodels.EntityUser,
+		EntityID:   userID,
+		Before:     &before,
+		After:      &user,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, tx.Commit()
+}
+
+func nullString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
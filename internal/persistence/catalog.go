@@ -0,0 +1,155 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// cityColumns is the column list selected into models.City.
+const cityColumns = `id, name, region, created_at, (SELECT count(*) FROM cars WHERE cars.city_id = cities.id
+	AND withdrawn_at IS NULL AND bid_expiration_time > now()) AS listings`
+
+// categoryColumns is the column list selected into models.Category.
+const categoryColumns = `id, name, description, attribute_schema, created_at, (SELECT count(*) FROM cars
+	WHERE cars.category = categories.id AND withdrawn_at IS NULL AND bid_expiration_time > now()) AS listings`
+
+// catalogWriteError maps uniqueness and foreign key violations of a catalog write onto exists and inUse.
+func catalogWriteError(err error, table string, exists error, inUse error) error {
+	if isUniqueViolation(err, table+"_pkey") || isUniqueViolation(err, table+"_name_key") {
+		return exists
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		return inUse
+	}
+
+	return err
+}
+
+// ListCities returns every city by name with its number of open auctions.
+func (r *RepositoryPg) ListCities(ctx context.Context) ([]models.City, error) {
+	cities := []models.City{}
+	err := r.db.SelectContext(ctx, &cities, "SELECT "+cityColumns+" FROM cities ORDER BY lower(name), id")
+
+	return cities, err
+}
+
+func (r *RepositoryPg) GetCity(ctx context.Context, cityID string) (*models.City, error) {
+	city := models.City{}
+	if err := r.db.GetContext(ctx, &city, "SELECT "+cityColumns+" FROM cities WHERE id = $1", cityID); err != nil {
+		return nil, err
+	}
+
+	return &city, nil
+}
+
+// CreateCity returns models.ErrCityExists when the id or the name is taken.
+func (r *RepositoryPg) CreateCity(ctx context.Context, city models.City) (*models.City, error) {
+	created := models.City{}
+	err := r.db.GetContext(ctx, &created, `INSERT INTO cities(id, name, region) VALUES($1, $2, $3) RETURNING `+cityColumns,
+		city.ID, city.Name, city.Region)
+	if err != nil {
+		return nil, catalogWriteError(err, "cities", models.ErrCityExists, models.ErrCityInUse)
+	}
+
+	return &created, nil
+}
+
+// UpdateCity renames a city. Its id never changes.
+func (r *RepositoryPg) UpdateCity(ctx context.Context, cityID string, city models.City) (*models.City, error) {
+	updated := models.City{}
+	err := r.db.GetContext(ctx, &updated, `UPDATE cities SET name = $2, region = $3 WHERE id = $1 RETURNING `+cityColumns,
+		cityID, city.Name, city.Region)
+	if err != nil {
+		return nil, catalogWriteError(err, "cities", models.ErrCityExists, models.ErrCityInUse)
+	}
+
+	return &updated, nil
+}
+
+// DeleteCity returns models.ErrCityInUse while cars, withdrawn or not, are listed in the city.
+func (r *RepositoryPg) DeleteCity(ctx context.Context, cityID string) (*models.City, error) {
+	deleted := models.City{}
+	err := r.db.GetContext(ctx, &deleted, `DELETE FROM cities WHERE id = $1 RETURNING id, name, region, created_at`,
+		cityID)
+	if err != nil {
+		return nil, catalogWriteError(err, "cities", models.ErrCityExists, models.ErrCityInUse)
+	}
+
+	return &deleted, nil
+}
+
+// ListCategories returns every category by name with its number of open auctions.
+func (r *RepositoryPg) ListCategories(ctx context.Context) ([]models.Category, error) {
+	categories := []models.Category{}
+	err := r.db.SelectContext(ctx, &categories, "SELECT "+categoryColumns+" FROM categories ORDER BY lower(name), id")
+
+	return categories, err
+}
+
+func (r *RepositoryPg) GetCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	category := models.Category{}
+	err := r.db.GetContext(ctx, &category, "SELECT "+categoryColumns+" FROM categories WHERE id = $1", categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// CreateCategory returns models.ErrCategoryExists when the id or the name is taken.
+func (r *RepositoryPg) CreateCategory(ctx context.Context, category models.Category) (*models.Category, error) {
+	created := models.Category{}
+	err := r.db.GetContext(ctx, &created, `INSERT INTO categories(id, name, description) VALUES($1, $2, $3)
+		RETURNING `+categoryColumns, category.ID, category.Name, category.Description)
+	if err != nil {
+		return nil, catalogWriteError(err, "categories", models.ErrCategoryExists, models.ErrCategoryInUse)
+	}
+
+	return &created, nil
+}
+
+// UpdateCategory renames a category. Its id never changes.
+func (r *RepositoryPg) UpdateCategory(ctx context.Context, categoryID string, category models.Category) (*models.Category, error) {
+	updated := models.Category{}
+	err := r.db.GetContext(ctx, &updated, `UPDATE categories SET name = $2, description = $3 WHERE id = $1
+		RETURNING `+categoryColumns, categoryID, category.Name, category.Description)
+	if err != nil {
+		return nil, catalogWriteError(err, "categories", models.ErrCategoryExists, models.ErrCategoryInUse)
+	}
+
+	return &updated, nil
+}
+
+// DeleteCategory returns models.ErrCategoryInUse while cars, withdrawn or not, are listed in the category.
+func (r *RepositoryPg) DeleteCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	deleted := models.Category{}
+	err := r.db.GetContext(ctx, &deleted, `DELETE FROM categories WHERE id = $1
+		RETURNING id, name, description, created_at`, categoryID)
+	if err != nil {
+		return nil, catalogWriteError(err, "categories", models.ErrCategoryExists, models.ErrCategoryInUse)
+	}
+
+	return &deleted, nil
+}
+
+// SetCategorySchema replaces the attribute schema of a category. A nil schema removes it.
+func (r *RepositoryPg) SetCategorySchema(ctx context.Context, categoryID string, schema []byte) (*models.Category, error) {
+	var value interface{}
+	if schema != nil {
+		value = string(schema)
+	}
+
+	updated := models.Category{}
+	err := r.db.GetContext(ctx, &updated, `UPDATE categories SET attribute_schema = $2 WHERE id = $1
+		RETURNING `+categoryColumns, categoryID, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
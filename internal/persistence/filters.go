@@ -0,0 +1,133 @@
+package persistence
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// carIDKey makes car orderings unique.
+var carIDKey = sortKey{expr: "id", cast: "uuid"}
+
+// newestKey orders cars by publication, newest first.
+var newestKey = sortKey{expr: "date_posted", cast: "timestamptz", desc: true}
+
+// carKeysets are the orderings of the car sorts. Cars without a price are listed last by both price sorts.
+var carKeysets = map[models.CarSort]keyset{
+	"":                {name: "newest", keys: []sortKey{newestKey, carIDKey}},
+	models.SortNewest: {name: "newest", keys: []sortKey{newestKey, carIDKey}},
+	models.SortEndingSoonest: {name: "ending_soonest", keys: []sortKey{
+		{expr: "COALESCE(bid_expiration_time, 'infinity')", cast: "timestamptz"},
+		carIDKey,
+	}},
+	models.SortPriceAsc: {name: "price_asc", keys: []sortKey{
+		{expr: "biding_price IS NULL", cast: "boolean"},
+		{expr: "COALESCE(biding_price, 0)", cast: "bigint"},
+		carIDKey,
+	}},
+	models.SortPriceDesc: {name: "price_desc", keys: []sortKey{
+		{expr: "biding_price IS NULL", cast: "boolean"},
+		{expr: "COALESCE(biding_price, 0)", cast: "bigint", desc: true},
+		carIDKey,
+	}},
+	models.SortMostBids: {name: "most_bids", keys: []sortKey{
+		{expr: "(SELECT count(*) FROM bids WHERE bids.car_id = cars.id::text)", cast: "bigint", desc: true},
+		newestKey,
+		carIDKey,
+	}},
+}
+
+// relevanceKeyset orders search results by rank against the tsquery query.
+var relevanceKeyset = keyset{name: "relevance", keys: []sortKey{
+	{expr: "ts_rank_cd(search, query)", cast: "real", desc: true},
+	newestKey,
+	carIDKey,
+}}
+
+// carFilterWhere returns the conditions on the cars table matching a models.CarFilter, with the arguments of
+// carFilterArgs bound from parameter $first onwards.
+func carFilterWhere(first int) string {
+	return carFilterConditions(func(i int) string {
+		return fmt.Sprintf("$%d", first+i)
+	})
+}
+
+// savedSearchFilterColumns are the columns of saved_searches holding the filters, in the order of carFilterArgs.
+var savedSearchFilterColumns = []string{
+	"city_id", "category",
+	"min_price", "max_price",
+	"min_mileage", "max_mileage",
+	"min_year", "max_year",
+	"fuel_types", "engine_types", "models",
+	"statuses",
+	"vin",
+	"make_id", "model_id",
+}
+
+// savedSearchWhere returns the conditions on the cars table matching the filters of the saved search in scope.
+func savedSearchWhere() string {
+	return carFilterConditions(func(i int) string {
+		return "saved_searches." + savedSearchFilterColumns[i]
+	})
+}
+
+// carFilterConditions returns the conditions of carFilterWhere, reading the i-th value of carFilterArgs from p(i).
+func carFilterConditions(p func(i int) string) string {
+	anyOf := func(i int, column string) string {
+		return fmt.Sprintf("(cardinality(%s::text[]) = 0 OR lower(%s) = ANY(%s))", p(i), column, p(i))
+	}
+
+	atLeast := func(i int, expr string) string {
+		return fmt.Sprintf("(%s::numeric IS NULL OR %s >= %s)", p(i), expr, p(i))
+	}
+
+	atMost := func(i int, expr string) string {
+		return fmt.Sprintf("(%s::numeric IS NULL OR %s <= %s)", p(i), expr, p(i))
+	}
+
+	return strings.Join([]string{
+		"withdrawn_at IS NULL",
+		fmt.Sprintf("(%s = '' OR city_id = %s)", p(0), p(0)),
+		fmt.Sprintf("(%s = '' OR category = %s)", p(1), p(1)),
+		atLeast(2, "biding_price"), atMost(3, "biding_price"),
+		atLeast(4, "mileage"), atMost(5, "mileage"),
+		atLeast(6, "year"), atMost(7, "year"),
+		anyOf(8, "fuel_type::text"), anyOf(9, "engine_type"), anyOf(10, "car_model"),
+		fmt.Sprintf(`(cardinality(%[1]s::text[]) = 0 OR ('open' = ANY(%[1]s) AND bid_expiration_time > now())
+			OR ('ended' = ANY(%[1]s) AND bid_expiration_time <= now()))`, p(11)),
+		fmt.Sprintf("(%s = '' OR vin = %s)", p(12), p(12)),
+		fmt.Sprintf("(%s = '' OR make_id = %s)", p(13), p(13)),
+		fmt.Sprintf("(%s = '' OR model_id = %s)", p(14), p(14)),
+	}, " AND ")
+}
+
+// carFilterArgs returns the arguments of carFilterWhere.
+func carFilterArgs(filter models.CarFilter) []interface{} {
+	statuses := make([]string, len(filter.Statuses))
+	for i, status := range filter.Statuses {
+		statuses[i] = string(status)
+	}
+
+	return []interface{}{
+		filter.CityID, filter.Category,
+		filter.MinPrice, filter.MaxPrice,
+		filter.MinMileage, filter.MaxMileage,
+		filter.MinYear, filter.MaxYear,
+		lowerArray(filter.FuelTypes), lowerArray(filter.EngineTypes), lowerArray(filter.Models),
+		lowerArray(statuses),
+		filter.VIN,
+		filter.MakeID, filter.ModelID,
+	}
+}
+
+// lowerArray returns values in lower case as a non-null postgres array.
+func lowerArray(values []string) pq.StringArray {
+	lowered := make(pq.StringArray, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(value)
+	}
+
+	return lowered
+}
@@ -0,0 +1,141 @@
+package persistence
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// sortKey is an expression that a list is ordered by. Its values must not be null.
+type sortKey struct {
+	expr string
+	// cast is the type that the text of cursor values is cast back to.
+	cast string
+	desc bool
+}
+
+// keyset is the ordering of a list read with keyset pagination. Its last key must be unique.
+type keyset struct {
+	// name identifies the ordering in cursors, so a cursor can not be used with another ordering.
+	name string
+	keys []sortKey
+}
+
+// cursor is the content of an opaque page cursor.
+type cursor struct {
+	Name   string   `json:"n"`
+	Values []string `json:"v"`
+}
+
+// orderBy returns the ORDER BY clause of k.
+func (k keyset) orderBy() string {
+	terms := make([]string, len(k.keys))
+
+	for i, key := range k.keys {
+		terms[i] = key.expr + " ASC"
+		if key.desc {
+			terms[i] = key.expr + " DESC"
+		}
+	}
+
+	return strings.Join(terms, ", ")
+}
+
+// cursorColumn selects the sort key values of a row as the cursor_keys text array.
+func (k keyset) cursorColumn() string {
+	exprs := make([]string, len(k.keys))
+
+	for i, key := range k.keys {
+		exprs[i] = "(" + key.expr + ")::text"
+	}
+
+	return "ARRAY[" + strings.Join(exprs, ", ") + "] AS cursor_keys"
+}
+
+// after returns the condition selecting the rows that follow the cursor, with its values bound from parameter $first
+// onwards. Every row follows the empty cursor.
+func (k keyset) after(encoded string, first int) (string, []interface{}, error) {
+	if encoded == "" {
+		return "TRUE", nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, models.ErrInvalidCursor
+	}
+
+	c := cursor{}
+	if err := json.Unmarshal(raw, &c); err != nil || c.Name != k.name || len(c.Values) != len(k.keys) {
+		return "", nil, models.ErrInvalidCursor
+	}
+
+	// (k1, k2, ...) follows (v1, v2, ...) if k1 follows v1, or k1 = v1 and k2 follows v2, and so on.
+	alternatives := make([]string, len(k.keys))
+	args := make([]interface{}, len(k.keys))
+
+	for i, key := range k.keys {
+		terms := make([]string, 0, i+1)
+
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = $%d::%s", k.keys[j].expr, first+j, k.keys[j].cast))
+		}
+
+		op := ">"
+		if key.desc {
+			op = "<"
+		}
+
+		terms = append(terms, fmt.Sprintf("%s %s $%d::%s", key.expr, op, first+i, key.cast))
+		alternatives[i] = "(" + strings.Join(terms, " AND ") + ")"
+		args[i] = c.Values[i]
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// encode returns the cursor of the row whose sort key values are values.
+func (k keyset) encode(values []string) string {
+	//nolint:errchkjson
+	raw, _ := json.Marshal(cursor{Name: k.name, Values: values})
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// pgDataExceptionClass is the postgres error class of invalid input values.
+const pgDataExceptionClass = "22"
+
+// cursorError returns models.ErrInvalidCursor if err is a cursor value that postgres could not cast, or err.
+func cursorError(err error, encoded string) error {
+	var pqErr *pq.Error
+	if encoded != "" && errors.As(err, &pqErr) && pqErr.Code.Class() == pgDataExceptionClass {
+		return models.ErrInvalidCursor
+	}
+
+	return err
+}
+
+// newPage returns the page of rows, which were read with a limit one greater than count so that the presence of a
+// following page is known. item converts a row and cursorKeys returns its sort key values.
+func newPage[R any, T any](k keyset, rows []R, count uint, item func(R) T, cursorKeys func(R) pq.StringArray) models.Page[T] {
+	more := uint(len(rows)) > count
+	if more {
+		rows = rows[:count]
+	}
+
+	page := models.Page[T]{Items: make([]T, len(rows))}
+
+	for i := range rows {
+		page.Items[i] = item(rows[i])
+	}
+
+	if more {
+		page.NextCursor = k.encode(cursorKeys(rows[count-1]))
+	}
+
+	return page
+}
@@ -3,10 +3,19 @@ package persistence
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
 	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
 	// pq is imported fore the postgres drivers.
 	_ "github.com/lib/pq"
 )
@@ -18,22 +27,201 @@ import (
 //
 //nolint:interfacebloat
 type Repository interface {
-	GetAllCars(ctx context.Context, cityID string, category string, startKey uint, count uint) ([]models.Cars, error)
-	UpdateCar(ctx context.Context, updatePayLoad models.Cars, carID string) (*models.Cars, error)
+	GetAllCars(ctx context.Context, filter models.CarFilter) (models.Page[models.Cars], error)
+	SearchCars(ctx context.Context, query string, filter models.CarFilter) (models.Page[models.CarSearchResult], error)
+	UpdateCar(ctx context.Context, updatePayLoad models.Cars, carID string, version int) (*models.Cars, error)
 	RegisterCar(ctx context.Context, carPayload models.Cars) (*models.Cars, error)
 	GetCarsByID(ctx context.Context, carID string) (*models.Cars, error)
 	PlaceBid(ctx context.Context, bid models.Bids) (*models.Bids, error)
+	CountBids(ctx context.Context, carID string) (int, error)
+	ListCarBids(ctx context.Context, carID string, page models.PageRequest) (models.Page[models.Bids], error)
+	ListUsers(ctx context.Context, role string, page models.PageRequest) (models.Page[models.Users], error)
+	ListBidders(ctx context.Context, carID string) ([]models.Users, error)
+	WithdrawCar(ctx context.Context, carID string, actorID string, reason string) (*models.Cars, error)
+	RestoreCar(ctx context.Context, carID string) (*models.Cars, error)
+	CreateCarPhoto(ctx context.Context, photo models.CarPhoto) (*models.CarPhoto, error)
+	ListCarPhotos(ctx context.Context, carID string) ([]models.CarPhoto, error)
+	DeleteCarPhoto(ctx context.Context, carID string, photoID string) (*models.CarPhoto, error)
+	ReorderCarPhotos(ctx context.Context, carID string, photoIDs []string) ([]models.CarPhoto, error)
+	SetPrimaryCarPhoto(ctx context.Context, carID string, photoID string) (*models.CarPhoto, error)
+	ListCarMigrationIssues(ctx context.Context) ([]models.CarMigrationIssue, error)
+	ListCities(ctx context.Context) ([]models.City, error)
+	GetCity(ctx context.Context, cityID string) (*models.City, error)
+	CreateCity(ctx context.Context, city models.City) (*models.City, error)
+	UpdateCity(ctx context.Context, cityID string, city models.City) (*models.City, error)
+	DeleteCity(ctx context.Context, cityID string) (*models.City, error)
+	SeedVehicles(ctx context.Context, vehicles []models.Vehicle) (bool, error)
+	ListVehicles(ctx context.Context, kind models.VehicleKind, parentID string) ([]models.Vehicle, error)
+	GetVehicle(ctx context.Context, kind models.VehicleKind, vehicleID string) (*models.Vehicle, error)
+	CreateVehicle(ctx context.Context, vehicle models.Vehicle) (*models.Vehicle, error)
+	MergeVehicles(ctx context.Context, kind models.VehicleKind, fromID string, intoID string) (*models.VehicleMerge, error)
+	ListUnmappedCars(ctx context.Context) ([]models.Cars, error)
+	SetCarVehicle(ctx context.Context, car models.Cars) error
+	ListCategories(ctx context.Context) ([]models.Category, error)
+	GetCategory(ctx context.Context, categoryID string) (*models.Category, error)
+	CreateCategory(ctx context.Context, category models.Category) (*models.Category, error)
+	UpdateCategory(ctx context.Context, categoryID string, category models.Category) (*models.Category, error)
+	DeleteCategory(ctx context.Context, categoryID string) (*models.Category, error)
+	SetCategorySchema(ctx context.Context, categoryID string, schema []byte) (*models.Category, error)
+	WatchCar(ctx context.Context, userID string, carID string) error
+	UnwatchCar(ctx context.Context, userID string, carID string) error
+	ListWatchlist(ctx context.Context, userID string) ([]models.WatchedCar, error)
+	GetWatchedCar(ctx context.Context, userID string, carID string) (*models.WatchedCar, error)
+	ListWatchers(ctx context.Context, carID string) ([]models.Users, error)
+	ClaimEndingWatches(ctx context.Context, period time.Duration) ([]models.Watch, error)
+	ClaimSoldWatches(ctx context.Context) ([]models.Watch, error)
+	ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error)
+	CreateSavedSearch(ctx context.Context, search models.SavedSearch) (*models.SavedSearch, error)
+	UpdateSavedSearch(ctx context.Context, search models.SavedSearch) (*models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, userID string, searchID string) (*models.SavedSearch, error)
+	QueueSearchAlerts(ctx context.Context, carID string) (int, error)
+	ClaimSearchAlerts(ctx context.Context, frequency models.AlertFrequency, age time.Duration) ([]models.SearchAlert, error)
 	GetBidByID(ctx context.Context, bidID string) (*models.Bids, error)
 	GetUserByID(ctx context.Context, userID string) (*models.Users, error)
 	CreateUser(ctx context.Context, user models.Users) (*models.Users, error)
+	CreateUserWithPassword(ctx context.Context, user models.Users, passwordHash string) (*models.Users, error)
+	GetCredentialsByEmail(ctx context.Context, email string) (*authmodels.Credentials, error)
+	IsLoginLocked(ctx context.Context, userID string, ipAddress string) (bool, error)
+	RecordFailedLogin(ctx context.Context, userID string, ipAddress string, maxAttempts int, lockout time.Duration) error
+	ResetFailedLogins(ctx context.Context, userID string, ipAddress string) error
+	CreateSession(ctx context.Context, userID string, client authmodels.ClientInfo, refreshTokenHash string, expiresAt time.Time) (*authmodels.Session, error)
+	RotateRefreshToken(ctx context.Context, oldHash string, newHash string, expiresAt time.Time) (*authmodels.Session, error)
+	RevokeSession(ctx context.Context, userID string, sessionID string, reason string) error
+	RevokeUserSessions(ctx context.Context, userID string, reason string) error
+	ListActiveSessions(ctx context.Context, userID string) ([]authmodels.Session, error)
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+	UpdateUserRole(ctx context.Context, userID string, role authmodels.Role) (*models.Users, error)
+	CreateOrganization(ctx context.Context, org authmodels.Organization) (*authmodels.Organization, error)
+	ListOrganizations(ctx context.Context) ([]authmodels.Organization, error)
+	CreateAPIKey(ctx context.Context, key authmodels.APIKey, keyHash string) (*authmodels.APIKey, error)
+	ListAPIKeys(ctx context.Context, organizationID string) ([]authmodels.APIKey, error)
+	GetAPIKey(ctx context.Context, keyID string) (*authmodels.APIKey, error)
+	UpdateAPIKey(ctx context.Context, keyID string, req authmodels.UpdateAPIKeyRequest) (*authmodels.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID string) error
+	GetAPIKeyCredentials(ctx context.Context, prefix string) (*authmodels.APIKeyCredentials, error)
+	TouchAPIKey(ctx context.Context, keyID string) error
+	CreateAccountToken(ctx context.Context, userID string, purpose string, expiresAt time.Time) (string, error)
+	CountAccountTokens(ctx context.Context, userID string, purpose string, since time.Time) (int, error)
+	ConsumeAccountToken(ctx context.Context, tokenID string, purpose string) (string, error)
+	MarkEmailVerified(ctx context.Context, userID string) (*models.Users, error)
+	UpdatePassword(ctx context.Context, userID string, passwordHash string) error
+	CreatePhoneOTP(ctx context.Context, phone string, codeHash string, expiresAt time.Time) error
+	CountPhoneOTPs(ctx context.Context, phone string, since time.Time) (int, error)
+	ConsumePhoneOTP(ctx context.Context, phone string, codeHash string, maxAttempts int) error
+	GetUserByPhone(ctx context.Context, phone string) (*models.Users, error)
+	CreatePhoneUser(ctx context.Context, user models.Users) (*models.Users, error)
+	SetVerifiedPhone(ctx context.Context, userID string, phone string) (*models.Users, error)
+	TakeRateLimitToken(ctx context.Context, key string, burst int, rate float64) (float64, bool, error)
+	PruneRateLimits(ctx context.Context, idle time.Duration) error
+	InsertAuditEntry(ctx context.Context, entry auditmodels.Entry) error
+	ListAuditEntries(ctx context.Context, filter auditmodels.Filter) (models.Page[auditmodels.Entry], error)
+	CreateKYCDocument(ctx context.Context, doc sellermodels.Document, content []byte) (*sellermodels.Document, error)
+	ListUnsubmittedKYCDocuments(ctx context.Context, userID string) ([]sellermodels.Document, error)
+	ListVerificationDocuments(ctx context.Context, verificationID string) ([]sellermodels.Document, error)
+	GetKYCDocumentContent(ctx context.Context, documentID string) (*sellermodels.Document, []byte, error)
+	SubmitSellerVerification(ctx context.Context, userID string) (*sellermodels.Verification, error)
+	ListSellerVerifications(ctx context.Context, status string) ([]sellermodels.Verification, error)
+	GetSellerVerification(ctx context.Context, verificationID string) (*sellermodels.Verification, error)
+	GetLatestSellerVerification(ctx context.Context, userID string) (*sellermodels.Verification, error)
+	ReviewSellerVerification(ctx context.Context, verificationID string, reviewerID string, approve bool, reason string) (*sellermodels.Verification, error)
 }
 
-// carRow contains the columns for an event.
-type carRow struct {
-	ID         string       `db:"id"`
-	Properties *models.Cars `db:"properties"`
+// carColumns is the column list selected into models.Cars.
+const carColumns = `id, COALESCE(seller_id, '') AS seller_id, car_name, date_posted, COALESCE(biding_price, 0) AS biding_price,
+	bid_expiration_time, COALESCE(city_id, '') AS city_id, engine_type, make, car_model, trim,
+	COALESCE(make_id, '') AS make_id, COALESCE(model_id, '') AS model_id, COALESCE(trim_id, '') AS trim_id, COALESCE(year, 0) AS year,
+	COALESCE(vin, '') AS vin,
+	(SELECT count(*) FROM bids WHERE bids.car_id = cars.id::text) AS number_of_bids, COALESCE(mileage, 0) AS mileage,
+	COALESCE(fuel_type::text, '') AS fuel_type, photo_url, COALESCE(category, '') AS category, description, extras, updated_at, version,
+	COALESCE((SELECT thumbnail_key FROM car_photos WHERE car_photos.car_id = cars.id AND is_primary), '') AS thumbnail_key,
+	withdrawn_at, COALESCE(withdrawn_by, '') AS withdrawn_by, COALESCE(withdrawal_reason, '') AS withdrawal_reason`
+
+// carWriteColumns are the columns of a car that its seller sets, in the order of carValues.
+const carWriteColumns = `seller_id, car_name, car_model, year, engine_type, fuel_type, mileage, biding_price, city_id,
+	category, photo_url, description, extras, bid_expiration_time, vin, make, trim, make_id, model_id, trim_id`
+
+// carWriteColumnCount is the number of carWriteColumns.
+const carWriteColumnCount = 20
+
+// carValues returns the values of carWriteColumns for car. Unknown years and references are stored as NULL. A mileage or
+// a price of 0 is a real value: new cars have not been driven.
+func carValues(car models.Cars) []interface{} {
+	extras := car.Extras
+	if len(extras) == 0 {
+		extras = types.JSONText("{}")
+	}
+
+	return []interface{}{
+		nullString(car.SellerID), car.CarName, car.CarModel, nullYear(car.Year), car.EngineType,
+		nullString(string(car.FuelType)), car.Mileage, car.BidingPrice, nullString(car.CityID),
+		nullString(car.Category), car.CarphotoUrl, car.Description, extras, car.BidExpirationTime, nullString(car.VIN), car.Make,
+		car.Trim, nullString(car.MakeID), nullString(car.ModelID), nullString(car.TrimID),
+	}
 }
 
+// nullYear stores the year 0, which no car was built in, as NULL.
+func nullYear(year int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(year), Valid: year != 0}
+}
+
+// placeholders returns n comma separated parameter placeholders starting at $first.
+func placeholders(first int, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", first+i)
+	}
+
+	return strings.Join(params, ", ")
+}
+
+// carWriteError maps foreign key and uniqueness violations of a car write onto model errors.
+func carWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		switch pqErr.Constraint {
+		case "cars_city_id_fkey":
+			return models.ErrUnknownCity
+		case "cars_category_fkey":
+			return models.ErrUnknownCategory
+		}
+	}
+
+	return err
+}
+
+// claimVIN returns models.ErrDuplicateVIN when a car other than carID is listed with vin in an open auction. The VIN
+// stays locked until tx ends, so that concurrent listings of a VIN are checked one after the other. Cars without a VIN
+// and cars whose auction has ended, at deadline, claim nothing.
+func claimVIN(ctx context.Context, tx *sqlx.Tx, vin string, deadline models.Time, carID string) error {
+	if vin == "" || (!deadline.IsZero() && !deadline.After(time.Now())) {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('cars.vin'), hashtext($1))`, vin); err != nil {
+		return err
+	}
+
+	var listed bool
+	if err := tx.GetContext(ctx, &listed, `SELECT EXISTS (SELECT 1 FROM cars WHERE vin = $1 AND id::text <> $2
+		AND withdrawn_at IS NULL AND COALESCE(bid_expiration_time, 'infinity') > now())`, vin, carID); err != nil {
+		return err
+	}
+
+	if listed {
+		return models.ErrDuplicateVIN
+	}
+
+	return nil
+}
+
+// userColumns is the column list selected into models.Users.
+const userColumns = `user_id, COALESCE(user_name, '') AS user_name, COALESCE(user_email, '') AS user_email, role, created_at,
+	email_verified_at, COALESCE(phone_number, '') AS phone_number, phone_verified_at, seller_status`
+
+// bidColumns is the column list selected into models.Bids.
+const bidColumns = `bid_id, car_id, COALESCE(user_id, '') AS user_id, bid_amount, COALESCE(email, '') AS email,
+	COALESCE(user_name, '') AS user_name, created_at`
+
 // RepositoryPg is a postgres implementation of Repository.
 type RepositoryPg struct {
 	db *sqlx.DB
@@ -50,95 +238,503 @@ func NewRepository(db *sql.DB) (*RepositoryPg, error) {
 	}, nil
 }
 
-// GetAllEvents implements Repository.
-func (r *RepositoryPg) GetAllCars(ctx context.Context, cityID string, category string, startKey uint, count uint) ([]models.Cars, error) {
-	rows := []carRow{}
+// GetAllCars returns a page of the listed cars matching filter.
+func (r *RepositoryPg) GetAllCars(ctx context.Context, filter models.CarFilter) (models.Page[models.Cars], error) {
+	order := carKeysets[filter.Sort]
+	args := carFilterArgs(filter)
 
-	err := r.db.SelectContext(ctx, &rows, `SELECT id, properties FROM cars WHERE ($2 = '' OR properties->>'category' = $2) ORDER BY properties->>'city_id' = $1 DESC, properties->>'date' ASC LIMIT $4 OFFSET $3`,
-		cityID, category, startKey, count)
+	after, cursorArgs, err := order.after(filter.Cursor, len(args)+1)
 	if err != nil {
-		return nil, err
+		return models.Page[models.Cars]{}, err
+	}
+
+	args = append(append(args, cursorArgs...), filter.Count+1)
+	rows := []carPageRow{}
+
+	err = r.db.SelectContext(ctx, &rows, `SELECT `+carColumns+`, `+order.cursorColumn()+` FROM cars
+		WHERE `+carFilterWhere(1)+` AND `+after+` ORDER BY `+order.orderBy()+fmt.Sprintf(` LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return models.Page[models.Cars]{}, cursorError(err, filter.Cursor)
+	}
+
+	page := newPage(order, rows, filter.Count,
+		func(row carPageRow) models.Cars { return row.Cars },
+		func(row carPageRow) pq.StringArray { return row.CursorKeys })
+
+	if filter.WithTotal {
+		page.Total, err = r.countCars(ctx, "TRUE", filter)
+	}
+
+	return page, err
+}
+
+// countCars counts the listed cars matching filter and the condition match, whose arguments are matchArgs.
+func (r *RepositoryPg) countCars(ctx context.Context, match string, filter models.CarFilter, matchArgs ...interface{}) (*int, error) {
+	total := 0
+	err := r.db.GetContext(ctx, &total, `SELECT count(*) FROM cars `+
+		`WHERE `+match+` AND `+carFilterWhere(len(matchArgs)+1), append(matchArgs, carFilterArgs(filter)...)...)
+
+	return &total, err
+}
+
+// carPageRow is a car with the sort key values of its page cursor.
+type carPageRow struct {
+	models.Cars
+	CursorKeys pq.StringArray `db:"cursor_keys"`
+}
+
+// carSearchRow is a carPageRow with its search rank and highlighted snippet.
+type carSearchRow struct {
+	carPageRow
+	Rank     float64 `db:"rank"`
+	Headline string  `db:"headline"`
+}
+
+// headlineOptions configures the snippets of search results.
+const headlineOptions = "StartSel=" + models.HighlightStart + ", StopSel=" + models.HighlightStop +
+	", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// SearchCars returns the listed cars matching both the web search syntax query and filter, most relevant first.
+// Snippets are only computed for the returned page.
+func (r *RepositoryPg) SearchCars(ctx context.Context, query string, filter models.CarFilter) (models.Page[models.CarSearchResult], error) {
+	args := append([]interface{}{query, headlineOptions}, carFilterArgs(filter)...)
+
+	after, cursorArgs, err := relevanceKeyset.after(filter.Cursor, len(args)+1)
+	if err != nil {
+		return models.Page[models.CarSearchResult]{}, err
 	}
 
-	carSlice := make([]models.Cars, len(rows))
+	args = append(append(args, cursorArgs...), filter.Count+1)
+	rows := []carSearchRow{}
 
-	for i := range rows {
-		carSlice[i] = *rows[i].Properties
-		carSlice[i].ID = rows[i].ID
+	err = r.db.SelectContext(ctx, &rows, `WITH matches AS (
+			SELECT `+carColumns+`, ts_rank_cd(search, query) AS rank, `+relevanceKeyset.cursorColumn()+`
+			FROM cars, websearch_to_tsquery('simple', $1) AS query
+			WHERE search @@ query AND `+carFilterWhere(3)+` AND `+after+`
+			ORDER BY `+relevanceKeyset.orderBy()+fmt.Sprintf(` LIMIT $%d`, len(args))+`
+		)
+		SELECT matches.*, ts_headline('simple',
+			concat_ws(' ', make, car_name, car_model, vin, description),
+			websearch_to_tsquery('simple', $1), $2) AS headline
+		FROM matches ORDER BY rank DESC, date_posted DESC, id ASC`, args...)
+	if err != nil {
+		return models.Page[models.CarSearchResult]{}, cursorError(err, filter.Cursor)
 	}
 
-	return carSlice, nil
+	page := newPage(relevanceKeyset, rows, filter.Count,
+		func(row carSearchRow) models.CarSearchResult {
+			return models.CarSearchResult{Car: row.Cars, Rank: row.Rank, Highlight: row.Headline}
+		},
+		func(row carSearchRow) pq.StringArray { return row.CursorKeys })
+
+	if filter.WithTotal {
+		page.Total, err = r.countCars(ctx, "search @@ websearch_to_tsquery('simple', $1)", filter, query)
+	}
+
+	return page, err
 }
 
+// RegisterCar returns models.ErrDuplicateVIN when the VIN of carPayload is listed in an open auction. The listing is
+// audited in the same transaction.
 func (r *RepositoryPg) RegisterCar(ctx context.Context, carPayload models.Cars) (*models.Cars, error) {
-	row := carRow{}
-	err := r.db.GetContext(ctx, &row, `INSERT INTO cars(properties) VALUES($1) RETURNING id, properties`, carPayload)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	row.Properties.ID = row.ID
+	//nolint:errcheck
+	defer tx.Rollback()
 
-	return row.Properties, nil
+	if err := claimVIN(ctx, tx, carPayload.VIN, carPayload.BidExpirationTime, ""); err != nil {
+		return nil, err
+	}
+
+	car := models.Cars{}
+	err = tx.GetContext(ctx, &car, `INSERT INTO cars(`+carWriteColumns+`) VALUES(`+placeholders(1, carWriteColumnCount)+`) RETURNING `+carColumns,
+		carValues(carPayload)...)
+	if err != nil {
+		return nil, carWriteError(err)
+	}
+
+	err = recordChange(ctx, tx, auditmodels.Change{
+		Action:     auditmodels.ActionCarRegistered,
+		EntityType: auditmodels.EntityCar,
+		EntityID:   car.ID,
+		After:      &car,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &car, tx.Commit()
 }
 
 func (r *RepositoryPg) GetCarsByID(ctx context.Context, carID string) (*models.Cars, error) {
-	row := carRow{}
-	err := r.db.GetContext(ctx, &row, "SELECT id, properties FROM cars WHERE id = $1", carID)
+	car := models.Cars{}
+	err := r.db.GetContext(ctx, &car, "SELECT "+carColumns+" FROM cars WHERE id = $1", carID)
 
 	if err != nil {
 		return nil, err
 	}
-	row.Properties.ID = row.ID
 
-	return row.Properties, nil
+	return &car, nil
 }
 
-func (r *RepositoryPg) UpdateCar(ctx context.Context, updatePayLoad models.Cars, carID string) (*models.Cars, error) {
-	row := carRow{}
-	err := r.db.GetContext(ctx, &row, "UPDATE cars SET properties=$1 WHERE id = $2 RETURNING id, properties", updatePayLoad, carID)
+// UpdateCar replaces the car carID if it is still at version, and increments its version.
+// It returns models.ErrVersionConflict if the car has changed since, and models.ErrDuplicateVIN when its VIN is listed
+// in another open auction. The update is audited in the same transaction.
+func (r *RepositoryPg) UpdateCar(ctx context.Context, updatePayLoad models.Cars, carID string, version int) (*models.Cars, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	if err := claimVIN(ctx, tx, updatePayLoad.VIN, updatePayLoad.BidExpirationTime, carID); err != nil {
+		return nil, err
+	}
+
+	before := models.Cars{}
 
+	err = tx.GetContext(ctx, &before, `SELECT `+carColumns+` FROM cars WHERE id = $1 FOR UPDATE`, carID)
 	if err != nil {
 		return nil, err
 	}
-	row.Properties.ID = row.ID
 
-	return row.Properties, nil
+	if before.Version != version {
+		return nil, models.ErrVersionConflict
+	}
+
+	car := models.Cars{}
+	err = tx.GetContext(ctx, &car, `UPDATE cars SET (`+carWriteColumns+`) = (`+placeholders(1, carWriteColumnCount)+`),
+		updated_at = now(), version = version + 1 `+fmt.Sprintf(`WHERE id = $%d`, carWriteColumnCount+1)+` RETURNING `+carColumns,
+		append(carValues(updatePayLoad), carID)...)
+	if err != nil {
+		return nil, carWriteError(err)
+	}
+
+	err = recordChange(ctx, tx, auditmodels.Change{
+		Action:     auditmodels.ActionCarUpdated,
+		EntityType: auditmodels.EntityCar,
+		EntityID:   carID,
+		Before:     &before,
+		After:      &car,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &car, tx.Commit()
 }
 
+// PlaceBid records bid and increments the version of the car, so that updates based on a version read before the bid
+// are refused. It returns models.ErrCarWithdrawn for withdrawn cars and models.ErrAuctionClosed once the deadline of the
+// car has passed. The bid is audited in the same transaction.
 func (r *RepositoryPg) PlaceBid(ctx context.Context, bid models.Bids) (*models.Bids, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	// Bumping the version locks the car, so a bid can not slip in while it is being withdrawn, nor after its deadline,
+	// which the sold notifications and the release of its VIN rely on.
+	res, err := tx.ExecContext(ctx, `UPDATE cars SET version = version + 1
+		WHERE id = $1 AND withdrawn_at IS NULL AND COALESCE(bid_expiration_time, 'infinity') > now()`, bid.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if updated == 0 {
+		car, err := r.GetCarsByID(ctx, bid.CarID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !car.WithdrawnAt.IsZero() {
+			return nil, models.ErrCarWithdrawn
+		}
+
+		return nil, models.ErrAuctionClosed
+	}
+
 	createdBid := models.Bids{}
-	err := r.db.GetContext(ctx, &createdBid, `INSERT INTO bids(car_id, bid_amount,email,user_name) VALUES($1,$2,$3,$4)RETURNING bid_id, car_id, bid_amount,email,user_name, created_at`, bid.CarID, bid.Amount, bid.Email, bid.UserName)
+	err = tx.GetContext(ctx, &createdBid, `INSERT INTO bids(car_id, user_id, bid_amount, email, user_name) VALUES($1,$2,$3,$4,$5) RETURNING `+bidColumns,
+		bid.CarID, bid.UserID, bid.Amount, bid.Email, bid.UserName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordChange(ctx, tx, auditmodels.Change{
+		Action:     auditmodels.ActionBidPlaced,
+		EntityType: auditmodels.EntityBid,
+		EntityID:   createdBid.BidID,
+		After:      &createdBid,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &createdBid, tx.Commit()
+}
+
+// ListBidders returns the users who have bid on the car carID.
+func (r *RepositoryPg) ListBidders(ctx context.Context, carID string) ([]models.Users, error) {
+	users := []models.Users{}
+	err := r.db.SelectContext(ctx, &users, `SELECT `+userColumns+` FROM users
+		WHERE user_id IN (SELECT user_id FROM bids WHERE car_id = $1) ORDER BY created_at`, carID)
+
+	return users, err
+}
+
+// WithdrawCar takes the car carID off the listings on behalf of actorID. It returns models.ErrCarWithdrawn if the car
+// has already been withdrawn. The withdrawal is audited in the same transaction.
+func (r *RepositoryPg) WithdrawCar(ctx context.Context, carID string, actorID string, reason string) (*models.Cars, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	listed := models.Cars{}
+
+	err = tx.GetContext(ctx, &listed, `SELECT `+carColumns+` FROM cars WHERE id = $1 FOR UPDATE`, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !listed.WithdrawnAt.IsZero() {
+		return nil, models.ErrCarWithdrawn
+	}
+
+	car := models.Cars{}
+
+	err = tx.GetContext(ctx, &car, `UPDATE cars SET withdrawn_at = now(), withdrawn_by = $2, withdrawal_reason = $3,
+		version = version + 1 WHERE id = $1 RETURNING `+carColumns, carID, actorID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordChange(ctx, tx, auditmodels.Change{
+		Action:     auditmodels.ActionCarWithdrawn,
+		EntityType: auditmodels.EntityCar,
+		EntityID:   carID,
+		Before:     &listed,
+		After:      &car,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &car, tx.Commit()
+}
+
+// RestoreCar lists the withdrawn car carID again. It returns models.ErrCarNotWithdrawn if the car is listed, and
+// models.ErrDuplicateVIN when its VIN has been listed in another open auction while it was withdrawn. The restoration is
+// audited in the same transaction.
+func (r *RepositoryPg) RestoreCar(ctx context.Context, carID string) (*models.Cars, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	withdrawn := models.Cars{}
+	err = tx.GetContext(ctx, &withdrawn, `SELECT `+carColumns+` FROM cars WHERE id = $1 FOR UPDATE`, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	if withdrawn.WithdrawnAt.IsZero() {
+		return nil, models.ErrCarNotWithdrawn
+	}
+
+	if err := claimVIN(ctx, tx, withdrawn.VIN, withdrawn.BidExpirationTime, carID); err != nil {
+		return nil, err
+	}
+
+	car := models.Cars{}
+	err = tx.GetContext(ctx, &car, `UPDATE cars SET withdrawn_at = NULL, withdrawn_by = NULL, withdrawal_reason = NULL,
+		version = version + 1 WHERE id = $1 RETURNING `+carColumns, carID)
+	if err != nil {
+		return nil, err
+	}
 
+	err = recordChange(ctx, tx, auditmodels.Change{
+		Action:     auditmodels.ActionCarRestored,
+		EntityType: auditmodels.EntityCar,
+		EntityID:   carID,
+		Before:     &withdrawn,
+		After:      &car,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &createdBid, nil
+	return &car, tx.Commit()
+}
+
+// ListCarMigrationIssues returns the car properties that could not be moved into typed columns, oldest first.
+func (r *RepositoryPg) ListCarMigrationIssues(ctx context.Context) ([]models.CarMigrationIssue, error) {
+	issues := []models.CarMigrationIssue{}
+	err := r.db.SelectContext(ctx, &issues, `SELECT id, car_id, field, value, reason, created_at
+		FROM car_migration_issues ORDER BY id`)
+
+	return issues, err
+}
+
+// bidKeyset orders bids newest first.
+var bidKeyset = keyset{name: "bids", keys: []sortKey{
+	{expr: "COALESCE(created_at, '-infinity')", cast: "timestamptz", desc: true},
+	{expr: "bid_id", cast: "uuid"},
+}}
+
+// bidPageRow is a bid with the sort key values of its page cursor.
+type bidPageRow struct {
+	models.Bids
+	CursorKeys pq.StringArray `db:"cursor_keys"`
+}
+
+// ListCarBids returns a page of the bids on the car carID, newest first.
+func (r *RepositoryPg) ListCarBids(ctx context.Context, carID string, page models.PageRequest) (models.Page[models.Bids], error) {
+	after, args, err := bidKeyset.after(page.Cursor, 2)
+	if err != nil {
+		return models.Page[models.Bids]{}, err
+	}
+
+	args = append(append([]interface{}{carID}, args...), page.Count+1)
+	rows := []bidPageRow{}
+
+	err = r.db.SelectContext(ctx, &rows, `SELECT `+bidColumns+`, `+bidKeyset.cursorColumn()+` FROM bids
+		WHERE car_id = $1 AND `+after+` ORDER BY `+bidKeyset.orderBy()+fmt.Sprintf(` LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return models.Page[models.Bids]{}, cursorError(err, page.Cursor)
+	}
+
+	bids := newPage(bidKeyset, rows, page.Count,
+		func(row bidPageRow) models.Bids { return row.Bids },
+		func(row bidPageRow) pq.StringArray { return row.CursorKeys })
+
+	if page.WithTotal {
+		total := 0
+		err = r.db.GetContext(ctx, &total, `SELECT count(*) FROM bids WHERE car_id = $1`, carID)
+		bids.Total = &total
+	}
+
+	return bids, err
+}
+
+// userKeyset orders users newest first.
+var userKeyset = keyset{name: "users", keys: []sortKey{
+	{expr: "created_at", cast: "timestamptz", desc: true},
+	{expr: "user_id", cast: "text"},
+}}
+
+// userPageRow is a user with the sort key values of its page cursor.
+type userPageRow struct {
+	models.Users
+	CursorKeys pq.StringArray `db:"cursor_keys"`
+}
+
+// ListUsers returns a page of the users with role, or of all users if role is empty, newest first.
+func (r *RepositoryPg) ListUsers(ctx context.Context, role string, page models.PageRequest) (models.Page[models.Users], error) {
+	after, args, err := userKeyset.after(page.Cursor, 2)
+	if err != nil {
+		return models.Page[models.Users]{}, err
+	}
+
+	args = append(append([]interface{}{role}, args...), page.Count+1)
+	rows := []userPageRow{}
+
+	err = r.db.SelectContext(ctx, &rows, `SELECT `+userColumns+`, `+userKeyset.cursorColumn()+` FROM users
+		WHERE ($1 = '' OR role = $1) AND `+after+` ORDER BY `+userKeyset.orderBy()+fmt.Sprintf(` LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return models.Page[models.Users]{}, cursorError(err, page.Cursor)
+	}
+
+	users := newPage(userKeyset, rows, page.Count,
+		func(row userPageRow) models.Users { return row.Users },
+		func(row userPageRow) pq.StringArray { return row.CursorKeys })
+
+	if page.WithTotal {
+		total := 0
+		err = r.db.GetContext(ctx, &total, `SELECT count(*) FROM users WHERE ($1 = '' OR role = $1)`, role)
+		users.Total = &total
+	}
+
+	return users, err
+}
+
+// CountBids returns the number of bids placed on the car carID.
+func (r *RepositoryPg) CountBids(ctx context.Context, carID string) (int, error) {
+	count := 0
+	err := r.db.GetContext(ctx, &count, `SELECT count(*) FROM bids WHERE car_id = $1`, carID)
+
+	return count, err
 }
 
 func (r *RepositoryPg) GetBidByID(ctx context.Context, bidID string) (*models.Bids, error) {
 	bids := models.Bids{}
-	err := r.db.GetContext(ctx, &bids, `SELECT * FROM bids WHERE bid_id=$1`, bidID)
+	err := r.db.GetContext(ctx, &bids, `SELECT `+bidColumns+` FROM bids WHERE bid_id=$1`, bidID)
 	if err != nil {
 		return nil, err
 	}
 	return &bids, nil
 }
 
+// CreateUser creates the profile of user. The creation is audited in the same transaction.
 func (r *RepositoryPg) CreateUser(ctx context.Context, user models.Users) (*models.Users, error) {
+	return r.createUser(ctx, authmodels.ErrEmailTaken, "users_user_email_key",
+		`INSERT INTO users(user_id,user_name,user_email) VALUES($1,$2,$3) RETURNING `+userColumns,
+		user.User_id, user.UserName, user.Email)
+}
+
+// createUser runs the insert query and audits the user it returns in the same transaction. A violation of the unique
+// constraint is returned as taken.
+func (r *RepositoryPg) createUser(ctx context.Context, taken error, constraint string, query string, args ...interface{}) (*models.Users, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
 	newUser := models.Users{}
 
-	err := r.db.GetContext(ctx, &newUser, `INSERT INTO users(user_id,user_name,user_email) VALUES($1,$2,$3) RETURNING user_id,user_name,user_email`, user.User_id, user.UserName, user.Email)
+	err = tx.GetContext(ctx, &newUser, query, args...)
+	if isUniqueViolation(err, constraint) {
+		return nil, taken
+	}
+
+	if err != nil {
+		return nil, err
+	}
 
+	err = recordChange(ctx, tx, auditmodels.Change{
+		Action:     auditmodels.ActionUserCreated,
+		EntityType: auditmodels.EntityUser,
+		EntityID:   newUser.User_id,
+		After:      &newUser,
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &newUser, nil
+
+	return &newUser, tx.Commit()
 }
 
 func (r *RepositoryPg) GetUserByID(ctx context.Context, userID string) (*models.Users, error) {
 	user := models.Users{}
-	err := r.db.GetContext(ctx, &user, `SELECT * FROM users WHERE user_id=$1`, userID)
+	err := r.db.GetContext(ctx, &user, `SELECT `+userColumns+` FROM users WHERE user_id=$1`, userID)
 	if err != nil {
 		return nil, err
 	}
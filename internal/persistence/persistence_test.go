@@ -5,11 +5,15 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	_ "github.com/lib/pq"
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
 	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	log "github.com/sirupsen/logrus"
@@ -95,23 +99,602 @@ func TestRepositoryPg_RegisterCar(t *testing.T) {
 	require.NotNil(t, repo)
 	carData := models.Cars{
 		ID:                "1",
-		SellerID:          "seller123",
 		CarName:           "Toyota Camry",
-		DatePosted:        "2024-01-18",
-		BidingPrice:       "15000",
-		BidExpirationTime: "2024-02-18",
-		CityID:            "city123",
+		BidingPrice:       15000,
+		BidExpirationTime: models.NewTime(time.Now().Add(30 * 24 * time.Hour)),
+		CityID:            "douala",
 		EngineType:        "V6",
 		CarModel:          "Camry XLE",
-		NumberOfBids:      "5",
-		Mileage:           "50000",
-		FuelType:          "Gasoline",
+		Mileage:           50000,
+		FuelType:          models.FuelPetrol,
 		CarphotoUrl:       "https://example.com/car.jpg",
-		Category:          "Sedan",
+		Category:          "sedan",
 		Description:       "Well-maintained car in excellent condition.",
 	}
 	newCar, err := repo.RegisterCar(ctx, carData)
 	assert.NoError(t, err)
 	assert.NotNilf(t, newCar, "failed to create new car")
+	assert.False(t, newCar.DatePosted.IsZero(), "date_posted should be set by the database")
+	assert.WithinDuration(t, carData.BidExpirationTime.Time, newCar.BidExpirationTime.Time, time.Second)
 
 }
+
+func TestRepositoryPg_SearchCars(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	expires := models.NewTime(time.Now().Add(7 * 24 * time.Hour))
+
+	corolla, err := repo.RegisterCar(ctx, models.Cars{
+		CarName:           "Toyota Corolla",
+		CarModel:          "Corolla 2015",
+		FuelType:          models.FuelDiesel,
+		CityID:            "douala",
+		Category:          "sedan",
+		Description:       "One owner, serviced in Douala.",
+		BidExpirationTime: expires,
+	})
+	require.NoError(t, err)
+
+	_, err = repo.RegisterCar(ctx, models.Cars{
+		CarName:           "Toyota Hilux",
+		FuelType:          models.FuelDiesel,
+		CityID:            "yaounde",
+		Category:          "pickup",
+		BidExpirationTime: expires,
+	})
+	require.NoError(t, err)
+
+	results, err := repo.SearchCars(ctx, "toyota corolla diesel douala", models.CarFilter{PageRequest: models.PageRequest{Count: 10}})
+	require.NoError(t, err)
+	require.Len(t, results.Items, 1)
+	assert.Equal(t, corolla.ID, results.Items[0].Car.ID)
+	assert.Contains(t, results.Items[0].Highlight, models.HighlightStart+"Corolla"+models.HighlightStop)
+
+	results, err = repo.SearchCars(ctx, "toyota diesel", models.CarFilter{Category: "pickup", PageRequest: models.PageRequest{Count: 10}})
+	require.NoError(t, err)
+	require.Len(t, results.Items, 1)
+	assert.Equal(t, "Toyota Hilux", results.Items[0].Car.CarName)
+}
+
+func TestRepositoryPg_GetAllCars_Filter(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	_, err = repo.CreateCategory(ctx, models.Category{ID: "filter-test", Name: "Filter test"})
+	require.NoError(t, err)
+
+	expires := models.NewTime(time.Now().Add(7 * 24 * time.Hour))
+
+	for _, car := range []models.Cars{
+		{CarName: "Filter A", Category: "filter-test", BidingPrice: 1500000, Mileage: 90000, Year: 2012, FuelType: models.FuelDiesel},
+		{CarName: "Filter B", Category: "filter-test", BidingPrice: 4000000, Mileage: 30000, Year: 2019, FuelType: models.FuelPetrol},
+		{CarName: "Filter C", Category: "filter-test", Mileage: 0, Year: 2021, FuelType: models.FuelPetrol},
+	} {
+		car.BidExpirationTime = expires
+		_, err := repo.RegisterCar(ctx, car)
+		require.NoError(t, err)
+	}
+
+	names := func(filter models.CarFilter) []string {
+		filter.Category = "filter-test"
+		filter.Count = 10
+
+		cars, err := repo.GetAllCars(ctx, filter)
+		require.NoError(t, err)
+
+		var names []string
+		for _, car := range cars.Items {
+			names = append(names, car.CarName)
+		}
+
+		return names
+	}
+
+	minPrice, maxYear, maxMileage, maxPrice := int64(1000000), int64(2020), int64(20000), int64(1000000)
+
+	assert.Equal(t, []string{"Filter B", "Filter A"}, names(models.CarFilter{MinPrice: &minPrice, Sort: models.SortPriceDesc}))
+	assert.Equal(t, []string{"Filter A", "Filter B"}, names(models.CarFilter{MaxYear: &maxYear, Sort: models.SortPriceAsc}))
+	assert.ElementsMatch(t, []string{"Filter B", "Filter C"}, names(models.CarFilter{FuelTypes: []string{"PETROL"}}))
+	assert.Len(t, names(models.CarFilter{Statuses: []models.AuctionStatus{models.AuctionEnded}}), 0)
+	// A new car has been driven 0 km, which is not an unknown mileage, and is listed for free.
+	assert.Equal(t, []string{"Filter C"}, names(models.CarFilter{MaxMileage: &maxMileage, MaxPrice: &maxPrice}))
+}
+
+func TestRepositoryPg_GetAllCars_Cursor(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	_, err = repo.CreateCategory(ctx, models.Category{ID: "cursor-test", Name: "Cursor test"})
+	require.NoError(t, err)
+
+	for _, price := range []int64{300, 100, 200, 100} {
+		_, err := repo.RegisterCar(ctx, models.Cars{
+			CarName:           fmt.Sprintf("Cursor %d", price),
+			Category:          "cursor-test",
+			BidingPrice:       price,
+			BidExpirationTime: models.NewTime(time.Now().Add(time.Hour)),
+		})
+		require.NoError(t, err)
+	}
+
+	filter := models.CarFilter{Category: "cursor-test", Sort: models.SortPriceAsc}
+	filter.Count = 3
+	filter.WithTotal = true
+
+	first, err := repo.GetAllCars(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, first.Items, 3)
+	require.NotEmpty(t, first.NextCursor)
+	require.NotNil(t, first.Total)
+	assert.Equal(t, 4, *first.Total)
+
+	filter.Cursor = first.NextCursor
+
+	second, err := repo.GetAllCars(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, second.Items, 1)
+	assert.Empty(t, second.NextCursor)
+	assert.Equal(t, int64(300), second.Items[0].BidingPrice)
+
+	filter.Sort = models.SortNewest
+
+	_, err = repo.GetAllCars(ctx, filter)
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+}
+
+func TestRepositoryPg_CarPhotos(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	car, err := repo.RegisterCar(ctx, models.Cars{
+		CarName:           "Photo test",
+		BidExpirationTime: models.NewTime(time.Now().Add(time.Hour)),
+	})
+	require.NoError(t, err)
+
+	var photos []*models.CarPhoto
+
+	for _, name := range []string{"a", "b", "c"} {
+		photo, err := repo.CreateCarPhoto(ctx, models.CarPhoto{
+			CarID:        car.ID,
+			ContentType:  "image/jpeg",
+			Width:        640,
+			Height:       480,
+			StorageKey:   "cars/" + car.ID + "/" + name + ".jpg",
+			ThumbnailKey: "cars/" + car.ID + "/" + name + "-thumb.jpg",
+		})
+		require.NoError(t, err)
+
+		photos = append(photos, photo)
+	}
+
+	assert.True(t, photos[0].Primary, "the first photo of a car is primary")
+	assert.False(t, photos[1].Primary)
+
+	listed, err := repo.GetCarsByID(ctx, car.ID)
+	require.NoError(t, err)
+	assert.Equal(t, photos[0].ThumbnailKey, listed.ThumbnailKey)
+
+	_, err = repo.ReorderCarPhotos(ctx, car.ID, []string{photos[2].ID, photos[0].ID})
+	assert.ErrorIs(t, err, models.ErrInvalidPhotoOrder)
+
+	reordered, err := repo.ReorderCarPhotos(ctx, car.ID, []string{photos[2].ID, photos[0].ID, photos[1].ID})
+	require.NoError(t, err)
+	assert.Equal(t, photos[2].ID, reordered[0].ID)
+
+	primary, err := repo.SetPrimaryCarPhoto(ctx, car.ID, photos[1].ID)
+	require.NoError(t, err)
+	assert.True(t, primary.Primary)
+
+	_, err = repo.DeleteCarPhoto(ctx, car.ID, photos[1].ID)
+	require.NoError(t, err)
+
+	remaining, err := repo.ListCarPhotos(ctx, car.ID)
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+	assert.True(t, remaining[0].Primary, "the first remaining photo becomes primary")
+}
+
+func TestRepositoryPg_CarVIN(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	listing := models.Cars{
+		CarName:           "VIN test",
+		VIN:               "1HGCM82633A004352",
+		BidExpirationTime: models.NewTime(time.Now().Add(time.Hour)),
+	}
+
+	car, err := repo.RegisterCar(ctx, listing)
+	require.NoError(t, err)
+
+	_, err = repo.RegisterCar(ctx, listing)
+	assert.ErrorIs(t, err, models.ErrDuplicateVIN, "a VIN can only be listed once")
+
+	found, err := repo.GetAllCars(ctx, models.CarFilter{VIN: listing.VIN, PageRequest: models.PageRequest{Count: 10}})
+	require.NoError(t, err)
+	require.Len(t, found.Items, 1)
+	assert.Equal(t, car.ID, found.Items[0].ID)
+
+	_, err = repo.WithdrawCar(ctx, car.ID, "", "sold")
+	require.NoError(t, err)
+
+	relisted, err := repo.RegisterCar(ctx, listing)
+	require.NoError(t, err, "withdrawn listings do not hold their VIN")
+
+	_, err = repo.RestoreCar(ctx, car.ID)
+	assert.ErrorIs(t, err, models.ErrDuplicateVIN)
+
+	results, err := repo.SearchCars(ctx, listing.VIN, models.CarFilter{PageRequest: models.PageRequest{Count: 10}})
+	require.NoError(t, err)
+	require.Len(t, results.Items, 1)
+	assert.Equal(t, relisted.ID, results.Items[0].Car.ID)
+
+	ended := models.Cars{
+		CarName:           "VIN test ended",
+		VIN:               "JH4KA7561PC008269",
+		BidExpirationTime: models.NewTime(time.Now().Add(-time.Hour)),
+	}
+
+	_, err = repo.RegisterCar(ctx, ended)
+	require.NoError(t, err)
+
+	ended.BidExpirationTime = models.NewTime(time.Now().Add(time.Hour))
+	_, err = repo.RegisterCar(ctx, ended)
+	require.NoError(t, err, "ended auctions do not hold their VIN")
+
+	_, err = repo.RegisterCar(ctx, ended)
+	assert.ErrorIs(t, err, models.ErrDuplicateVIN)
+}
+
+func TestRepositoryPg_Catalog(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	city, err := repo.CreateCity(ctx, models.City{ID: "kribi", Name: "Kribi", Region: "South"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, city.Listings)
+
+	_, err = repo.CreateCity(ctx, models.City{ID: "kribi-2", Name: "KRIBI"})
+	assert.ErrorIs(t, err, models.ErrCityExists)
+
+	category, err := repo.CreateCategory(ctx, models.Category{ID: "catalog-test", Name: "Catalog test"})
+	require.NoError(t, err)
+
+	_, err = repo.CreateCategory(ctx, models.Category{ID: "catalog-test", Name: "Another name"})
+	assert.ErrorIs(t, err, models.ErrCategoryExists)
+
+	_, err = repo.RegisterCar(ctx, models.Cars{
+		CarName:           "Catalog car",
+		CityID:            city.ID,
+		Category:          category.ID,
+		BidExpirationTime: models.NewTime(time.Now().Add(time.Hour)),
+	})
+	require.NoError(t, err)
+
+	_, err = repo.RegisterCar(ctx, models.Cars{CarName: "Unknown category", Category: "no-such-category"})
+	assert.ErrorIs(t, err, models.ErrUnknownCategory)
+
+	got, err := repo.GetCategory(ctx, category.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Listings)
+	assert.Nil(t, got.AttributeSchema)
+
+	withSchema, err := repo.SetCategorySchema(ctx, category.ID, []byte(`{"type": "object"}`))
+	require.NoError(t, err)
+	require.NotNil(t, withSchema.AttributeSchema)
+	assert.JSONEq(t, `{"type": "object"}`, withSchema.AttributeSchema.String())
+
+	withoutSchema, err := repo.SetCategorySchema(ctx, category.ID, nil)
+	require.NoError(t, err)
+	assert.Nil(t, withoutSchema.AttributeSchema)
+
+	updated, err := repo.UpdateCity(ctx, city.ID, models.City{Name: "Kribi", Region: "Sud"})
+	require.NoError(t, err)
+	assert.Equal(t, "Sud", updated.Region)
+	assert.Equal(t, 1, updated.Listings)
+
+	_, err = repo.DeleteCity(ctx, city.ID)
+	assert.ErrorIs(t, err, models.ErrCityInUse)
+
+	_, err = repo.DeleteCategory(ctx, category.ID)
+	assert.ErrorIs(t, err, models.ErrCategoryInUse)
+
+	empty, err := repo.CreateCategory(ctx, models.Category{ID: "catalog-empty", Name: "Catalog empty"})
+	require.NoError(t, err)
+
+	_, err = repo.DeleteCategory(ctx, empty.ID)
+	require.NoError(t, err)
+
+	_, err = repo.GetCategory(ctx, empty.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRepositoryPg_MergeVehicles(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	for _, vehicle := range []models.Vehicle{
+		{Kind: models.VehicleMake, ID: "merge-test", Name: "Merge Test"},
+		{Kind: models.VehicleMake, ID: "merge-tset", Name: "Merge Tset"},
+		{Kind: models.VehicleModel, ID: "merge-test-alpha", ParentID: "merge-test", Name: "Alpha"},
+		{Kind: models.VehicleModel, ID: "merge-tset-alpha", ParentID: "merge-tset", Name: "ALPHA"},
+		{Kind: models.VehicleModel, ID: "merge-tset-beta", ParentID: "merge-tset", Name: "Beta"},
+	} {
+		_, err := repo.CreateVehicle(ctx, vehicle)
+		require.NoError(t, err)
+	}
+
+	_, err = repo.CreateVehicle(ctx, models.Vehicle{Kind: models.VehicleModel, ID: "merge-test-alpha-2", ParentID: "merge-test", Name: "alpha"})
+	assert.ErrorIs(t, err, models.ErrVehicleExists)
+
+	car, err := repo.RegisterCar(ctx, models.Cars{
+		CarName: "Merge car", Make: "Merge Tset", CarModel: "ALPHA", MakeID: "merge-tset", ModelID: "merge-tset-alpha",
+	})
+	require.NoError(t, err)
+
+	_, err = repo.MergeVehicles(ctx, models.VehicleModel, "merge-test-alpha", "merge-tset-beta")
+	assert.ErrorIs(t, err, models.ErrInvalidMerge, "models of different makes can not be merged")
+
+	merge, err := repo.MergeVehicles(ctx, models.VehicleMake, "merge-tset", "merge-test")
+	require.NoError(t, err)
+	assert.Equal(t, 1, merge.Cars)
+
+	merged, err := repo.GetCarsByID(ctx, car.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "merge-test", merged.MakeID)
+	assert.Equal(t, "Merge Test", merged.Make)
+	assert.Equal(t, "merge-test-alpha", merged.ModelID, "models with the same name are merged")
+	assert.Equal(t, "Alpha", merged.CarModel)
+	assert.Equal(t, car.Version+2, merged.Version)
+
+	vehicleMake, err := repo.GetVehicle(ctx, models.VehicleMake, "merge-test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Merge Tset"}, vehicleMake.Aliases)
+
+	vehicleModels, err := repo.ListVehicles(ctx, models.VehicleModel, "merge-test")
+	require.NoError(t, err)
+	require.Len(t, vehicleModels, 2)
+	assert.Equal(t, "merge-tset-beta", vehicleModels[1].ID, "other models are moved")
+	assert.Equal(t, []string{"ALPHA"}, vehicleModels[0].Aliases)
+
+	_, err = repo.GetVehicle(ctx, models.VehicleMake, "merge-tset")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRepositoryPg_Watchlist(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	watcher, err := repo.CreateUser(ctx, models.Users{User_id: "watcher", UserName: "Watcher", Email: "watcher@example.com"})
+	require.NoError(t, err)
+
+	car, err := repo.RegisterCar(ctx, models.Cars{
+		CarName:           "Watchlist test",
+		BidingPrice:       10000,
+		BidExpirationTime: models.NewTime(time.Now().Add(30 * time.Minute)),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.WatchCar(ctx, watcher.User_id, car.ID))
+	require.NoError(t, repo.WatchCar(ctx, watcher.User_id, car.ID), "watching a car twice has no effect")
+
+	watched, err := repo.GetWatchedCar(ctx, watcher.User_id, car.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), watched.CurrentPrice, "cars without bids are at their starting price")
+	assert.InDelta(t, 30*60, watched.SecondsLeft, 5)
+
+	_, err = repo.PlaceBid(ctx, models.Bids{CarID: car.ID, UserID: watcher.User_id, Amount: "12500"})
+	require.NoError(t, err)
+
+	watchlist, err := repo.ListWatchlist(ctx, watcher.User_id)
+	require.NoError(t, err)
+	require.Len(t, watchlist, 1)
+	assert.Equal(t, int64(12500), watchlist[0].CurrentPrice)
+
+	ending, err := repo.ClaimEndingWatches(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, ending, models.Watch{UserID: watcher.User_id, CarID: car.ID})
+
+	ending, err = repo.ClaimEndingWatches(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.NotContains(t, ending, models.Watch{UserID: watcher.User_id, CarID: car.ID}, "watchers are told once")
+
+	_, err = database.ExecContext(ctx, `UPDATE cars SET bid_expiration_time = bid_expiration_time + interval '10 minutes'
+		WHERE id = $1`, car.ID)
+	require.NoError(t, err)
+
+	ending, err = repo.ClaimEndingWatches(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, ending, models.Watch{UserID: watcher.User_id, CarID: car.ID}, "extended auctions end again")
+
+	_, err = database.ExecContext(ctx, `UPDATE cars SET bid_expiration_time = now() - interval '1 minute' WHERE id = $1`,
+		car.ID)
+	require.NoError(t, err)
+
+	_, err = repo.PlaceBid(ctx, models.Bids{CarID: car.ID, UserID: watcher.User_id, Amount: "13000"})
+	assert.ErrorIs(t, err, models.ErrAuctionClosed, "sold cars take no more bids")
+
+	sold, err := repo.ClaimSoldWatches(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, sold, models.Watch{UserID: watcher.User_id, CarID: car.ID})
+
+	sold, err = repo.ClaimSoldWatches(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, sold, models.Watch{UserID: watcher.User_id, CarID: car.ID})
+
+	require.NoError(t, repo.UnwatchCar(ctx, watcher.User_id, car.ID))
+	assert.ErrorIs(t, repo.UnwatchCar(ctx, watcher.User_id, car.ID), sql.ErrNoRows)
+}
+
+func TestRepositoryPg_SavedSearches(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	buyer, err := repo.CreateUser(ctx, models.Users{User_id: "searcher", UserName: "Searcher", Email: "searcher@example.com"})
+	require.NoError(t, err)
+
+	maxPrice := int64(20000)
+
+	hybrids, err := repo.CreateSavedSearch(ctx, models.SavedSearch{
+		UserID:    buyer.User_id,
+		Name:      "Cheap hybrids",
+		Frequency: models.AlertsDaily,
+		Filters:   models.CarFilter{MaxPrice: &maxPrice, FuelTypes: []string{"Hybrid"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hybrid"}, hybrids.Filters.FuelTypes, "filters are stored as GET /cars matches them")
+
+	_, err = repo.CreateSavedSearch(ctx, models.SavedSearch{
+		UserID:    buyer.User_id,
+		Name:      "Hybrids",
+		Frequency: models.AlertsInstant,
+		Filters:   models.CarFilter{FuelTypes: []string{"hybrid"}},
+	})
+	require.NoError(t, err)
+
+	_, err = repo.CreateSavedSearch(ctx, models.SavedSearch{UserID: buyer.User_id, Name: "HYBRIDS", Frequency: models.AlertsInstant})
+	assert.ErrorIs(t, err, models.ErrSavedSearchExists)
+
+	cheap, err := repo.RegisterCar(ctx, models.Cars{
+		CarName:           "Saved search test",
+		BidingPrice:       15000,
+		FuelType:          models.FuelHybrid,
+		BidExpirationTime: models.NewTime(time.Now().Add(time.Hour)),
+	})
+	require.NoError(t, err)
+
+	diesel, err := repo.RegisterCar(ctx, models.Cars{
+		CarName:           "Saved search diesel",
+		FuelType:          models.FuelDiesel,
+		BidExpirationTime: models.NewTime(time.Now().Add(time.Hour)),
+	})
+	require.NoError(t, err)
+
+	queued, err := repo.QueueSearchAlerts(ctx, cheap.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued, "users matched by several searches get one alert")
+
+	queued, err = repo.QueueSearchAlerts(ctx, diesel.ID)
+	require.NoError(t, err)
+	assert.Zero(t, queued)
+
+	daily, err := repo.ClaimSearchAlerts(ctx, models.AlertsDaily, 0)
+	require.NoError(t, err)
+	assert.Empty(t, daily, "alerts are sent at the most frequent frequency of the matching searches")
+
+	instant, err := repo.ClaimSearchAlerts(ctx, models.AlertsInstant, 0)
+	require.NoError(t, err)
+	require.Len(t, instant, 1)
+	assert.Equal(t, cheap.ID, instant[0].CarID)
+	assert.Equal(t, "Hybrids", instant[0].SearchName)
+
+	instant, err = repo.ClaimSearchAlerts(ctx, models.AlertsInstant, 0)
+	require.NoError(t, err)
+	assert.Empty(t, instant, "alerts are sent once")
+
+	_, err = repo.DeleteSavedSearch(ctx, buyer.User_id, hybrids.ID)
+	require.NoError(t, err)
+
+	searches, err := repo.ListSavedSearches(ctx, buyer.User_id)
+	require.NoError(t, err)
+	require.Len(t, searches, 1)
+	assert.Equal(t, "Hybrids", searches[0].Name)
+}
+
+func TestRepositoryPg_SellerVerificationInvalidID(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	_, err = repo.GetSellerVerification(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, sellermodels.ErrVerificationNotFound)
+
+	_, _, err = repo.GetKYCDocumentContent(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRepositoryPg_CreateAPIKey_UnknownOrganization(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	for _, organizationID := range []string{"00000000-0000-0000-0000-000000000000", "not-a-uuid"} {
+		_, err = repo.CreateAPIKey(ctx, authmodels.APIKey{
+			OrganizationID: organizationID,
+			Name:           "partner",
+			Prefix:         "0123456789abcdef",
+			Scopes:         []string{authmodels.ScopeCarsWrite},
+		}, strings.Repeat("0", 64))
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	}
+}
+
+func TestRepositoryPg_LoginFailures(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	user, err := repo.CreateUser(ctx, models.Users{User_id: "guessed", UserName: "Guessed", Email: "guessed@example.com"})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.RecordFailedLogin(ctx, user.User_id, "192.0.2.1", 3, time.Minute))
+	}
+
+	locked, err := repo.IsLoginLocked(ctx, user.User_id, "192.0.2.1")
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	locked, err = repo.IsLoginLocked(ctx, user.User_id, "198.51.100.7")
+	require.NoError(t, err)
+	assert.False(t, locked, "other clients are not locked out")
+
+	require.NoError(t, repo.UpdatePassword(ctx, user.User_id, "new-hash"))
+
+	locked, err = repo.IsLoginLocked(ctx, user.User_id, "192.0.2.1")
+	require.NoError(t, err)
+	assert.False(t, locked, "a password reset lifts the lockout")
+}
+
+func TestRepositoryPg_AuditedWithdrawal(t *testing.T) {
+	repo, err := NewRepository(database)
+	require.NoError(t, err)
+
+	car, err := repo.RegisterCar(ctx, models.Cars{CarName: "Audit test"})
+	require.NoError(t, err)
+
+	_, err = repo.RestoreCar(ctx, car.ID)
+	assert.ErrorIs(t, err, models.ErrCarNotWithdrawn)
+
+	_, err = repo.WithdrawCar(ctx, car.ID, "", "sold")
+	require.NoError(t, err)
+
+	_, err = repo.WithdrawCar(ctx, car.ID, "", "again")
+	assert.ErrorIs(t, err, models.ErrCarWithdrawn)
+
+	_, err = repo.RestoreCar(ctx, car.ID)
+	require.NoError(t, err)
+
+	entries, err := repo.ListAuditEntries(ctx, auditmodels.Filter{
+		EntityType:  auditmodels.EntityCar,
+		EntityID:    car.ID,
+		PageRequest: models.PageRequest{Count: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, entries.Items, 3, "failed changes are not audited")
+	assert.Equal(t, auditmodels.ActionCarRestored, entries.Items[0].Action)
+	assert.Equal(t, auditmodels.ActionCarWithdrawn, entries.Items[1].Action)
+	assert.Contains(t, string(entries.Items[1].Diff), "withdrawal_reason")
+	assert.Equal(t, auditmodels.ActionCarRegistered, entries.Items[2].Action)
+
+	first, err := repo.ListAuditEntries(ctx, auditmodels.Filter{EntityID: car.ID, PageRequest: models.PageRequest{Count: 2}})
+	require.NoError(t, err)
+	require.NotEmpty(t, first.NextCursor)
+
+	rest, err := repo.ListAuditEntries(ctx, auditmodels.Filter{
+		EntityID:    car.ID,
+		PageRequest: models.PageRequest{Cursor: first.NextCursor, Count: 2},
+	})
+	require.NoError(t, err)
+	require.Len(t, rest.Items, 1)
+	assert.Equal(t, auditmodels.ActionCarRegistered, rest.Items[0].Action)
+}
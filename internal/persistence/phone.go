@@ -0,0 +1,125 @@
+package persistence
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// CreatePhoneOTP stores a new one-time code for phone. Earlier codes for the same number stop being valid.
+func (r *RepositoryPg) CreatePhoneOTP(ctx context.Context, phone string, codeHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE phone_otps SET consumed_at = now() WHERE phone_number = $1 AND consumed_at IS NULL`, phone)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO phone_otps(phone_number, code_hash, expires_at) VALUES($1, $2, $3)`,
+		phone, codeHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CountPhoneOTPs counts the codes sent to phone since the given time.
+func (r *RepositoryPg) CountPhoneOTPs(ctx context.Context, phone string, since time.Time) (int, error) {
+	var count int
+
+	err := r.db.GetContext(ctx, &count, `SELECT count(*) FROM phone_otps WHERE phone_number = $1 AND created_at >= $2`, phone, since)
+
+	return count, err
+}
+
+// ConsumePhoneOTP checks codeHash against the outstanding code of phone and marks it used when it matches.
+// Every wrong guess counts as an attempt; the code is burnt once maxAttempts is reached.
+func (r *RepositoryPg) ConsumePhoneOTP(ctx context.Context, phone string, codeHash string, maxAttempts int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	otp := authmodels.PhoneOTP{}
+
+	err = tx.GetContext(ctx, &otp, `SELECT id, code_hash, attempts FROM phone_otps
+		WHERE phone_number = $1 AND consumed_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC LIMIT 1 FOR UPDATE`, phone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return authmodels.ErrInvalidOTP
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(codeHash)) == 1 {
+		if _, err := tx.ExecContext(ctx, `UPDATE phone_otps SET consumed_at = now() WHERE id = $1`, otp.ID); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE phone_otps SET attempts = attempts + 1,
+			consumed_at = CASE WHEN attempts + 1 >= $2 THEN now() END
+		WHERE id = $1`, otp.ID, maxAttempts)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return authmodels.ErrInvalidOTP
+}
+
+func (r *RepositoryPg) GetUserByPhone(ctx context.Context, phone string) (*models.Users, error) {
+	user := models.Users{}
+
+	err := r.db.GetContext(ctx, &user, `SELECT `+userColumns+` FROM users WHERE phone_number = $1`, phone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// CreatePhoneUser creates an account identified by a verified phone number. The creation is audited in the same
+// transaction.
+func (r *RepositoryPg) CreatePhoneUser(ctx context.Context, user models.Users) (*models.Users, error) {
+	return r.createUser(ctx, authmodels.ErrPhoneTaken, "users_phone_number_key", `INSERT INTO users(user_name, role,
+		phone_number, phone_verified_at) VALUES(NULLIF($1, ''), $2, $3, now()) RETURNING `+userColumns,
+		user.UserName, user.Role, user.PhoneNumber)
+}
+
+// SetVerifiedPhone attaches a verified phone number to userID, replacing any previous number.
+func (r *RepositoryPg) SetVerifiedPhone(ctx context.Context, userID string, phone string) (*models.Users, error) {
+	user := models.Users{}
+
+	err := r.db.GetContext(ctx, &user, `UPDATE users SET phone_number = $2, phone_verified_at = now()
+		WHERE user_id = $1 RETURNING `+userColumns, userID, phone)
+	if isUniqueViolation(err, "users_phone_number_key") {
+		return nil, authmodels.ErrPhoneTaken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
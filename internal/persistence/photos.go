@@ -0,0 +1,197 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// photoColumns is the column list selected into models.CarPhoto.
+const photoColumns = `id, car_id, position, is_primary, content_type, width, height, size, storage_key, thumbnail_key,
+	uploaded_at`
+
+// CreateCarPhoto adds photo after the other photos of its car. The first photo of a car is always primary.
+// It returns models.ErrTooManyPhotos once the car has models.MaxPhotosPerCar photos.
+func (r *RepositoryPg) CreateCarPhoto(ctx context.Context, photo models.CarPhoto) (*models.CarPhoto, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	if err := lockCar(ctx, tx, photo.CarID); err != nil {
+		return nil, err
+	}
+
+	var count, last int
+
+	err = tx.QueryRowxContext(ctx, `SELECT count(*), COALESCE(max(position), 0) FROM car_photos WHERE car_id = $1`,
+		photo.CarID).Scan(&count, &last)
+	if err != nil {
+		return nil, err
+	}
+
+	if count >= models.MaxPhotosPerCar {
+		return nil, models.ErrTooManyPhotos
+	}
+
+	primary := photo.Primary || count == 0
+	if primary {
+		if _, err := tx.ExecContext(ctx, `UPDATE car_photos SET is_primary = false WHERE car_id = $1 AND is_primary`,
+			photo.CarID); err != nil {
+			return nil, err
+		}
+	}
+
+	created := models.CarPhoto{}
+	err = tx.GetContext(ctx, &created, `INSERT INTO car_photos(car_id, position, is_primary, content_type, width, height,
+		size, storage_key, thumbnail_key) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING `+photoColumns,
+		photo.CarID, last+1, primary, photo.ContentType, photo.Width, photo.Height, photo.Size, photo.StorageKey,
+		photo.ThumbnailKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, tx.Commit()
+}
+
+// ListCarPhotos returns the photos of the car carID in order.
+func (r *RepositoryPg) ListCarPhotos(ctx context.Context, carID string) ([]models.CarPhoto, error) {
+	photos := []models.CarPhoto{}
+	err := r.db.SelectContext(ctx, &photos, `SELECT `+photoColumns+` FROM car_photos WHERE car_id = $1
+		ORDER BY position, uploaded_at`, carID)
+
+	return photos, err
+}
+
+// DeleteCarPhoto deletes the photo photoID of the car carID and returns it. When the primary photo is deleted the
+// next photo becomes primary.
+func (r *RepositoryPg) DeleteCarPhoto(ctx context.Context, carID string, photoID string) (*models.CarPhoto, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	if err := lockCar(ctx, tx, carID); err != nil {
+		return nil, err
+	}
+
+	deleted := models.CarPhoto{}
+	err = tx.GetContext(ctx, &deleted, `DELETE FROM car_photos WHERE car_id = $1 AND id = $2 RETURNING `+photoColumns,
+		carID, photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted.Primary {
+		_, err = tx.ExecContext(ctx, `UPDATE car_photos SET is_primary = true WHERE id = (
+			SELECT id FROM car_photos WHERE car_id = $1 ORDER BY position, uploaded_at LIMIT 1)`, carID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &deleted, tx.Commit()
+}
+
+// ReorderCarPhotos numbers the photos of the car carID in the order of photoIDs, which must list each of them once.
+func (r *RepositoryPg) ReorderCarPhotos(ctx context.Context, carID string, photoIDs []string) ([]models.CarPhoto, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	if err := lockCar(ctx, tx, carID); err != nil {
+		return nil, err
+	}
+
+	current := []string{}
+	if err := tx.SelectContext(ctx, &current, `SELECT id FROM car_photos WHERE car_id = $1`, carID); err != nil {
+		return nil, err
+	}
+
+	if !samePhotos(current, photoIDs) {
+		return nil, models.ErrInvalidPhotoOrder
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE car_photos SET position = ordered.position
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS ordered(id, position)
+		WHERE car_photos.car_id = $1 AND car_photos.id = ordered.id`, carID, pq.StringArray(photoIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	photos := []models.CarPhoto{}
+	err = tx.SelectContext(ctx, &photos, `SELECT `+photoColumns+` FROM car_photos WHERE car_id = $1 ORDER BY position`,
+		carID)
+	if err != nil {
+		return nil, err
+	}
+
+	return photos, tx.Commit()
+}
+
+// SetPrimaryCarPhoto makes the photo photoID the primary photo of the car carID.
+func (r *RepositoryPg) SetPrimaryCarPhoto(ctx context.Context, carID string, photoID string) (*models.CarPhoto, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	if err := lockCar(ctx, tx, carID); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE car_photos SET is_primary = false WHERE car_id = $1 AND is_primary AND id <> $2`,
+		carID, photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	photo := models.CarPhoto{}
+	err = tx.GetContext(ctx, &photo, `UPDATE car_photos SET is_primary = true WHERE car_id = $1 AND id = $2
+		RETURNING `+photoColumns, carID, photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &photo, tx.Commit()
+}
+
+// lockCar serializes changes to the photos of the car carID.
+func lockCar(ctx context.Context, tx *sqlx.Tx, carID string) error {
+	var id string
+
+	return tx.GetContext(ctx, &id, `SELECT id FROM cars WHERE id = $1 FOR UPDATE`, carID)
+}
+
+// samePhotos reports whether ordered lists each id of current exactly once.
+func samePhotos(current []string, ordered []string) bool {
+	if len(current) != len(ordered) {
+		return false
+	}
+
+	seen := make(map[string]bool, len(current))
+	for _, id := range current {
+		seen[id] = true
+	}
+
+	for _, id := range ordered {
+		if !seen[id] {
+			return false
+		}
+
+		delete(seen, id)
+	}
+
+	return true
+}
@@ -0,0 +1,37 @@
+package persistence
+
+import (
+	"context"
+	"time"
+)
+
+// TakeRateLimitToken refills the bucket key at rate tokens per second up to burst and takes a token if one is left.
+// It returns the tokens left and whether a token was taken. The database clock is used so that replicas agree.
+func (r *RepositoryPg) TakeRateLimitToken(ctx context.Context, key string, burst int, rate float64) (float64, bool, error) {
+	row := struct {
+		Tokens  float64 `db:"tokens"`
+		Allowed bool    `db:"allowed"`
+	}{}
+
+	// The refill is computed inside ON CONFLICT DO UPDATE, which sees the locked current row, so concurrent requests
+	// can not take the same token.
+	err := r.db.GetContext(ctx, &row, `INSERT INTO rate_limits AS b (key, tokens, allowed, updated_at) VALUES ($1, $2::float8 - 1, true, now())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = least($2::float8, b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8)
+				- CASE WHEN least($2::float8, b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8) >= 1 THEN 1 ELSE 0 END,
+			allowed = least($2::float8, b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8) >= 1,
+			updated_at = now()
+		RETURNING tokens, allowed`, key, burst, rate)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return row.Tokens, row.Allowed, nil
+}
+
+// PruneRateLimits deletes buckets that have not been used for idle.
+func (r *RepositoryPg) PruneRateLimits(ctx context.Context, idle time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE updated_at < now() - make_interval(secs => $1)`, idle.Seconds())
+
+	return err
+}
@@ -0,0 +1,207 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// savedSearchColumns is the column list selected into savedSearchRow.
+var savedSearchColumns = `id, user_id, name, frequency, created_at, updated_at, ` +
+	strings.Join(savedSearchFilterColumns, ", ")
+
+// savedSearchRow is a saved search with its filters in the columns of saved_searches.
+type savedSearchRow struct {
+	models.SavedSearch
+	CityID      string         `db:"city_id"`
+	Category    string         `db:"category"`
+	MinPrice    *int64         `db:"min_price"`
+	MaxPrice    *int64         `db:"max_price"`
+	MinMileage  *int64         `db:"min_mileage"`
+	MaxMileage  *int64         `db:"max_mileage"`
+	MinYear     *int64         `db:"min_year"`
+	MaxYear     *int64         `db:"max_year"`
+	FuelTypes   pq.StringArray `db:"fuel_types"`
+	EngineTypes pq.StringArray `db:"engine_types"`
+	Models      pq.StringArray `db:"models"`
+	Statuses    pq.StringArray `db:"statuses"`
+	VIN         string         `db:"vin"`
+	MakeID      string         `db:"make_id"`
+	ModelID     string         `db:"model_id"`
+}
+
+func (row savedSearchRow) savedSearch() models.SavedSearch {
+	search := row.SavedSearch
+	search.Filters = models.CarFilter{
+		CityID:      row.CityID,
+		Category:    row.Category,
+		VIN:         row.VIN,
+		MakeID:      row.MakeID,
+		ModelID:     row.ModelID,
+		MinPrice:    row.MinPrice,
+		MaxPrice:    row.MaxPrice,
+		MinMileage:  row.MinMileage,
+		MaxMileage:  row.MaxMileage,
+		MinYear:     row.MinYear,
+		MaxYear:     row.MaxYear,
+		FuelTypes:   row.FuelTypes,
+		EngineTypes: row.EngineTypes,
+		Models:      row.Models,
+	}
+
+	for _, status := range row.Statuses {
+		search.Filters.Statuses = append(search.Filters.Statuses, models.AuctionStatus(status))
+	}
+
+	return search
+}
+
+// savedSearchWriteError maps the violation of the unique name of saved searches onto models.ErrSavedSearchExists.
+func savedSearchWriteError(err error) error {
+	if isUniqueViolation(err, "saved_searches_name_key") {
+		return models.ErrSavedSearchExists
+	}
+
+	return err
+}
+
+// ListSavedSearches returns the saved searches of userID by name.
+func (r *RepositoryPg) ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error) {
+	rows := []savedSearchRow{}
+	if err := r.db.SelectContext(ctx, &rows, `SELECT `+savedSearchColumns+` FROM saved_searches WHERE user_id = $1
+		ORDER BY lower(name), id`, userID); err != nil {
+		return nil, err
+	}
+
+	searches := make([]models.SavedSearch, len(rows))
+	for i, row := range rows {
+		searches[i] = row.savedSearch()
+	}
+
+	return searches, nil
+}
+
+// CreateSavedSearch returns models.ErrTooManySavedSearches once the user has models.MaxSavedSearches saved searches,
+// and models.ErrSavedSearchExists when the name is taken.
+func (r *RepositoryPg) CreateSavedSearch(ctx context.Context, search models.SavedSearch) (*models.SavedSearch, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	// Locking the user keeps concurrent requests from saving more searches than allowed.
+	var userID string
+	if err := tx.GetContext(ctx, &userID, `SELECT user_id FROM users WHERE user_id = $1 FOR UPDATE`, search.UserID); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := tx.GetContext(ctx, &count, `SELECT count(*) FROM saved_searches WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	if count >= models.MaxSavedSearches {
+		return nil, models.ErrTooManySavedSearches
+	}
+
+	placeholders := make([]string, len(savedSearchFilterColumns))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+4)
+	}
+
+	row := savedSearchRow{}
+	err = tx.GetContext(ctx, &row, `INSERT INTO saved_searches(user_id, name, frequency, `+
+		strings.Join(savedSearchFilterColumns, ", ")+`) VALUES($1, $2, $3, `+strings.Join(placeholders, ", ")+`)
+		RETURNING `+savedSearchColumns,
+		append([]interface{}{userID, search.Name, search.Frequency}, carFilterArgs(search.Filters)...)...)
+	if err != nil {
+		return nil, savedSearchWriteError(err)
+	}
+
+	created := row.savedSearch()
+
+	return &created, tx.Commit()
+}
+
+// UpdateSavedSearch replaces the name, frequency and filters of the saved search of search.UserID with id search.ID.
+// It returns sql.ErrNoRows when the user has no such search.
+func (r *RepositoryPg) UpdateSavedSearch(ctx context.Context, search models.SavedSearch) (*models.SavedSearch, error) {
+	assignments := make([]string, len(savedSearchFilterColumns))
+	for i, column := range savedSearchFilterColumns {
+		assignments[i] = fmt.Sprintf("%s = $%d", column, i+5)
+	}
+
+	row := savedSearchRow{}
+	err := r.db.GetContext(ctx, &row, `UPDATE saved_searches SET name = $3, frequency = $4, `+
+		strings.Join(assignments, ", ")+`, updated_at = now() WHERE id = $1 AND user_id = $2
+		RETURNING `+savedSearchColumns,
+		append([]interface{}{search.ID, search.UserID, search.Name, search.Frequency}, carFilterArgs(search.Filters)...)...)
+	if err != nil {
+		return nil, savedSearchWriteError(err)
+	}
+
+	updated := row.savedSearch()
+
+	return &updated, nil
+}
+
+// DeleteSavedSearch returns sql.ErrNoRows when userID has no saved search searchID. Its pending alerts are dropped.
+func (r *RepositoryPg) DeleteSavedSearch(ctx context.Context, userID string, searchID string) (*models.SavedSearch, error) {
+	row := savedSearchRow{}
+	err := r.db.GetContext(ctx, &row, `DELETE FROM saved_searches WHERE id = $1 AND user_id = $2
+		RETURNING `+savedSearchColumns, searchID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := row.savedSearch()
+
+	return &deleted, nil
+}
+
+// QueueSearchAlerts queues an alert of the car carID for every user with a saved search matching it, other than its
+// seller, and returns the number of alerts queued. Users matched by several searches get a single alert, sent at the
+// most frequent of their frequencies.
+func (r *RepositoryPg) QueueSearchAlerts(ctx context.Context, carID string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO search_alerts(user_id, car_id, search_id, frequency)
+		SELECT DISTINCT ON (saved_searches.user_id) saved_searches.user_id, $1::uuid, saved_searches.id,
+			saved_searches.frequency
+		FROM saved_searches WHERE EXISTS (SELECT 1 FROM cars WHERE id = $1::uuid
+			AND seller_id IS DISTINCT FROM saved_searches.user_id AND `+savedSearchWhere()+`)
+		ORDER BY saved_searches.user_id, saved_searches.frequency = 'instant' DESC, saved_searches.created_at
+		ON CONFLICT DO NOTHING`, carID)
+	if err != nil {
+		return 0, err
+	}
+
+	queued, err := res.RowsAffected()
+
+	return int(queued), err
+}
+
+// ClaimSearchAlerts marks sent and returns the pending alerts of frequency of the users whose oldest pending alert of
+// frequency was queued at least age ago, by user and in the order they were queued. Alerts of cars withdrawn since
+// they were queued are marked sent but not returned.
+func (r *RepositoryPg) ClaimSearchAlerts(ctx context.Context, frequency models.AlertFrequency, age time.Duration) ([]models.SearchAlert, error) {
+	alerts := []models.SearchAlert{}
+	err := r.db.SelectContext(ctx, &alerts, `WITH claimed AS (
+			UPDATE search_alerts SET sent_at = now() WHERE sent_at IS NULL AND frequency = $1 AND user_id IN (
+				SELECT user_id FROM search_alerts WHERE sent_at IS NULL AND frequency = $1
+				GROUP BY user_id HAVING min(created_at) <= now() - $2 * interval '1 second'
+			)
+			RETURNING user_id, car_id, search_id, created_at
+		)
+		SELECT claimed.user_id, claimed.car_id::text AS car_id, saved_searches.name AS search_name, cars.car_name,
+			COALESCE(cars.biding_price, 0) AS biding_price, cars.bid_expiration_time
+		FROM claimed JOIN saved_searches ON saved_searches.id = claimed.search_id JOIN cars ON cars.id = claimed.car_id
+		WHERE cars.withdrawn_at IS NULL ORDER BY claimed.user_id, claimed.created_at, claimed.car_id`,
+		frequency, age.Seconds())
+
+	return alerts, err
+}
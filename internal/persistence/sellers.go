@@ -0,0 +1,219 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
+)
+
+// documentColumns is the column list selected into sellermodels.Document. The content is never selected with it.
+const documentColumns = `id, user_id, verification_id, kind, file_name, content_type, size, uploaded_at`
+
+// verificationColumns is the column list selected into sellermodels.Verification.
+const verificationColumns = `id, user_id, status, submitted_at, reviewed_by, reviewed_at, reason`
+
+func (r *RepositoryPg) CreateKYCDocument(ctx context.Context, doc sellermodels.Document, content []byte) (*sellermodels.Document, error) {
+	created := sellermodels.Document{}
+
+	err := r.db.GetContext(ctx, &created, `INSERT INTO kyc_documents(user_id, kind, file_name, content_type, size, content)
+		VALUES($1, $2, $3, $4, $5, $6) RETURNING `+documentColumns,
+		doc.UserID, doc.Kind, doc.FileName, doc.ContentType, len(content), content)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// ListUnsubmittedKYCDocuments returns the documents of userID that are not attached to a verification request yet.
+func (r *RepositoryPg) ListUnsubmittedKYCDocuments(ctx context.Context, userID string) ([]sellermodels.Document, error) {
+	docs := []sellermodels.Document{}
+
+	err := r.db.SelectContext(ctx, &docs, `SELECT `+documentColumns+` FROM kyc_documents
+		WHERE user_id = $1 AND verification_id IS NULL ORDER BY uploaded_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func (r *RepositoryPg) ListVerificationDocuments(ctx context.Context, verificationID string) ([]sellermodels.Document, error) {
+	docs := []sellermodels.Document{}
+
+	err := r.db.SelectContext(ctx, &docs, `SELECT `+documentColumns+` FROM kyc_documents
+		WHERE verification_id = $1 ORDER BY uploaded_at`, verificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// GetKYCDocumentContent returns a document and its content.
+func (r *RepositoryPg) GetKYCDocumentContent(ctx context.Context, documentID string) (*sellermodels.Document, []byte, error) {
+	row := struct {
+		sellermodels.Document
+		Content []byte `db:"content"`
+	}{}
+
+	err := r.db.GetContext(ctx, &row, `SELECT `+documentColumns+`, content FROM kyc_documents WHERE id = $1`, documentID)
+	if isInvalidText(err) {
+		return nil, nil, sql.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &row.Document, row.Content, nil
+}
+
+// SubmitSellerVerification opens a verification request for userID with all their unsubmitted documents and marks the
+// user as pending.
+func (r *RepositoryPg) SubmitSellerVerification(ctx context.Context, userID string) (*sellermodels.Verification, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	verification := sellermodels.Verification{}
+
+	err = tx.GetContext(ctx, &verification, `INSERT INTO seller_verifications(user_id) VALUES($1) RETURNING `+verificationColumns, userID)
+	if isUniqueViolation(err, "seller_verifications_pending_key") {
+		return nil, sellermodels.ErrVerificationPending
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE kyc_documents SET verification_id = $2 WHERE user_id = $1 AND verification_id IS NULL`,
+		userID, verification.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET seller_status = 'pending' WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification, tx.Commit()
+}
+
+// ListSellerVerifications returns the verification requests with status, oldest first so the queue is worked in order.
+func (r *RepositoryPg) ListSellerVerifications(ctx context.Context, status string) ([]sellermodels.Verification, error) {
+	verifications := []sellermodels.Verification{}
+
+	err := r.db.SelectContext(ctx, &verifications, `SELECT `+verificationColumns+` FROM seller_verifications
+		WHERE ($1 = '' OR status = $1) ORDER BY submitted_at`, status)
+	if err != nil {
+		return nil, err
+	}
+
+	return verifications, nil
+}
+
+func (r *RepositoryPg) GetSellerVerification(ctx context.Context, verificationID string) (*sellermodels.Verification, error) {
+	verification := sellermodels.Verification{}
+
+	err := r.db.GetContext(ctx, &verification, `SELECT `+verificationColumns+` FROM seller_verifications WHERE id = $1`, verificationID)
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return nil, sellermodels.ErrVerificationNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// GetLatestSellerVerification returns the most recent verification request of userID, or nil if there is none.
+func (r *RepositoryPg) GetLatestSellerVerification(ctx context.Context, userID string) (*sellermodels.Verification, error) {
+	verification := sellermodels.Verification{}
+
+	err := r.db.GetContext(ctx, &verification, `SELECT `+verificationColumns+` FROM seller_verifications
+		WHERE user_id = $1 ORDER BY submitted_at DESC LIMIT 1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// ReviewSellerVerification records the outcome of a pending verification request and updates the seller's status.
+// Approved buyers are promoted to sellers. The review is audited in the same transaction.
+func (r *RepositoryPg) ReviewSellerVerification(ctx context.Context, verificationID string, reviewerID string, approve bool,
+	reason string,
+) (*sellermodels.Verification, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	status, sellerStatus, action := sellermodels.VerificationRejected, sellermodels.StatusRejected,
+		auditmodels.ActionVerificationRejected
+	if approve {
+		status, sellerStatus, action = sellermodels.VerificationApproved, sellermodels.StatusVerified,
+			auditmodels.ActionVerificationApproved
+	}
+
+	before := sellermodels.Verification{}
+
+	err = tx.GetContext(ctx, &before, `SELECT `+verificationColumns+` FROM seller_verifications WHERE id = $1 FOR UPDATE`,
+		verificationID)
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return nil, sellermodels.ErrVerificationNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	verification := sellermodels.Verification{}
+
+	err = tx.GetContext(ctx, &verification, `UPDATE seller_verifications
+		SET status = $2, reviewed_by = $3, reviewed_at = now(), reason = NULLIF($4, '')
+		WHERE id = $1 AND status = 'pending' RETURNING `+verificationColumns,
+		verificationID, status, reviewerID, reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, sellermodels.ErrVerificationNotPending
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET seller_status = $2,
+			role = CASE WHEN $3 AND role = 'buyer' THEN 'seller' ELSE role END
+		WHERE user_id = $1`, verification.UserID, sellerStatus, approve)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordChange(ctx, tx, auditmodels.Change{
+		Action:     action,
+		EntityType: auditmodels.EntitySellerVerification,
+		EntityID:   verificationID,
+		Before:     &before,
+		After:      &verification,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification, tx.Commit()
+}
@@ -0,0 +1,306 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// vehicleTable is where the entries of a taxonomy level are stored.
+type vehicleTable struct {
+	name string
+	// parent is the column referencing the parent entry. Makes have none.
+	parent string
+	// carID and carName are the columns of cars that name the entry.
+	carID, carName string
+	// child is the level below, if any.
+	child models.VehicleKind
+}
+
+var vehicleTables = map[models.VehicleKind]vehicleTable{
+	models.VehicleMake:  {name: "vehicle_makes", carID: "make_id", carName: "make", child: models.VehicleModel},
+	models.VehicleModel: {name: "vehicle_models", parent: "make_id", carID: "model_id", carName: "car_model", child: models.VehicleTrim},
+	models.VehicleTrim:  {name: "vehicle_trims", parent: "model_id", carID: "trim_id", carName: "trim"},
+}
+
+// vehicleRow is a models.Vehicle as selected by vehicleSelect.
+type vehicleRow struct {
+	models.Vehicle
+	Aliases pq.StringArray `db:"aliases"`
+}
+
+func (row vehicleRow) vehicle() models.Vehicle {
+	vehicle := row.Vehicle
+	vehicle.Aliases = row.Aliases
+
+	return vehicle
+}
+
+// vehicleSelect returns the SELECT of the entries of kind, aliased as v.
+func vehicleSelect(kind models.VehicleKind) (vehicleTable, string, error) {
+	table, ok := vehicleTables[kind]
+	if !ok {
+		return table, "", models.ErrInvalidVehicleKind
+	}
+
+	parent := "''"
+	if table.parent != "" {
+		parent = "v." + table.parent
+	}
+
+	return table, fmt.Sprintf(`SELECT '%[1]s' AS kind, v.id, %[2]s AS parent_id, v.name, v.created_at,
+		ARRAY(SELECT name FROM vehicle_aliases WHERE kind = '%[1]s' AND target_id = v.id ORDER BY name) AS aliases
+		FROM %[3]s v`, kind, parent, table.name), nil
+}
+
+// SeedVehicles inserts vehicles, parents before their children, unless the taxonomy already has makes. It reports
+// whether the vehicles were inserted. Once seeded the taxonomy is only changed by admins, so that merged entries stay
+// merged.
+func (r *RepositoryPg) SeedVehicles(ctx context.Context, vehicles []models.Vehicle) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	// The lock keeps api instances starting together from seeding twice.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE vehicle_makes IN EXCLUSIVE MODE`); err != nil {
+		return false, err
+	}
+
+	var seeded bool
+	if err := tx.GetContext(ctx, &seeded, `SELECT EXISTS (SELECT 1 FROM vehicle_makes)`); err != nil {
+		return false, err
+	}
+
+	if seeded {
+		return false, nil
+	}
+
+	for _, vehicle := range vehicles {
+		if err := insertVehicle(ctx, tx, vehicle, "ON CONFLICT DO NOTHING"); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+func insertVehicle(ctx context.Context, tx sqlx.ExecerContext, vehicle models.Vehicle, onConflict string) error {
+	table, ok := vehicleTables[vehicle.Kind]
+	if !ok {
+		return models.ErrInvalidVehicleKind
+	}
+
+	if table.parent == "" {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s(id, name) VALUES($1, $2) %s`, table.name, onConflict),
+			vehicle.ID, vehicle.Name)
+
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s(id, %s, name) VALUES($1, $2, $3) %s`,
+		table.name, table.parent, onConflict), vehicle.ID, vehicle.ParentID, vehicle.Name)
+
+	return err
+}
+
+// ListVehicles returns the entries of kind by name. Models and trims are limited to the children of parentID unless
+// it is empty.
+func (r *RepositoryPg) ListVehicles(ctx context.Context, kind models.VehicleKind, parentID string) ([]models.Vehicle, error) {
+	table, query, err := vehicleSelect(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{}
+
+	if table.parent != "" && parentID != "" {
+		query += " WHERE v." + table.parent + " = $1"
+
+		args = append(args, parentID)
+	}
+
+	rows := []vehicleRow{}
+	if err := r.db.SelectContext(ctx, &rows, query+" ORDER BY lower(v.name), v.id", args...); err != nil {
+		return nil, err
+	}
+
+	vehicles := make([]models.Vehicle, len(rows))
+	for i, row := range rows {
+		vehicles[i] = row.vehicle()
+	}
+
+	return vehicles, nil
+}
+
+func (r *RepositoryPg) GetVehicle(ctx context.Context, kind models.VehicleKind, vehicleID string) (*models.Vehicle, error) {
+	_, query, err := vehicleSelect(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	row := vehicleRow{}
+	if err := r.db.GetContext(ctx, &row, query+" WHERE v.id = $1", vehicleID); err != nil {
+		return nil, err
+	}
+
+	vehicle := row.vehicle()
+
+	return &vehicle, nil
+}
+
+// CreateVehicle returns models.ErrVehicleExists when the id, or the name under the same parent, is taken.
+func (r *RepositoryPg) CreateVehicle(ctx context.Context, vehicle models.Vehicle) (*models.Vehicle, error) {
+	table, ok := vehicleTables[vehicle.Kind]
+	if !ok {
+		return nil, models.ErrInvalidVehicleKind
+	}
+
+	err := insertVehicle(ctx, r.db, vehicle, "")
+	if isUniqueViolation(err, table.name+"_pkey") || isUniqueViolation(err, table.name+"_name_key") {
+		return nil, models.ErrVehicleExists
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		return nil, sql.ErrNoRows
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetVehicle(ctx, vehicle.Kind, vehicle.ID)
+}
+
+// MergeVehicles merges the entry fromID into intoID and deletes it. Children of fromID are moved under intoID, or
+// merged into the child of intoID with the same name. Cars are remapped, their version incremented, and the names of
+// merged entries kept as aliases of the entries they were merged into.
+func (r *RepositoryPg) MergeVehicles(ctx context.Context, kind models.VehicleKind, fromID string, intoID string) (*models.VehicleMerge, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	cars, err := mergeVehicle(ctx, tx, kind, fromID, intoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VehicleMerge{Kind: kind, FromID: fromID, IntoID: intoID, Cars: cars}, tx.Commit()
+}
+
+// mergeVehicle merges fromID into intoID within tx and returns the number of cars remapped.
+func mergeVehicle(ctx context.Context, tx *sqlx.Tx, kind models.VehicleKind, fromID string, intoID string) (int, error) {
+	if fromID == intoID {
+		return 0, fmt.Errorf("%w: an entry can not be merged into itself", models.ErrInvalidMerge)
+	}
+
+	table, query, err := vehicleSelect(kind)
+	if err != nil {
+		return 0, err
+	}
+
+	var from, into vehicleRow
+	if err := tx.GetContext(ctx, &from, query+" WHERE v.id = $1 FOR UPDATE", fromID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.GetContext(ctx, &into, query+" WHERE v.id = $1 FOR UPDATE", intoID); err != nil {
+		return 0, err
+	}
+
+	if parent, ok := kind.Parent(); ok && from.ParentID != into.ParentID {
+		return 0, fmt.Errorf("%w: %ss can only be merged within the same %s", models.ErrInvalidMerge, kind, parent)
+	}
+
+	cars := 0
+
+	if child, ok := vehicleTables[table.child]; ok {
+		children := []models.Vehicle{}
+		err := tx.SelectContext(ctx, &children, fmt.Sprintf(`SELECT id, name FROM %s WHERE %s = $1 ORDER BY id`,
+			child.name, child.parent), fromID)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, c := range children {
+			var twin string
+
+			err := tx.GetContext(ctx, &twin, fmt.Sprintf(`SELECT id FROM %s WHERE %s = $1 AND lower(name) = lower($2)`,
+				child.name, child.parent), intoID, c.Name)
+
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET %s = $2 WHERE id = $1`, child.name, child.parent),
+					c.ID, intoID)
+			case err == nil:
+				var merged int
+				merged, err = mergeVehicle(ctx, tx, table.child, c.ID, twin)
+				cars += merged
+			}
+
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE cars SET %[1]s = $2, %[2]s = $3, updated_at = now(),
+		version = version + 1 WHERE %[1]s = $1`, table.carID, table.carName), fromID, intoID, into.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	remapped, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO vehicle_aliases(kind, target_id, name)
+		SELECT kind, $3::text, name FROM vehicle_aliases WHERE kind = $1::text AND target_id = $2
+		UNION SELECT $1::text, $3::text, $4::text
+		ON CONFLICT DO NOTHING`, kind, fromID, intoID, from.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM vehicle_aliases WHERE kind = $1 AND target_id = $2`, kind, fromID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, table.name), fromID); err != nil {
+		return 0, err
+	}
+
+	return cars + int(remapped), nil
+}
+
+// ListUnmappedCars returns the cars whose make, model or trim is not mapped onto the taxonomy.
+func (r *RepositoryPg) ListUnmappedCars(ctx context.Context) ([]models.Cars, error) {
+	cars := []models.Cars{}
+	err := r.db.SelectContext(ctx, &cars, `SELECT `+carColumns+` FROM cars
+		WHERE (make_id IS NULL AND (make <> '' OR car_name <> '')) OR (model_id IS NULL AND car_model <> '')
+			OR (trim_id IS NULL AND trim <> '')
+		ORDER BY date_posted, id`)
+
+	return cars, err
+}
+
+// SetCarVehicle stores the make, model and trim of car, with their taxonomy ids, and increments its version.
+func (r *RepositoryPg) SetCarVehicle(ctx context.Context, car models.Cars) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE cars SET make = $2, car_model = $3, trim = $4, make_id = $5, model_id = $6,
+		trim_id = $7, updated_at = now(), version = version + 1 WHERE id = $1`,
+		car.ID, car.Make, car.CarModel, car.Trim, nullString(car.MakeID), nullString(car.ModelID), nullString(car.TrimID))
+
+	return err
+}
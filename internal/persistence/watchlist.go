@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// watchedCarColumns is the column list selected into models.WatchedCar from cars joined with watchlist.
+const watchedCarColumns = carColumns + `,
+	COALESCE((SELECT max(bid_amount::numeric) FROM bids WHERE bids.car_id = cars.id::text
+		AND bid_amount ~ '^[0-9]+(\.[0-9]+)?$'), biding_price, 0)::bigint AS current_price,
+	COALESCE(GREATEST(extract(epoch FROM bid_expiration_time - now()), 0), 0)::bigint AS seconds_left,
+	watchlist.created_at AS watched_at`
+
+// WatchCar adds the car carID to the watchlist of userID. Watching a car twice has no effect. Cars watched after
+// their auction ended are not announced as sold.
+func (r *RepositoryPg) WatchCar(ctx context.Context, userID string, carID string) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO watchlist(user_id, car_id, closed_for)
+		SELECT $1, id, CASE WHEN bid_expiration_time <= now() THEN bid_expiration_time END FROM cars WHERE id = $2
+		ON CONFLICT DO NOTHING`, userID, carID)
+
+	return err
+}
+
+// UnwatchCar returns sql.ErrNoRows when the car carID is not on the watchlist of userID.
+func (r *RepositoryPg) UnwatchCar(ctx context.Context, userID string, carID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM watchlist WHERE user_id = $1 AND car_id = $2`, userID, carID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListWatchlist returns the cars userID watches, open auctions ending soonest first, then ended and withdrawn ones.
+func (r *RepositoryPg) ListWatchlist(ctx context.Context, userID string) ([]models.WatchedCar, error) {
+	cars := []models.WatchedCar{}
+	err := r.db.SelectContext(ctx, &cars, `SELECT `+watchedCarColumns+` FROM cars
+		JOIN watchlist ON watchlist.car_id = cars.id WHERE watchlist.user_id = $1
+		ORDER BY withdrawn_at IS NOT NULL OR bid_expiration_time <= now(), bid_expiration_time, watchlist.created_at`,
+		userID)
+
+	return cars, err
+}
+
+func (r *RepositoryPg) GetWatchedCar(ctx context.Context, userID string, carID string) (*models.WatchedCar, error) {
+	car := models.WatchedCar{}
+	err := r.db.GetContext(ctx, &car, `SELECT `+watchedCarColumns+` FROM cars
+		JOIN watchlist ON watchlist.car_id = cars.id WHERE watchlist.user_id = $1 AND watchlist.car_id = $2`,
+		userID, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &car, nil
+}
+
+// ListWatchers returns the users watching the car carID.
+func (r *RepositoryPg) ListWatchers(ctx context.Context, carID string) ([]models.Users, error) {
+	users := []models.Users{}
+	err := r.db.SelectContext(ctx, &users, `SELECT `+userColumns+` FROM users
+		WHERE user_id IN (SELECT user_id FROM watchlist WHERE car_id = $1) ORDER BY created_at`, carID)
+
+	return users, err
+}
+
+// ClaimEndingWatches returns the watches of listed cars whose auction ends within the next period and marks them
+// notified for the current deadline, so that each watcher is told once per deadline even with several replicas.
+func (r *RepositoryPg) ClaimEndingWatches(ctx context.Context, period time.Duration) ([]models.Watch, error) {
+	watches := []models.Watch{}
+	err := r.db.SelectContext(ctx, &watches, `UPDATE watchlist SET ending_notified_for = cars.bid_expiration_time
+		FROM cars WHERE cars.id = watchlist.car_id AND cars.withdrawn_at IS NULL AND cars.bid_expiration_time > now()
+		AND cars.bid_expiration_time <= now() + $1 * interval '1 second'
+		AND watchlist.ending_notified_for IS DISTINCT FROM cars.bid_expiration_time
+		RETURNING watchlist.user_id, watchlist.car_id::text AS car_id`, period.Seconds())
+
+	return watches, err
+}
+
+// ClaimSoldWatches marks the watches of ended auctions closed for their deadline and returns those of the cars that
+// received bids. Payments are not recorded, so a car counts as sold once its auction ends with a bid.
+func (r *RepositoryPg) ClaimSoldWatches(ctx context.Context) ([]models.Watch, error) {
+	watches := []models.Watch{}
+	err := r.db.SelectContext(ctx, &watches, `WITH closed AS (
+			UPDATE watchlist SET closed_for = cars.bid_expiration_time FROM cars WHERE cars.id = watchlist.car_id
+			AND cars.withdrawn_at IS NULL AND cars.bid_expiration_time <= now()
+			AND watchlist.closed_for IS DISTINCT FROM cars.bid_expiration_time
+			RETURNING watchlist.user_id, watchlist.car_id
+		)
+		SELECT user_id, car_id::text AS car_id FROM closed
+		WHERE EXISTS (SELECT 1 FROM bids WHERE bids.car_id = closed.car_id::text)`)
+
+	return watches, err
+}
@@ -0,0 +1,283 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/audit"
+)
+
+//go:generate mockgen -source ./apikey_service.go -destination mocks/apikey_service.mock.go -package mocks
+
+// keyPrefix starts every API key so leaked keys are easy to recognise.
+const keyPrefix = "sak_"
+
+// Service manages partner organizations and their API keys.
+//
+//nolint:interfacebloat
+type Service interface {
+	CreateOrganization(ctx context.Context, req authmodels.CreateOrganizationRequest) (*authmodels.Organization, error)
+	ListOrganizations(ctx context.Context) ([]authmodels.Organization, error)
+	CreateAPIKey(ctx context.Context, organizationID string, req authmodels.CreateAPIKeyRequest) (*authmodels.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, organizationID string) ([]authmodels.APIKey, error)
+	GetAPIKey(ctx context.Context, keyID string) (*authmodels.APIKey, error)
+	UpdateAPIKey(ctx context.Context, keyID string, req authmodels.UpdateAPIKeyRequest) (*authmodels.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID string) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*authmodels.Principal, error)
+}
+
+type ServiceImpl struct {
+	repo    persistence.Repository
+	auditor audit.Recorder
+}
+
+//nolint:exhaustivestruct
+var _ Service = &ServiceImpl{}
+
+func NewService(repo persistence.Repository, auditor audit.Recorder) (*ServiceImpl, error) {
+	return &ServiceImpl{repo: repo, auditor: auditor}, nil
+}
+
+// CreateOrganization implements Service.
+func (s *ServiceImpl) CreateOrganization(ctx context.Context, req authmodels.CreateOrganizationRequest) (*authmodels.Organization, error) {
+	if _, err := s.repo.GetUserByID(ctx, req.OwnerID); err != nil {
+		return nil, err
+	}
+
+	org, err := s.repo.CreateOrganization(ctx, authmodels.Organization{
+		Name:    strings.TrimSpace(req.Name),
+		OwnerID: req.OwnerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionOrganizationCreated,
+		EntityType: auditmodels.EntityOrganization,
+		EntityID:   org.ID,
+		After:      org,
+	})
+
+	return org, nil
+}
+
+// ListOrganizations implements Service.
+func (s *ServiceImpl) ListOrganizations(ctx context.Context) ([]authmodels.Organization, error) {
+	return s.repo.ListOrganizations(ctx)
+}
+
+// CreateAPIKey implements Service. The returned key is only available in this response.
+func (s *ServiceImpl) CreateAPIKey(ctx context.Context, organizationID string, req authmodels.CreateAPIKeyRequest) (*authmodels.CreatedAPIKey, error) {
+	if err := validateScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+
+	prefix, secret, err := newKeyParts()
+	if err != nil {
+		return nil, err
+	}
+
+	key := authmodels.APIKey{
+		OrganizationID: organizationID,
+		Name:           strings.TrimSpace(req.Name),
+		Prefix:         prefix,
+		Scopes:         req.Scopes,
+		ExpiresAt:      req.ExpiresAt,
+	}
+
+	if principal, ok := authmodels.PrincipalFromContext(ctx); ok {
+		key.CreatedBy = &principal.UserID
+	}
+
+	plainKey := keyPrefix + prefix + "_" + secret
+
+	created, err := s.repo.CreateAPIKey(ctx, key, hashKey(plainKey))
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionAPIKeyCreated,
+		EntityType: auditmodels.EntityAPIKey,
+		EntityID:   created.ID,
+		After:      created,
+	})
+
+	return &authmodels.CreatedAPIKey{APIKey: *created, Key: plainKey}, nil
+}
+
+// ListAPIKeys implements Service.
+func (s *ServiceImpl) ListAPIKeys(ctx context.Context, organizationID string) ([]authmodels.APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, organizationID)
+}
+
+// GetAPIKey implements Service.
+func (s *ServiceImpl) GetAPIKey(ctx context.Context, keyID string) (*authmodels.APIKey, error) {
+	return s.repo.GetAPIKey(ctx, keyID)
+}
+
+// UpdateAPIKey implements Service.
+func (s *ServiceImpl) UpdateAPIKey(ctx context.Context, keyID string, req authmodels.UpdateAPIKeyRequest) (*authmodels.APIKey, error) {
+	if req.Scopes != nil {
+		if err := validateScopes(req.Scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	before, err := s.repo.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateAPIKey(ctx, keyID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionAPIKeyUpdated,
+		EntityType: auditmodels.EntityAPIKey,
+		EntityID:   keyID,
+		Before:     before,
+		After:      updated,
+	})
+
+	return updated, nil
+}
+
+// RevokeAPIKey implements Service.
+func (s *ServiceImpl) RevokeAPIKey(ctx context.Context, keyID string) error {
+	before, err := s.repo.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeAPIKey(ctx, keyID); err != nil {
+		return err
+	}
+
+	after, err := s.repo.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionAPIKeyRevoked,
+		EntityType: auditmodels.EntityAPIKey,
+		EntityID:   keyID,
+		Before:     before,
+		After:      after,
+	})
+
+	return nil
+}
+
+// AuthenticateAPIKey implements Service. The key acts as its organization's owner, limited to its scopes. Keys of
+// organizations owned by admins act as sellers, so that a leaked key can not act on every car.
+func (s *ServiceImpl) AuthenticateAPIKey(ctx context.Context, key string) (*authmodels.Principal, error) {
+	prefix, ok := parsePrefix(key)
+	if !ok {
+		return nil, authmodels.ErrInvalidAPIKey
+	}
+
+	credentials, err := s.repo.GetAPIKeyCredentials(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(credentials.KeyHash)) != 1 {
+		return nil, authmodels.ErrInvalidAPIKey
+	}
+
+	if !credentials.RevokedAt.IsZero() || (!credentials.ExpiresAt.IsZero() && credentials.ExpiresAt.Before(time.Now())) {
+		return nil, authmodels.ErrInvalidAPIKey
+	}
+
+	if err := s.repo.TouchAPIKey(ctx, credentials.ID); err != nil {
+		return nil, err
+	}
+
+	role := authmodels.Role(credentials.OwnerRole)
+	if role == authmodels.RoleAdmin {
+		role = authmodels.RoleSeller
+	}
+
+	return &authmodels.Principal{
+		UserID:         credentials.OwnerID,
+		Role:           role,
+		APIKeyID:       credentials.ID,
+		OrganizationID: credentials.OrganizationID,
+		Scopes:         credentials.Scopes,
+	}, nil
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return authmodels.ErrInvalidScope
+	}
+
+	for _, scope := range scopes {
+		valid := false
+
+		for _, known := range authmodels.ValidScopes {
+			if scope == known {
+				valid = true
+
+				break
+			}
+		}
+
+		if !valid {
+			return authmodels.ErrInvalidScope
+		}
+	}
+
+	return nil
+}
+
+// prefixBytes is the length of the random lookup prefix of new keys. Prefixes are unique; with 64 random bits a new key
+// practically never collides with an existing one.
+const prefixBytes = 8
+
+// legacyPrefixBytes is the length of the prefix of the keys created before prefixes were lengthened.
+const legacyPrefixBytes = 4
+
+// newKeyParts returns the lookup prefix and the secret part of a new key.
+func newKeyParts() (string, string, error) {
+	raw := make([]byte, prefixBytes+32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	return hex.EncodeToString(raw[:prefixBytes]), base64.RawURLEncoding.EncodeToString(raw[prefixBytes:]), nil
+}
+
+// parsePrefix extracts the lookup prefix from a key of the form sak_<prefix>_<secret>.
+func parsePrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return "", false
+	}
+
+	prefix, secret, found := strings.Cut(strings.TrimPrefix(key, keyPrefix), "_")
+	if !found || (len(prefix) != 2*prefixBytes && len(prefix) != 2*legacyPrefixBytes) || secret == "" {
+		return "", false
+	}
+
+	return prefix, true
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
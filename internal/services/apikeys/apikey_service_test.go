@@ -0,0 +1,91 @@
+package apikeys
+
+import (
+	"context"
+	"testing"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrefix(t *testing.T) {
+	prefix, secret, err := newKeyParts()
+	require.NoError(t, err)
+	assert.Len(t, prefix, 16)
+
+	tests := []struct {
+		name   string
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"new key", keyPrefix + prefix + "_" + secret, prefix, true},
+		{"key with a short prefix", keyPrefix + "0a1b2c3d_" + secret, "0a1b2c3d", true},
+		{"other prefix length", keyPrefix + "0a1b2c_" + secret, "", false},
+		{"no secret", keyPrefix + prefix + "_", "", false},
+		{"not an api key", "Bearer " + prefix, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parsePrefix(tt.key)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type fakeRepo struct {
+	persistence.Repository
+
+	credentials authmodels.APIKeyCredentials
+}
+
+func (r *fakeRepo) GetAPIKeyCredentials(_ context.Context, prefix string) (*authmodels.APIKeyCredentials, error) {
+	if prefix != r.credentials.Prefix {
+		return nil, authmodels.ErrInvalidAPIKey
+	}
+
+	credentials := r.credentials
+
+	return &credentials, nil
+}
+
+func (r *fakeRepo) TouchAPIKey(context.Context, string) error {
+	return nil
+}
+
+func TestAuthenticateAPIKey_Role(t *testing.T) {
+	prefix, secret, err := newKeyParts()
+	require.NoError(t, err)
+
+	key := keyPrefix + prefix + "_" + secret
+
+	for _, tt := range []struct{ owner, want authmodels.Role }{
+		{authmodels.RoleBuyer, authmodels.RoleBuyer},
+		{authmodels.RoleSeller, authmodels.RoleSeller},
+		{authmodels.RoleAdmin, authmodels.RoleSeller},
+	} {
+		t.Run(string(tt.owner), func(t *testing.T) {
+			repo := &fakeRepo{credentials: authmodels.APIKeyCredentials{
+				APIKey:    authmodels.APIKey{ID: "key-1", Prefix: prefix},
+				KeyHash:   hashKey(key),
+				OwnerID:   "owner-1",
+				OwnerRole: string(tt.owner),
+			}}
+
+			service, err := NewService(repo, nil)
+			require.NoError(t, err)
+
+			principal, err := service.AuthenticateAPIKey(context.Background(), key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, principal.Role, "api keys never act as admins")
+			assert.False(t, principal.IsAdmin())
+
+			_, err = service.AuthenticateAPIKey(context.Background(), key+"x")
+			assert.ErrorIs(t, err, authmodels.ErrInvalidAPIKey)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/rs/zerolog"
+)
+
+var logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+
+//go:generate mockgen -source ./audit_service.go -destination mocks/audit_service.mock.go -package mocks
+
+// Recorder records state-changing actions in the audit log.
+type Recorder interface {
+	// Record appends change to the audit log, attributed to the principal, request id and client ip in ctx.
+	// The action has already happened, so failures are logged rather than returned. New listings, car updates, bids,
+	// new users and admin decisions about users and listings are audited by the repository instead, in the transaction
+	// that makes them.
+	Record(ctx context.Context, change auditmodels.Change)
+}
+
+// Service records and queries the audit log.
+type Service interface {
+	Recorder
+	ListEntries(ctx context.Context, filter auditmodels.Filter) (models.Page[auditmodels.Entry], error)
+}
+
+type ServiceImpl struct {
+	repo persistence.Repository
+}
+
+//nolint:exhaustivestruct
+var _ Service = &ServiceImpl{}
+
+func NewService(repo persistence.Repository) (*ServiceImpl, error) {
+	return &ServiceImpl{
+		repo: repo,
+	}, nil
+}
+
+// Record implements Recorder. A failed write is logged with the entity, actor and diff, so that the entry can be
+// restored from the logs.
+func (s *ServiceImpl) Record(ctx context.Context, change auditmodels.Change) {
+	entry, err := auditmodels.NewEntry(ctx, change)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("correlationID", fmt.Sprint(ctx.Value(auditmodels.RequestIDKey))).
+			Str("action", change.Action).
+			Str("entityType", change.EntityType).
+			Str("entityID", change.EntityID).
+			Msg("failed to build audit log entry")
+
+		return
+	}
+
+	if err := s.repo.InsertAuditEntry(ctx, *entry); err != nil {
+		logger.Error().Err(err).
+			Str("correlationID", entry.RequestID).
+			Str("action", entry.Action).
+			Str("entityType", entry.EntityType).
+			Str("entityID", entry.EntityID).
+			Str("actorID", entry.ActorID).
+			RawJSON("diff", entry.Diff).
+			Msg("failed to write audit log entry")
+	}
+}
+
+// ListEntries implements Service.
+func (s *ServiceImpl) ListEntries(ctx context.Context, filter auditmodels.Filter) (models.Page[auditmodels.Entry], error) {
+	return s.repo.ListAuditEntries(ctx, filter)
+}
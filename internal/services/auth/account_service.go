@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+
+var ErrAccountEmailsNotConfigured = errors.New("a mailer and account token signer are required")
+
+// AccountEmails configures the email verification and password reset emails.
+type AccountEmails struct {
+	Mailer services.Mailer
+	Tokens *AccountTokens
+	// AppBaseURL is the address of the frontend that the links in the emails point to.
+	AppBaseURL       string
+	VerifyEmailTTL   time.Duration
+	PasswordResetTTL time.Duration
+	// MaxPerHour limits how many emails of each kind are sent to a user per hour.
+	MaxPerHour int
+}
+
+// RequestEmailVerification implements Service by sending a new verification link to the authenticated user.
+func (s *ServiceImpl) RequestEmailVerification(ctx context.Context) error {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return authmodels.ErrUnauthenticated
+	}
+
+	user, err := s.repo.GetUserByID(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+
+	if !user.EmailVerifiedAt.IsZero() {
+		return nil
+	}
+
+	// Accounts created from a phone number have no email address to verify.
+	if user.Email == "" {
+		return authmodels.ErrInvalidEmail
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// ConfirmEmail implements Service.
+func (s *ServiceImpl) ConfirmEmail(ctx context.Context, token string) (*models.Users, error) {
+	userID, err := s.consumeToken(ctx, authmodels.TokenPurposeVerifyEmail, token)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.MarkEmailVerified(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionEmailVerified,
+		EntityType: auditmodels.EntityUser,
+		EntityID:   userID,
+		Before:     before,
+		After:      user,
+	})
+
+	return user, nil
+}
+
+// RequestPasswordReset implements Service. It succeeds whether or not an account exists for email, and rate limited
+// requests are dropped silently, so that the response does not reveal which addresses have accounts.
+func (s *ServiceImpl) RequestPasswordReset(ctx context.Context, email string) error {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := s.repo.GetCredentialsByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, credentials.UserID)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.issueToken(ctx, user.User_id, authmodels.TokenPurposeResetPassword, s.emails.PasswordResetTTL)
+	if errors.Is(err, authmodels.ErrTooManyRequests) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	link := s.link("/reset-password", token)
+
+	return s.emails.Mailer.SendEmail(ctx, services.Email{
+		To:      []string{user.Email},
+		Subject: "Reset your Sigma Auto password",
+		HTMLBody: fmt.Sprintf(`<p>Hello %s,</p><p>Follow <a href="%s">this link</a> to choose a new password. `+
+			`It expires in %s.</p><p>If you did not ask for a password reset you can ignore this email.</p>`,
+			html.EscapeString(user.UserName), html.EscapeString(link), s.emails.PasswordResetTTL),
+		TextBody: fmt.Sprintf("Hello %s,\n\nOpen %s to choose a new password. It expires in %s.\n\n"+
+			"If you did not ask for a password reset you can ignore this email.\n",
+			user.UserName, link, s.emails.PasswordResetTTL),
+	})
+}
+
+// ResetPassword implements Service. All sessions of the user are revoked.
+func (s *ServiceImpl) ResetPassword(ctx context.Context, token string, password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return authmodels.ErrWeakPassword
+	}
+
+	// The token is checked before hashing so that forged tokens are cheap to refuse.
+	if _, err := s.emails.Tokens.verify(authmodels.TokenPurposeResetPassword, token); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.consumeToken(ctx, authmodels.TokenPurposeResetPassword, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(ctx, userID, string(hash)); err != nil {
+		return err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionPasswordReset,
+		EntityType: auditmodels.EntityUser,
+		EntityID:   userID,
+	})
+
+	return s.repo.RevokeUserSessions(ctx, userID, "password_reset")
+}
+
+func (s *ServiceImpl) sendVerificationEmail(ctx context.Context, user *models.Users) error {
+	token, err := s.issueToken(ctx, user.User_id, authmodels.TokenPurposeVerifyEmail, s.emails.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.link("/verify-email", token)
+
+	return s.emails.Mailer.SendEmail(ctx, services.Email{
+		To:      []string{user.Email},
+		Subject: "Confirm your Sigma Auto email address",
+		HTMLBody: fmt.Sprintf(`<p>Hello %s,</p><p>Follow <a href="%s">this link</a> to confirm your email address. `+
+			`It expires in %s.</p>`, html.EscapeString(user.UserName), html.EscapeString(link), s.emails.VerifyEmailTTL),
+		TextBody: fmt.Sprintf("Hello %s,\n\nOpen %s to confirm your email address. It expires in %s.\n",
+			user.UserName, link, s.emails.VerifyEmailTTL),
+	})
+}
+
+// issueToken records and signs a new token, unless the user has already been sent MaxPerHour tokens for purpose.
+func (s *ServiceImpl) issueToken(ctx context.Context, userID string, purpose string, ttl time.Duration) (string, error) {
+	now := s.emails.Tokens.now()
+
+	issued, err := s.repo.CountAccountTokens(ctx, userID, purpose, now.Add(-time.Hour))
+	if err != nil {
+		return "", err
+	}
+
+	if issued >= s.emails.MaxPerHour {
+		return "", authmodels.ErrTooManyRequests
+	}
+
+	expiresAt := now.Add(ttl)
+
+	tokenID, err := s.repo.CreateAccountToken(ctx, userID, purpose, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	return s.emails.Tokens.sign(purpose, tokenID, expiresAt), nil
+}
+
+func (s *ServiceImpl) consumeToken(ctx context.Context, purpose string, token string) (string, error) {
+	tokenID, err := s.emails.Tokens.verify(purpose, token)
+	if err != nil {
+		return "", err
+	}
+
+	return s.repo.ConsumeAccountToken(ctx, tokenID, purpose)
+}
+
+func (s *ServiceImpl) link(path string, token string) string {
+	return strings.TrimSuffix(s.emails.AppBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
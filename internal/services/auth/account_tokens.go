@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+)
+
+// minAccountTokenSecretLength is the shortest secret accepted for signing account tokens.
+const minAccountTokenSecretLength = 32
+
+var ErrWeakAccountTokenSecret = errors.New("account token secret must be at least 32 bytes")
+
+// AccountTokens signs and verifies the single-use tokens sent in email verification and password reset emails.
+// A token is <id>.<expiry>.<signature>; the signature covers the purpose too, so a token can only be used for the
+// purpose it was issued for. Single use is enforced by the account_tokens row named by id.
+type AccountTokens struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func NewAccountTokens(secret string) (*AccountTokens, error) {
+	if len(secret) < minAccountTokenSecretLength {
+		return nil, ErrWeakAccountTokenSecret
+	}
+
+	return &AccountTokens{
+		secret: []byte(secret),
+		now:    time.Now,
+	}, nil
+}
+
+func (t *AccountTokens) sign(purpose string, tokenID string, expiresAt time.Time) string {
+	payload := tokenID + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+
+	return payload + "." + t.signature(purpose, payload)
+}
+
+// verify checks the signature and expiry of token and returns the id of its account_tokens row.
+func (t *AccountTokens) verify(purpose string, token string) (string, error) {
+	separator := strings.LastIndexByte(token, '.')
+	if separator < 0 {
+		return "", authmodels.ErrInvalidAccountToken
+	}
+
+	payload, signature := token[:separator], token[separator+1:]
+	if !hmac.Equal([]byte(signature), []byte(t.signature(purpose, payload))) {
+		return "", authmodels.ErrInvalidAccountToken
+	}
+
+	tokenID, expiry, found := strings.Cut(payload, ".")
+	if !found {
+		return "", authmodels.ErrInvalidAccountToken
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || t.now().Unix() >= expiresAt {
+		return "", authmodels.ErrInvalidAccountToken
+	}
+
+	return tokenID, nil
+}
+
+func (t *AccountTokens) signature(purpose string, payload string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(purpose + ":" + payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
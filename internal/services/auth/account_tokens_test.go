@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountTokens(t *testing.T) {
+	tokens, err := NewAccountTokens("0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+
+	now := time.Now()
+	tokens.now = func() time.Time { return now }
+
+	token := tokens.sign(authmodels.TokenPurposeVerifyEmail, "3f1c", now.Add(time.Hour))
+
+	tokenID, err := tokens.verify(authmodels.TokenPurposeVerifyEmail, token)
+	require.NoError(t, err)
+	assert.Equal(t, "3f1c", tokenID)
+
+	_, err = tokens.verify(authmodels.TokenPurposeResetPassword, token)
+	assert.ErrorIs(t, err, authmodels.ErrInvalidAccountToken, "tokens are bound to their purpose")
+
+	_, err = tokens.verify(authmodels.TokenPurposeVerifyEmail, "9999"+token[4:])
+	assert.ErrorIs(t, err, authmodels.ErrInvalidAccountToken, "the token id is signed")
+
+	tokens.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	_, err = tokens.verify(authmodels.TokenPurposeVerifyEmail, token)
+	assert.ErrorIs(t, err, authmodels.ErrInvalidAccountToken, "expired tokens are refused")
+
+	_, err = NewAccountTokens("short")
+	assert.ErrorIs(t, err, ErrWeakAccountTokenSecret)
+}
@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/audit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//go:generate mockgen -source ./auth_service.go -destination mocks/auth_service.mock.go -package mocks
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything after the 72nd byte.
+	maxPasswordLength = 72
+)
+
+// Service provides first-party account signup, login and session management.
+//
+//nolint:interfacebloat
+type Service interface {
+	Signup(ctx context.Context, req authmodels.SignupRequest, client authmodels.ClientInfo) (*authmodels.TokenResponse, error)
+	Login(ctx context.Context, req authmodels.LoginRequest, client authmodels.ClientInfo) (*authmodels.TokenResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*authmodels.TokenResponse, error)
+	Logout(ctx context.Context) error
+	LogoutEverywhere(ctx context.Context) error
+	ListSessions(ctx context.Context) ([]authmodels.Session, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+	SetUserRole(ctx context.Context, userID string, role authmodels.Role) (*models.Users, error)
+	RequestEmailVerification(ctx context.Context) error
+	ConfirmEmail(ctx context.Context, token string) (*models.Users, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+	RequestPhoneOTP(ctx context.Context, phone string) error
+	LoginWithPhone(ctx context.Context, req authmodels.PhoneVerifyRequest, client authmodels.ClientInfo) (*authmodels.TokenResponse, error)
+	LinkPhone(ctx context.Context, req authmodels.PhoneVerifyRequest) (*models.Users, error)
+}
+
+// LoginThrottle locks logins to an account from one client IP for Lockout after MaxAttempts consecutive failed logins
+// from that IP. Other clients can still log in to the account.
+type LoginThrottle struct {
+	MaxAttempts int
+	Lockout     time.Duration
+}
+
+type ServiceImpl struct {
+	repo       persistence.Repository
+	signer     *Signer
+	refreshTTL time.Duration
+	throttle   LoginThrottle
+	emails     AccountEmails
+	phone      PhoneOTPs
+	auditor    audit.Recorder
+	// dummyHash is compared against when the email is unknown so both paths cost the same.
+	dummyHash []byte
+}
+
+//nolint:exhaustivestruct
+var _ Service = &ServiceImpl{}
+
+// NewService returns a Service. Sessions expire after refreshTTL without a refresh.
+func NewService(repo persistence.Repository, signer *Signer, refreshTTL time.Duration, throttle LoginThrottle, emails AccountEmails,
+	phone PhoneOTPs, auditor audit.Recorder,
+) (*ServiceImpl, error) {
+	if emails.Mailer == nil || emails.Tokens == nil {
+		return nil, ErrAccountEmailsNotConfigured
+	}
+
+	if phone.Sender == nil || phone.DefaultCountryCode == "" {
+		return nil, ErrPhoneOTPsNotConfigured
+	}
+
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceImpl{
+		repo:       repo,
+		signer:     signer,
+		refreshTTL: refreshTTL,
+		throttle:   throttle,
+		emails:     emails,
+		phone:      phone,
+		auditor:    auditor,
+		dummyHash:  dummyHash,
+	}, nil
+}
+
+// Signup implements Service.
+func (s *ServiceImpl) Signup(ctx context.Context, req authmodels.SignupRequest, client authmodels.ClientInfo) (*authmodels.TokenResponse, error) {
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		return nil, authmodels.ErrWeakPassword
+	}
+
+	// Admins can only be appointed by other admins.
+	role := req.Role
+	if role == "" {
+		role = authmodels.RoleBuyer
+	}
+
+	if role != authmodels.RoleBuyer && role != authmodels.RoleSeller {
+		return nil, authmodels.ErrInvalidRole
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.CreateUserWithPassword(ctx, models.Users{
+		UserName: strings.TrimSpace(req.UserName),
+		Email:    email,
+		Role:     string(role),
+	}, string(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	// The account is usable without a verified email, so a mail outage must not fail the signup.
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		logger.Error().Err(err).Str("userID", user.User_id).Msg("sending verification email")
+	}
+
+	return s.openSession(ctx, user, client)
+}
+
+// Login implements Service.
+func (s *ServiceImpl) Login(ctx context.Context, req authmodels.LoginRequest, client authmodels.ClientInfo) (*authmodels.TokenResponse, error) {
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, authmodels.ErrInvalidCredentials
+	}
+
+	credentials, err := s.repo.GetCredentialsByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(req.Password))
+
+		return nil, authmodels.ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := s.repo.IsLoginLocked(ctx, credentials.UserID, client.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Accounts created through POST /user have no password and cannot log in. A locked client gets the same answer as a
+	// wrong password, so that the lockout does not reveal that the account exists.
+	if credentials.PasswordHash == nil || locked {
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(req.Password))
+
+		return nil, authmodels.ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(*credentials.PasswordHash), []byte(req.Password)); err != nil {
+		if err := s.repo.RecordFailedLogin(ctx, credentials.UserID, client.IPAddress, s.throttle.MaxAttempts, s.throttle.Lockout); err != nil {
+			return nil, err
+		}
+
+		return nil, authmodels.ErrInvalidCredentials
+	}
+
+	if err := s.repo.ResetFailedLogins(ctx, credentials.UserID, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, credentials.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openSession(ctx, user, client)
+}
+
+// Refresh implements Service. The presented refresh token is rotated and can not be used again.
+func (s *ServiceImpl) Refresh(ctx context.Context, refreshToken string) (*authmodels.TokenResponse, error) {
+	if refreshToken == "" {
+		return nil, authmodels.ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, newHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.RotateRefreshToken(ctx, hashToken(refreshToken), newHash, time.Now().Add(s.refreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	// The role is read again so that role changes are picked up on refresh.
+	user, err := s.repo.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenResponse(session, newRefreshToken, user)
+}
+
+// Logout implements Service by revoking the session of the current access token.
+func (s *ServiceImpl) Logout(ctx context.Context) error {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return authmodels.ErrUnauthenticated
+	}
+
+	if principal.SessionID == "" {
+		return authmodels.ErrSessionNotFound
+	}
+
+	return s.repo.RevokeSession(ctx, principal.UserID, principal.SessionID, "logout")
+}
+
+// LogoutEverywhere implements Service by revoking every session of the current user.
+func (s *ServiceImpl) LogoutEverywhere(ctx context.Context) error {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return authmodels.ErrUnauthenticated
+	}
+
+	return s.repo.RevokeUserSessions(ctx, principal.UserID, "logout_everywhere")
+}
+
+// ListSessions implements Service.
+func (s *ServiceImpl) ListSessions(ctx context.Context) ([]authmodels.Session, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	sessions, err := s.repo.ListActiveSessions(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == principal.SessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession implements Service. Users can only revoke their own sessions.
+func (s *ServiceImpl) RevokeSession(ctx context.Context, sessionID string) error {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return authmodels.ErrUnauthenticated
+	}
+
+	return s.repo.RevokeSession(ctx, principal.UserID, sessionID, "revoked_by_user")
+}
+
+// SetUserRole implements Service. The user's sessions are revoked so the new role applies immediately. The repository
+// audits the change.
+func (s *ServiceImpl) SetUserRole(ctx context.Context, userID string, role authmodels.Role) (*models.Users, error) {
+	if !role.Valid() {
+		return nil, authmodels.ErrInvalidRole
+	}
+
+	user, err := s.repo.UpdateUserRole(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RevokeUserSessions(ctx, userID, "role_changed"); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// IsSessionActive implements Service.
+func (s *ServiceImpl) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s.repo.IsSessionActive(ctx, sessionID)
+}
+
+func (s *ServiceImpl) openSession(ctx context.Context, user *models.Users, client authmodels.ClientInfo) (*authmodels.TokenResponse, error) {
+	refreshToken, refreshHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.CreateSession(ctx, user.User_id, client, refreshHash, time.Now().Add(s.refreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenResponse(session, refreshToken, user)
+}
+
+func (s *ServiceImpl) tokenResponse(session *authmodels.Session, refreshToken string, user *models.Users) (*authmodels.TokenResponse, error) {
+	accessToken, err := s.signer.IssueAccessToken(session.UserID, session.ID, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authmodels.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.signer.TTL().Seconds()),
+		RefreshToken: refreshToken,
+		User:         user,
+	}, nil
+}
+
+func normalizeEmail(email string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" {
+		return "", authmodels.ErrInvalidEmail
+	}
+
+	return strings.ToLower(address.Address), nil
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/sms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeRepo keeps one password account and counts failed logins per account and IP with the rules of RepositoryPg.
+// Methods the tests do not use panic on the nil embedded Repository.
+type fakeRepo struct {
+	persistence.Repository
+
+	user         models.Users
+	passwordHash string
+	failures     map[string]int
+	lockedUntil  map[string]time.Time
+}
+
+func (r *fakeRepo) GetCredentialsByEmail(_ context.Context, email string) (*authmodels.Credentials, error) {
+	if email != r.user.Email {
+		return nil, sql.ErrNoRows
+	}
+
+	return &authmodels.Credentials{UserID: r.user.User_id, PasswordHash: &r.passwordHash}, nil
+}
+
+func (r *fakeRepo) IsLoginLocked(_ context.Context, userID string, ipAddress string) (bool, error) {
+	return r.lockedUntil[userID+"@"+ipAddress].After(time.Now()), nil
+}
+
+func (r *fakeRepo) RecordFailedLogin(_ context.Context, userID string, ipAddress string, maxAttempts int, lockout time.Duration) error {
+	key := userID + "@" + ipAddress
+
+	r.failures[key]++
+	if r.failures[key] >= maxAttempts {
+		r.failures[key] = 0
+		r.lockedUntil[key] = time.Now().Add(lockout)
+	}
+
+	return nil
+}
+
+func (r *fakeRepo) ResetFailedLogins(_ context.Context, userID string, ipAddress string) error {
+	delete(r.failures, userID+"@"+ipAddress)
+	delete(r.lockedUntil, userID+"@"+ipAddress)
+
+	return nil
+}
+
+func (r *fakeRepo) GetUserByID(_ context.Context, userID string) (*models.Users, error) {
+	user := r.user
+
+	return &user, nil
+}
+
+func (r *fakeRepo) CreateSession(_ context.Context, userID string, _ authmodels.ClientInfo, _ string, expiresAt time.Time) (*authmodels.Session, error) {
+	return &authmodels.Session{ID: "session-1", UserID: userID, ExpiresAt: models.NewTime(expiresAt)}, nil
+}
+
+func newTestService(t *testing.T) *ServiceImpl {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &fakeRepo{
+		user:         models.Users{User_id: "user-1", Email: "ada@example.com", Role: string(authmodels.RoleBuyer)},
+		passwordHash: string(hash),
+		failures:     map[string]int{},
+		lockedUntil:  map[string]time.Time{},
+	}
+
+	signer, err := LoadSigner(KeyConfig{HMACSecrets: "test-secret"}, "", "", time.Minute)
+	require.NoError(t, err)
+
+	tokens, err := NewAccountTokens("0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+
+	service, err := NewService(repo, signer, time.Hour, LoginThrottle{MaxAttempts: 3, Lockout: time.Minute},
+		AccountEmails{Mailer: services.LogMailer{}, Tokens: tokens}, PhoneOTPs{Sender: sms.LogSender{}, DefaultCountryCode: "237"}, nil)
+	require.NoError(t, err)
+
+	return service
+}
+
+func TestLogin_LockoutPerClient(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	login := func(password string, ip string) error {
+		_, err := service.Login(ctx, authmodels.LoginRequest{Email: "ada@example.com", Password: password},
+			authmodels.ClientInfo{IPAddress: ip})
+
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, login("guess", "192.0.2.1"), authmodels.ErrInvalidCredentials)
+	}
+
+	assert.ErrorIs(t, login("correct horse", "192.0.2.1"), authmodels.ErrInvalidCredentials,
+		"a locked client gets the same answer as a wrong password")
+	assert.NoError(t, login("correct horse", "198.51.100.7"), "other clients can still log in")
+
+	_, err := service.Login(ctx, authmodels.LoginRequest{Email: "nobody@example.com", Password: "guess"},
+		authmodels.ClientInfo{IPAddress: "192.0.2.1"})
+	assert.ErrorIs(t, err, authmodels.ErrInvalidCredentials)
+}
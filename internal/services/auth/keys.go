@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Signing algorithms accepted for access tokens.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+var (
+	ErrNoKeys         = errors.New("no token verification keys configured")
+	ErrNoSigningKey   = errors.New("no token signing key configured")
+	ErrDuplicateKeyID = errors.New("duplicate key id")
+	ErrUnsupportedKey = errors.New("unsupported key")
+)
+
+// KeyConfig describes where verification and signing keys are loaded from.
+// HMACSecrets and PublicKeyFiles are comma separated lists of `kid:value` pairs; a single entry may omit the kid unless
+// its value contains `:`.
+// Tokens issued by this API are signed with PrivateKeyFile when set, otherwise with the HMAC secret named by SigningKeyID.
+type KeyConfig struct {
+	HMACSecrets    string
+	PublicKeyFiles string
+	JWKSFile       string
+	PrivateKeyFile string
+	SigningKeyID   string
+}
+
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// KeySet holds token verification keys indexed by key id, which allows keys to be rotated.
+type KeySet struct {
+	keys map[string]verificationKey
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: map[string]verificationKey{}}
+}
+
+// LoadKeySet builds a KeySet from every source set in cfg.
+func LoadKeySet(cfg KeyConfig) (*KeySet, error) {
+	keySet := NewKeySet()
+
+	secrets, err := splitKeyList(cfg.HMACSecrets)
+	if err != nil {
+		return nil, fmt.Errorf("JWT HMAC secrets: %w", err)
+	}
+
+	publicKeyFiles, err := splitKeyList(cfg.PublicKeyFiles)
+	if err != nil {
+		return nil, fmt.Errorf("JWT public key files: %w", err)
+	}
+
+	for kid, secret := range secrets {
+		if err := keySet.Add(kid, AlgHS256, []byte(secret)); err != nil {
+			return nil, err
+		}
+	}
+
+	for kid, path := range publicKeyFiles {
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading public key %s: %w", path, err)
+		}
+
+		alg, key, err := parsePublicKeyPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing public key %s: %w", path, err)
+		}
+
+		if err := keySet.Add(kid, alg, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.JWKSFile != "" {
+		jwks, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading jwks file: %w", err)
+		}
+
+		if err := keySet.AddJWKS(jwks); err != nil {
+			return nil, fmt.Errorf("parsing jwks file: %w", err)
+		}
+	}
+
+	if cfg.PrivateKeyFile != "" {
+		alg, privateKey, err := loadPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// The public key may also be listed with the verification keys, but not another key under the same kid.
+		publicKey := publicKeyOf(privateKey)
+		if existing, exists := keySet.keys[cfg.SigningKeyID]; !exists {
+			if err := keySet.Add(cfg.SigningKeyID, alg, publicKey); err != nil {
+				return nil, err
+			}
+		} else if existing.alg != alg || !samePublicKey(existing.key, publicKey) {
+			return nil, fmt.Errorf("%w: %q does not verify the signing key", ErrDuplicateKeyID, cfg.SigningKeyID)
+		}
+	}
+
+	if keySet.Len() == 0 {
+		return nil, ErrNoKeys
+	}
+
+	return keySet, nil
+}
+
+// Add registers a key. The key type must match alg: []byte for HS256, *rsa.PublicKey for RS256
+// and a P-256 *ecdsa.PublicKey for ES256.
+func (k *KeySet) Add(kid string, alg string, key interface{}) error {
+	switch alg {
+	case AlgHS256:
+		if secret, ok := key.([]byte); !ok || len(secret) == 0 {
+			return fmt.Errorf("%w: HS256 requires a non-empty secret", ErrUnsupportedKey)
+		}
+	case AlgRS256:
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("%w: RS256 requires an RSA public key", ErrUnsupportedKey)
+		}
+	case AlgES256:
+		if ecKey, ok := key.(*ecdsa.PublicKey); !ok || ecKey.Curve != elliptic.P256() {
+			return fmt.Errorf("%w: ES256 requires a P-256 public key", ErrUnsupportedKey)
+		}
+	default:
+		return fmt.Errorf("%w: algorithm %q", ErrUnsupportedKey, alg)
+	}
+
+	if _, exists := k.keys[kid]; exists {
+		return fmt.Errorf("%w: %q", ErrDuplicateKeyID, kid)
+	}
+
+	k.keys[kid] = verificationKey{alg: alg, key: key}
+
+	return nil
+}
+
+func (k *KeySet) Len() int {
+	return len(k.keys)
+}
+
+// lookup finds the key for kid. Tokens without a kid are accepted only when the set holds a single key.
+func (k *KeySet) lookup(kid string) (verificationKey, bool) {
+	if key, ok := k.keys[kid]; ok {
+		return key, true
+	}
+
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+
+	return verificationKey{}, false
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// AddJWKS registers the signing keys of a JSON Web Key Set document.
+func (k *KeySet) AddJWKS(document []byte) error {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	if err := json.Unmarshal(document, &jwks); err != nil {
+		return err
+	}
+
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		alg, key, err := jwk.decode()
+		if err != nil {
+			return fmt.Errorf("key %q: %w", jwk.Kid, err)
+		}
+
+		if jwk.Alg != "" && jwk.Alg != alg {
+			return fmt.Errorf("key %q: %w: algorithm %q", jwk.Kid, ErrUnsupportedKey, jwk.Alg)
+		}
+
+		if err := k.Add(jwk.Kid, alg, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (jwk jsonWebKey) decode() (string, interface{}, error) {
+	switch jwk.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return AlgHS256, secret, nil
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return "", nil, err
+		}
+
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return AlgRS256, &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return "", nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, jwk.Crv)
+		}
+
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return "", nil, err
+		}
+
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return "", nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return "", nil, fmt.Errorf("%w: point is not on P-256", ErrUnsupportedKey)
+		}
+
+		return AlgES256, key, nil
+	default:
+		return "", nil, fmt.Errorf("%w: key type %q", ErrUnsupportedKey, jwk.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
+
+func parsePublicKeyPEM(pemBytes []byte) (string, interface{}, error) {
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		return AlgRS256, rsaKey, nil
+	}
+
+	ecKey, err := jwt.ParseECPublicKeyFromPEM(pemBytes)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: expected an RSA or EC public key", ErrUnsupportedKey)
+	}
+
+	return AlgES256, ecKey, nil
+}
+
+func loadPrivateKey(path string) (string, interface{}, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading private key %s: %w", path, err)
+	}
+
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+		return AlgRS256, rsaKey, nil
+	}
+
+	ecKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+	if err != nil {
+		return "", nil, fmt.Errorf("parsing private key %s: %w: expected an RSA or EC private key", path, ErrUnsupportedKey)
+	}
+
+	return AlgES256, ecKey, nil
+}
+
+func publicKeyOf(privateKey interface{}) interface{} {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &key.PublicKey
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey
+	default:
+		return nil
+	}
+}
+
+// samePublicKey reports whether the RSA or ECDSA public keys a and b are equal.
+func samePublicKey(a interface{}, b interface{}) bool {
+	key, ok := a.(interface{ Equal(x crypto.PublicKey) bool })
+
+	return ok && key.Equal(b)
+}
+
+// splitKeyList parses `kid:value,kid2:value2`. Entries are split at their first `:`, so values may contain more. An
+// entry without `:` is registered under the empty kid; a value containing `:` therefore needs a kid. A kid listed twice
+// is an ErrDuplicateKeyID rather than a silent replacement of the earlier key.
+func splitKeyList(list string) (map[string]string, error) {
+	entries := map[string]string{}
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, value := "", entry
+		if parts := strings.SplitN(entry, ":", 2); len(parts) == 2 {
+			kid, value = parts[0], parts[1]
+		}
+
+		kid = strings.TrimSpace(kid)
+		if _, exists := entries[kid]; exists {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateKeyID, kid)
+		}
+
+		entries[kid] = strings.TrimSpace(value)
+	}
+
+	return entries, nil
+}
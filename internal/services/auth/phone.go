@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/sms"
+)
+
+const (
+	otpDigits = 6
+	// E.164 numbers have at most 15 digits, country code included.
+	maxPhoneDigits = 15
+	minPhoneDigits = 8
+	// cameroonCountryCode numbers have 9 digits after the country code.
+	cameroonCountryCode  = "237"
+	cameroonNumberDigits = 9
+	internationalPrefix  = "00"
+)
+
+var ErrPhoneOTPsNotConfigured = errors.New("an sms sender and default country code are required")
+
+// PhoneOTPs configures login with one-time codes sent by SMS.
+type PhoneOTPs struct {
+	Sender sms.Sender
+	// DefaultCountryCode is assumed for numbers given in national format, e.g. 237 for Cameroon.
+	DefaultCountryCode string
+	TTL                time.Duration
+	// MaxAttempts is the number of wrong guesses after which a code stops being valid.
+	MaxAttempts int
+	// MaxPerHour limits how many codes are sent to a number per hour.
+	MaxPerHour int
+}
+
+// NormalizePhone returns phone in E.164 form. Numbers without an international prefix are assumed to belong to
+// defaultCountryCode.
+func NormalizePhone(phone string, defaultCountryCode string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+			return -1
+		default:
+			// Only a leading + is allowed, which is handled below.
+			return 'x'
+		}
+	}, strings.TrimPrefix(strings.TrimSpace(phone), "+"))
+
+	switch {
+	case strings.ContainsRune(digits, 'x') || digits == "":
+		return "", authmodels.ErrInvalidPhone
+	case strings.HasPrefix(strings.TrimSpace(phone), "+"):
+		// Already in international form.
+	case strings.HasPrefix(digits, internationalPrefix):
+		digits = strings.TrimPrefix(digits, internationalPrefix)
+	case defaultCountryCode == cameroonCountryCode && len(digits) == len(cameroonCountryCode)+cameroonNumberDigits &&
+		strings.HasPrefix(digits, cameroonCountryCode):
+		// Cameroonian numbers are often written with the country code but without the +.
+	default:
+		digits = defaultCountryCode + strings.TrimPrefix(digits, "0")
+	}
+
+	if len(digits) < minPhoneDigits || len(digits) > maxPhoneDigits || digits[0] == '0' {
+		return "", authmodels.ErrInvalidPhone
+	}
+
+	if strings.HasPrefix(digits, cameroonCountryCode) && len(digits) != len(cameroonCountryCode)+cameroonNumberDigits {
+		return "", authmodels.ErrInvalidPhone
+	}
+
+	return "+" + digits, nil
+}
+
+// RequestPhoneOTP implements Service by texting a one-time code to phone.
+func (s *ServiceImpl) RequestPhoneOTP(ctx context.Context, phone string) error {
+	phone, err := NormalizePhone(phone, s.phone.DefaultCountryCode)
+	if err != nil {
+		return err
+	}
+
+	sent, err := s.repo.CountPhoneOTPs(ctx, phone, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if sent >= s.phone.MaxPerHour {
+		return authmodels.ErrTooManyRequests
+	}
+
+	code, err := newOTP()
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.CreatePhoneOTP(ctx, phone, hashOTP(phone, code), time.Now().Add(s.phone.TTL)); err != nil {
+		return err
+	}
+
+	return s.phone.Sender.SendSMS(ctx, phone, fmt.Sprintf("Your Sigma Auto code is %s. It expires in %s.", code, s.phone.TTL))
+}
+
+// LoginWithPhone implements Service. An account is created for numbers that do not have one yet.
+func (s *ServiceImpl) LoginWithPhone(ctx context.Context, req authmodels.PhoneVerifyRequest, client authmodels.ClientInfo) (*authmodels.TokenResponse, error) {
+	phone, err := s.consumePhoneOTP(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByPhone(ctx, phone)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = s.repo.CreatePhoneUser(ctx, models.Users{
+			UserName:    strings.TrimSpace(req.UserName),
+			Role:        string(authmodels.RoleBuyer),
+			PhoneNumber: phone,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openSession(ctx, user, client)
+}
+
+// LinkPhone implements Service by attaching a verified phone number to the authenticated user.
+func (s *ServiceImpl) LinkPhone(ctx context.Context, req authmodels.PhoneVerifyRequest) (*models.Users, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	phone, err := s.consumePhoneOTP(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := s.repo.GetUserByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.SetVerifiedPhone(ctx, principal.UserID, phone)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionPhoneLinked,
+		EntityType: auditmodels.EntityUser,
+		EntityID:   user.User_id,
+		Before:     before,
+		After:      user,
+	})
+
+	return user, nil
+}
+
+func (s *ServiceImpl) consumePhoneOTP(ctx context.Context, req authmodels.PhoneVerifyRequest) (string, error) {
+	phone, err := NormalizePhone(req.PhoneNumber, s.phone.DefaultCountryCode)
+	if err != nil {
+		return "", err
+	}
+
+	if len(req.Code) != otpDigits {
+		return "", authmodels.ErrInvalidOTP
+	}
+
+	if err := s.repo.ConsumePhoneOTP(ctx, phone, hashOTP(phone, req.Code), s.phone.MaxAttempts); err != nil {
+		return "", err
+	}
+
+	return phone, nil
+}
+
+func newOTP() (string, error) {
+	//nolint:gomnd
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", otpDigits, n.Int64()), nil
+}
+
+// hashOTP binds a code to the number it was sent to.
+func hashOTP(phone string, code string) string {
+	return hashToken(phone + ":" + code)
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"testing"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{"677 12 34 56", "+237677123456"},
+		{"+237 677-12-34-56", "+237677123456"},
+		{"237677123456", "+237677123456"},
+		{"00237 (677) 123 456", "+237677123456"},
+		{"+33 6 12 34 56 78", "+33612345678"},
+		{"67712345", ""},
+		{"+237 677 12 34 567", ""},
+		{"677+123456", ""},
+		{"call me", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phone, func(t *testing.T) {
+			got, err := NormalizePhone(tt.phone, "237")
+			if tt.want == "" {
+				assert.ErrorIs(t, err, authmodels.ErrInvalidPhone)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Signer issues access tokens that Verifier accepts.
+type Signer struct {
+	method   jwt.SigningMethod
+	kid      string
+	key      interface{}
+	issuer   string
+	audience string
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// LoadSigner returns a Signer for the signing key described by cfg. Issued tokens carry issuer and audience and live for ttl.
+func LoadSigner(cfg KeyConfig, issuer string, audience string, ttl time.Duration) (*Signer, error) {
+	signer := &Signer{
+		kid:      cfg.SigningKeyID,
+		issuer:   issuer,
+		audience: audience,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+
+	if cfg.PrivateKeyFile != "" {
+		alg, privateKey, err := loadPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		signer.method = jwt.GetSigningMethod(alg)
+		signer.key = privateKey
+
+		return signer, nil
+	}
+
+	secrets, err := splitKeyList(cfg.HMACSecrets)
+	if err != nil {
+		return nil, fmt.Errorf("JWT HMAC secrets: %w", err)
+	}
+
+	secret, ok := secrets[cfg.SigningKeyID]
+	if !ok {
+		return nil, ErrNoSigningKey
+	}
+
+	signer.method = jwt.SigningMethodHS256
+	signer.key = []byte(secret)
+
+	return signer, nil
+}
+
+// TTL is the lifetime of issued access tokens.
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// IssueAccessToken signs an access token for userID with role, bound to sessionID.
+func (s *Signer) IssueAccessToken(userID string, sessionID string, role string) (string, error) {
+	jti, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	now := s.now()
+
+	token := jwt.NewWithClaims(s.method, AccessTokenClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Role:      role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   userID,
+			Issuer:    s.issuer,
+			Audience:  s.audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(s.ttl).Unix(),
+		},
+	})
+
+	if s.kid != "" {
+		token.Header["kid"] = s.kid
+	}
+
+	return token.SignedString(s.key)
+}
+
+func randomID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(raw), nil
+}
+
+// newOpaqueToken returns a random url-safe token and the hex SHA-256 hash under which it is stored.
+func newOpaqueToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
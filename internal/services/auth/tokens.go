@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+//go:generate mockgen -source ./tokens.go -destination mocks/tokens.mock.go -package mocks
+
+// clockSkew is tolerated when checking the exp and nbf claims.
+const clockSkew = 30 * time.Second
+
+var ErrInvalidToken = errors.New("invalid access token")
+
+// AccessTokenClaims are the claims carried by an access token.
+type AccessTokenClaims struct {
+	UserID string `json:"user_id"`
+	// SessionID is set on tokens issued by this API and ties the token to a revocable session. It uses a private claim
+	// name because the registered OIDC `sid` claim is set by external issuers for sessions this API does not know.
+	SessionID string `json:"sigma_sid,omitempty"`
+	// Role is one of buyer, seller or admin. Tokens without a role are treated as buyer tokens.
+	Role string `json:"role,omitempty"`
+	jwt.StandardClaims
+}
+
+// TokenVerifier verifies access tokens and returns their claims.
+type TokenVerifier interface {
+	ParseAccessToken(tokenString string) (*AccessTokenClaims, error)
+}
+
+// Verifier checks the signature, lifetime, issuer and audience of access tokens.
+type Verifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+//nolint:exhaustivestruct
+var _ TokenVerifier = &Verifier{}
+
+// NewVerifier returns a Verifier for keys. The iss and aud claims are only enforced when issuer and audience are set.
+func NewVerifier(keys *KeySet, issuer string, audience string) (*Verifier, error) {
+	if keys == nil || keys.Len() == 0 {
+		return nil, ErrNoKeys
+	}
+
+	return &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		now:      time.Now,
+	}, nil
+}
+
+// ParseAccessToken implements TokenVerifier.
+func (v *Verifier) ParseAccessToken(tokenString string) (*AccessTokenClaims, error) {
+	//nolint:exhaustruct
+	claims := AccessTokenClaims{}
+
+	parser := jwt.Parser{
+		ValidMethods:         []string{AlgHS256, AlgRS256, AlgES256},
+		SkipClaimsValidation: true,
+	}
+
+	if _, err := parser.ParseWithClaims(tokenString, &claims, v.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := v.keys.lookup(kid)
+	if !ok {
+		//nolint:goerr113
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	// The algorithm is pinned by the key so that a token cannot pick how it is verified.
+	if token.Method.Alg() != key.alg {
+		//nolint:goerr113
+		return nil, fmt.Errorf("algorithm %s does not match key %q", token.Method.Alg(), kid)
+	}
+
+	return key.key, nil
+}
+
+func (v *Verifier) validateClaims(claims *AccessTokenClaims) error {
+	now := v.now()
+
+	if !claims.VerifyExpiresAt(now.Add(-clockSkew).Unix(), true) {
+		return fmt.Errorf("%w: token is expired or has no exp claim", ErrInvalidToken)
+	}
+
+	if !claims.VerifyNotBefore(now.Add(clockSkew).Unix(), false) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+
+	if v.audience != "" && !claims.VerifyAudience(v.audience, true) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	if claims.UserID == "" {
+		return fmt.Errorf("%w: user_id must be set in JWT claims", ErrInvalidToken)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims AccessTokenClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func validClaims() AccessTokenClaims {
+	return AccessTokenClaims{
+		UserID: "user-1",
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "sigma-auto",
+			Audience:  "sigma-auto-api",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+}
+
+func TestVerifier_ParseAccessToken_HS256(t *testing.T) {
+	keys, err := LoadKeySet(KeyConfig{HMACSecrets: "2024:old-secret,2025:new-secret"})
+	require.NoError(t, err)
+
+	verifier, err := NewVerifier(keys, "sigma-auto", "sigma-auto-api")
+	require.NoError(t, err)
+
+	expired := validClaims()
+	expired.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+
+	notYetValid := validClaims()
+	notYetValid.NotBefore = time.Now().Add(time.Hour).Unix()
+
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = 0
+
+	wrongAudience := validClaims()
+	wrongAudience.Audience = "another-api"
+
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "someone-else"
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid token signed with the current key", signToken(t, jwt.SigningMethodHS256, "2025", []byte("new-secret"), validClaims()), false},
+		{"valid token signed with a rotated key", signToken(t, jwt.SigningMethodHS256, "2024", []byte("old-secret"), validClaims()), false},
+		{"forged signature", signToken(t, jwt.SigningMethodHS256, "2025", []byte("guessed"), validClaims()), true},
+		{"unknown kid", signToken(t, jwt.SigningMethodHS256, "2023", []byte("new-secret"), validClaims()), true},
+		{"ambiguous missing kid", signToken(t, jwt.SigningMethodHS256, "", []byte("new-secret"), validClaims()), true},
+		{"expired", signToken(t, jwt.SigningMethodHS256, "2025", []byte("new-secret"), expired), true},
+		{"not yet valid", signToken(t, jwt.SigningMethodHS256, "2025", []byte("new-secret"), notYetValid), true},
+		{"missing exp", signToken(t, jwt.SigningMethodHS256, "2025", []byte("new-secret"), noExpiry), true},
+		{"wrong audience", signToken(t, jwt.SigningMethodHS256, "2025", []byte("new-secret"), wrongAudience), true},
+		{"wrong issuer", signToken(t, jwt.SigningMethodHS256, "2025", []byte("new-secret"), wrongIssuer), true},
+		{"unsigned", signToken(t, jwt.SigningMethodNone, "2025", jwt.UnsafeAllowNoneSignatureType, validClaims()), true},
+		{"garbage", "not-a-jwt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.ParseAccessToken(tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims.UserID)
+		})
+	}
+}
+
+func TestVerifier_ParseAccessToken_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode([]byte{1, 0, 1})},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(ecKey.X.Bytes()), "y": encode(ecKey.Y.Bytes())},
+		},
+	})
+	require.NoError(t, err)
+
+	keys := NewKeySet()
+	require.NoError(t, keys.AddJWKS(jwks))
+
+	verifier, err := NewVerifier(keys, "", "")
+	require.NoError(t, err)
+
+	_, err = verifier.ParseAccessToken(signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims()))
+	assert.NoError(t, err)
+
+	_, err = verifier.ParseAccessToken(signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, validClaims()))
+	assert.NoError(t, err)
+
+	// A token may not select an algorithm other than the one bound to its key.
+	_, err = verifier.ParseAccessToken(signToken(t, jwt.SigningMethodES256, "rsa-1", ecKey, validClaims()))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLoadKeySet_SigningKeyID(t *testing.T) {
+	writePEM := func(blockType string, der []byte) string {
+		path := filepath.Join(t.TempDir(), "key.pem")
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+
+		return path
+	}
+
+	newKey := func() (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		private, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+
+		public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+
+		return writePEM("EC PRIVATE KEY", private), writePEM("PUBLIC KEY", public)
+	}
+
+	privateFile, publicFile := newKey()
+	_, otherPublicFile := newKey()
+
+	keys, err := LoadKeySet(KeyConfig{PublicKeyFiles: "current:" + publicFile, PrivateKeyFile: privateFile, SigningKeyID: "current"})
+	require.NoError(t, err, "the public half of the signing key may be listed")
+	assert.Equal(t, 1, keys.Len())
+
+	_, err = LoadKeySet(KeyConfig{PublicKeyFiles: "current:" + otherPublicFile, PrivateKeyFile: privateFile, SigningKeyID: "current"})
+	assert.ErrorIs(t, err, ErrDuplicateKeyID)
+
+	_, err = LoadKeySet(KeyConfig{HMACSecrets: "current:secret", PrivateKeyFile: privateFile, SigningKeyID: "current"})
+	assert.ErrorIs(t, err, ErrDuplicateKeyID)
+}
+
+func TestSplitKeyList(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want map[string]string
+	}{
+		{"single value", "secret", map[string]string{"": "secret"}},
+		{"pairs", "old:first, new:second", map[string]string{"old": "first", "new": "second"}},
+		{"value with colons", "current:a:b:c", map[string]string{"current": "a:b:c"}},
+		{"windows path", "current:C:\\keys\\public.pem", map[string]string{"current": "C:\\keys\\public.pem"}},
+		{"empty entries", " ,current:secret,", map[string]string{"current": "secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitKeyList(tt.list)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitKeyList_DuplicateKeyID(t *testing.T) {
+	for _, list := range []string{"old:first,old:second", "first, second", "current:a, current :b"} {
+		_, err := splitKeyList(list)
+		assert.ErrorIs(t, err, ErrDuplicateKeyID, list)
+	}
+
+	_, err := LoadKeySet(KeyConfig{HMACSecrets: "current:first,current:second"})
+	assert.ErrorIs(t, err, ErrDuplicateKeyID, "a repeated kid fails at startup")
+}
@@ -2,11 +2,24 @@ package cars
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
 	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	paymentModels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/payments"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
 	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/audit"
 	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/payments"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/vehicles"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/vin"
+	"github.com/rs/zerolog"
 )
 
 //go:generate mockgen -source ./events.go -destination mocks/events.mock.go -package mocks
@@ -15,22 +28,45 @@ import (
 //
 //nolint:interfacebloat
 type Service interface {
-	GetAllCars(ctx context.Context, cityID string, category string, startKey uint, count uint) ([]models.Cars, error)
-	// UpdateCar(ctx context.Context, updatePayLoad models.Cars, carID string) (*models.Cars, error)
+	GetAllCars(ctx context.Context, filter models.CarFilter) (models.Page[models.Cars], error)
+	SearchCars(ctx context.Context, query string, filter models.CarFilter) (models.Page[models.CarSearchResult], error)
+	ListCarBids(ctx context.Context, carID string, page models.PageRequest) (models.Page[models.Bids], error)
+	ListUsers(ctx context.Context, role string, page models.PageRequest) (models.Page[models.Users], error)
+	UpdateCar(ctx context.Context, updatePayLoad models.Cars, carID string, version int) (*models.Cars, error)
+	PatchCar(ctx context.Context, carID string, patch []byte, version int) (*models.Cars, error)
 	RegisterCar(ctx context.Context, carPayload models.Cars) (*models.Cars, error)
 	GetCarsByID(ctx context.Context, carID string) (*models.Cars, error)
 	PlaceBid(ctx context.Context, bid models.Bids) (*models.Bids, error)
 	GetBidByID(ctx context.Context, bidID string) (*models.Bids, error)
 	GetUserByID(ctx context.Context, userID string) (*models.Users, error)
 	CreateUser(ctx context.Context, user models.Users) (*models.Users, error)
+	PayBid(ctx context.Context, bidID string) (*paymentModels.ResponseBody, error)
+	WithdrawCar(ctx context.Context, carID string, reason string) (*models.Cars, error)
+	RestoreCar(ctx context.Context, carID string) (*models.Cars, error)
+	UploadPhotos(ctx context.Context, carID string, photos []io.Reader, primary bool) ([]models.CarPhoto, error)
+	ListPhotos(ctx context.Context, carID string) ([]models.CarPhoto, error)
+	ReorderPhotos(ctx context.Context, carID string, photoIDs []string) ([]models.CarPhoto, error)
+	SetPrimaryPhoto(ctx context.Context, carID string, photoID string) (*models.CarPhoto, error)
+	DeletePhoto(ctx context.Context, carID string, photoID string) error
+	OpenPhoto(ctx context.Context, key string, expires string, signature string) ([]byte, string, error)
+	DecodeVIN(ctx context.Context, number string) (models.VINInfo, error)
+	WatchCar(ctx context.Context, userID string, carID string) (*models.WatchedCar, error)
+	UnwatchCar(ctx context.Context, userID string, carID string) error
+	ListWatchlist(ctx context.Context, userID string) ([]models.WatchedCar, error)
 }
 
 type ServiceImpl struct {
-	repo       persistence.Repository
-	pgGateway  payments.PaymentService
-	webHookKey string
+	repo        persistence.Repository
+	pgGateway   payments.PaymentService
+	webHookKey  string
+	auditor     audit.Recorder
+	withdrawals Withdrawals
+	photos      Photos
+	watchlist   Watchlist
 }
 
+var logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+
 var (
 	ErrUnepectedSigningAlg = fmt.Errorf("unexpected signing algorithm")
 )
@@ -38,33 +74,281 @@ var (
 //nolint:exhaustivestruct
 var _ Service = &ServiceImpl{}
 
-func NewService(repo persistence.Repository, pgGateway payments.PaymentService, webHookAppKey string) (*ServiceImpl, error) {
+func NewService(repo persistence.Repository, pgGateway payments.PaymentService, webHookAppKey string, auditor audit.Recorder, withdrawals Withdrawals, photos Photos, watchlist Watchlist) (*ServiceImpl, error) {
+	if withdrawals.Mailer == nil || withdrawals.SMS == nil {
+		return nil, ErrWithdrawalsNotConfigured
+	}
+
+	if photos.Storage == nil {
+		return nil, ErrPhotosNotConfigured
+	}
+
+	if watchlist.Mailer == nil || watchlist.SMS == nil {
+		return nil, ErrWatchlistNotConfigured
+	}
+
 	return &ServiceImpl{
-		repo:       repo,
-		pgGateway:  pgGateway,
-		webHookKey: webHookAppKey,
+		repo:        repo,
+		pgGateway:   pgGateway,
+		webHookKey:  webHookAppKey,
+		auditor:     auditor,
+		withdrawals: withdrawals,
+		photos:      photos,
+		watchlist:   watchlist,
 	}, nil
 }
 
-// GetAllEvents implements Service.
-func (s *ServiceImpl) GetAllCars(ctx context.Context, cityID string, categoryID string, startKey uint, count uint) ([]models.Cars, error) {
+// GetAllCars implements Service. Auctions that are ending soonest are open auctions unless statuses are given.
+func (s *ServiceImpl) GetAllCars(ctx context.Context, filter models.CarFilter) (models.Page[models.Cars], error) {
+	if err := filter.Validate(); err != nil {
+		return models.Page[models.Cars]{}, err
+	}
+
+	if filter.VIN != "" {
+		number, err := vin.Parse(filter.VIN)
+		if err != nil {
+			return models.Page[models.Cars]{}, err
+		}
+
+		filter.VIN = number
+	}
 
-	cars, err := s.repo.GetAllCars(ctx, cityID, categoryID, startKey, count)
+	if filter.Sort == models.SortEndingSoonest && len(filter.Statuses) == 0 {
+		filter.Statuses = []models.AuctionStatus{models.AuctionOpen}
+	}
+
+	cars, err := s.repo.GetAllCars(ctx, filter)
 	if err != nil {
+		return models.Page[models.Cars]{}, err
+	}
 
-		return nil, err
+	listed := make([]*models.Cars, len(cars.Items))
+	for i := range cars.Items {
+		listed[i] = &cars.Items[i]
 	}
 
+	s.signThumbnails(ctx, listed)
+
 	return cars, nil
 }
 
-// func (s *ServiceImpl) UpdateCar(ctx context.Context, updatePayLoad models.Cars, carID string) (*models.Cars, error) {
-// }
+// UpdateCar implements Service by replacing the car. Only the seller of the car or an admin may update it.
+// When version is not zero the update is refused with models.ErrVersionConflict unless the car is still at version.
+func (s *ServiceImpl) UpdateCar(ctx context.Context, updatePayLoad models.Cars, carID string, version int) (*models.Cars, error) {
+	car, err := s.carToManage(ctx, carID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ownership and server-set fields can not be changed through an update.
+	updatePayLoad.ID = car.ID
+	updatePayLoad.SellerID = car.SellerID
+	updatePayLoad.DatePosted = car.DatePosted
+	updatePayLoad.Version = car.Version
+	updatePayLoad.NumberOfBids = car.NumberOfBids
+	updatePayLoad.UpdatedAt = car.UpdatedAt
+	updatePayLoad.WithdrawnAt = car.WithdrawnAt
+	updatePayLoad.WithdrawnBy = car.WithdrawnBy
+	updatePayLoad.WithdrawalReason = car.WithdrawalReason
+
+	return s.saveCar(ctx, car, updatePayLoad)
+}
+
+// PatchCar implements Service by applying an RFC 7396 JSON merge patch to the car. Only the seller of the car or an
+// admin may patch it. When version is not zero the patch is refused with models.ErrVersionConflict unless the car is
+// still at version.
+func (s *ServiceImpl) PatchCar(ctx context.Context, carID string, patch []byte, version int) (*models.Cars, error) {
+	car, err := s.carToManage(ctx, carID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := json.Marshal(car)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := applyMergePatch(doc, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := models.Cars{}
+	if err := json.Unmarshal(patched, &updated); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidPatch, err)
+	}
+
+	// The JSON encoding of times drops their fractional seconds, so the fields held by the server are checked against
+	// the members the patch changes, and copied from car rather than read back from the patched document.
+	changed, err := changedMembers(doc, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	immutable := []string{
+		"id", "seller_id", "date_posted", "version", "number_of_bids", "updated_at", "make_id", "model_id", "trim_id",
+		"withdrawn_at", "withdrawn_by", "withdrawal_reason",
+	}
+
+	for _, name := range immutable {
+		if changed[name] {
+			return nil, fmt.Errorf("%w: %s", models.ErrImmutableField, name)
+		}
+	}
+
+	updated.ID, updated.SellerID, updated.DatePosted = car.ID, car.SellerID, car.DatePosted
+	updated.Version, updated.NumberOfBids, updated.UpdatedAt = car.Version, car.NumberOfBids, car.UpdatedAt
+	updated.MakeID, updated.ModelID, updated.TrimID = car.MakeID, car.ModelID, car.TrimID
+	updated.WithdrawnAt, updated.WithdrawnBy, updated.WithdrawalReason = car.WithdrawnAt, car.WithdrawnBy, car.WithdrawalReason
+
+	if !changed["bid_expiration_time"] {
+		updated.BidExpirationTime = car.BidExpirationTime
+	}
+
+	return s.saveCar(ctx, car, updated)
+}
+
+// carToManage returns the car carID if the caller may change it and it is at version, or at any version if version is
+// zero.
+func (s *ServiceImpl) carToManage(ctx context.Context, carID string, version int) (*models.Cars, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	car, err := s.repo.GetCarsByID(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canManageCar(principal, car) {
+		return nil, models.ErrForbidden
+	}
+
+	if !car.WithdrawnAt.IsZero() {
+		return nil, models.ErrCarWithdrawn
+	}
+
+	if version != 0 && version != car.Version {
+		return nil, models.ErrVersionConflict
+	}
+
+	return car, nil
+}
+
+// saveCar stores updated in place of car. Once a car has bids its price-affecting fields are locked and its deadline
+// can only be extended.
+func (s *ServiceImpl) saveCar(ctx context.Context, car *models.Cars, updated models.Cars) (*models.Cars, error) {
+	if err := updated.Normalize(); err != nil {
+		return nil, err
+	}
+
+	if err := applyVIN(&updated); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCatalog(ctx, updated); err != nil {
+		return nil, err
+	}
+
+	// The taxonomy may have changed since the car was resolved, so resolving it again could rewrite fields the seller
+	// did not touch, and that are locked once bidding has started.
+	if updated.CarName == car.CarName && updated.Make == car.Make && updated.CarModel == car.CarModel &&
+		updated.Trim == car.Trim {
+		updated.MakeID, updated.ModelID, updated.TrimID = car.MakeID, car.ModelID, car.TrimID
+	} else if err := vehicles.Resolve(ctx, s.repo, &updated); err != nil {
+		return nil, err
+	}
+
+	deadlineChanged := !updated.BidExpirationTime.Equal(car.BidExpirationTime.Time)
+	if deadlineChanged && (updated.BidExpirationTime.IsZero() || !updated.BidExpirationTime.After(time.Now())) {
+		return nil, models.ErrInvalidBidExpiration
+	}
+
+	field, lockedChanged := car.LockedFieldChanged(updated)
+
+	if lockedChanged || deadlineChanged {
+		bids, err := s.repo.CountBids(ctx, car.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if bids > 0 && lockedChanged {
+			return nil, fmt.Errorf("%w: %s", models.ErrFieldLocked, field)
+		}
+
+		if bids > 0 && updated.BidExpirationTime.Before(car.BidExpirationTime.Time) {
+			return nil, models.ErrBidExpirationLocked
+		}
+	}
+
+	// The version read with the car guards against bids and updates that arrived since the checks above.
+	saved, err := s.repo.UpdateCar(ctx, updated, car.ID, car.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if saved.BidExpirationTime.After(car.BidExpirationTime.Time) {
+		go s.notifyWatchersOf(saved, car.SellerID, "A car you watch has been extended",
+			fmt.Sprintf("The auction of %s has been extended until %s.", saved.CarName,
+				saved.BidExpirationTime.In(models.DefaultLocation).Format(deadlineLayout)))
+	}
+
+	return saved, nil
+}
+
+// RegisterCar implements Service. The car is listed for the authenticated seller regardless of the seller_id in the payload.
+// Only sellers whose identity and vehicle ownership have been verified, and admins, may publish listings.
 func (s *ServiceImpl) RegisterCar(ctx context.Context, carPayload models.Cars) (*models.Cars, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	seller, err := s.verifiedUser(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !principal.IsAdmin() && sellermodels.Status(seller.SellerStatus) != sellermodels.StatusVerified {
+		return nil, sellermodels.ErrSellerNotVerified
+	}
+
+	carPayload.SellerID = principal.UserID
+
+	if err := carPayload.Normalize(); err != nil {
+		return nil, err
+	}
+
+	if err := applyVIN(&carPayload); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCatalog(ctx, carPayload); err != nil {
+		return nil, err
+	}
+
+	if err := vehicles.Resolve(ctx, s.repo, &carPayload); err != nil {
+		return nil, err
+	}
+
+	if carPayload.BidExpirationTime.IsZero() || !carPayload.BidExpirationTime.After(time.Now()) {
+		return nil, models.ErrInvalidBidExpiration
+	}
+
+	// date_posted is always set by the database.
+	carPayload.DatePosted = models.Time{}
+
 	newRegisteredCar, err := s.repo.RegisterCar(ctx, carPayload)
 	if err != nil {
 		return nil, err
 	}
+
+	// Users are alerted of the listing by the search alerts sweep; a failure to queue them does not unlist the car.
+	if _, err := s.repo.QueueSearchAlerts(ctx, newRegisteredCar.ID); err != nil {
+		logger.Error().Err(err).Str("carID", newRegisteredCar.ID).Msg("queueing search alerts")
+	}
+
 	return newRegisteredCar, nil
 }
 
@@ -73,39 +357,222 @@ func (s *ServiceImpl) GetCarsByID(ctx context.Context, carID string) (*models.Ca
 	if err != nil {
 		return nil, err
 	}
+
+	car.Photos, err = s.repo.ListCarPhotos(ctx, car.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.signPhotos(ctx, car.Photos); err != nil {
+		return nil, err
+	}
+
+	s.signThumbnails(ctx, []*models.Cars{car})
+
 	return car, nil
 }
 
+// PlaceBid implements Service. The bid is attributed to the authenticated user; identity fields in the payload are ignored.
 func (s *ServiceImpl) PlaceBid(ctx context.Context, bid models.Bids) (*models.Bids, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	car, err := s.repo.GetCarsByID(ctx, bid.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	if car.SellerID == principal.UserID {
+		return nil, models.ErrOwnCarBid
+	}
+
+	if !car.WithdrawnAt.IsZero() {
+		return nil, models.ErrCarWithdrawn
+	}
+
+	if !car.BidExpirationTime.IsZero() && !car.BidExpirationTime.After(time.Now()) {
+		return nil, models.ErrAuctionClosed
+	}
+
+	bidder, err := s.verifiedUser(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	bid.UserID = bidder.User_id
+	bid.Email = bidder.Email
+	bid.UserName = bidder.UserName
+
 	bids, err := s.repo.PlaceBid(ctx, bid)
 	if err != nil {
 		return nil, err
 	}
+
+	go s.notifyWatchersOf(car, bids.UserID, "A car you watch has a new bid",
+		fmt.Sprintf("%s has a new bid of %s XAF.", car.CarName, bids.Amount))
+
 	return bids, nil
 }
 
+// GetBidByID implements Service. The bidder's identity is only shown to the bidder and to admins.
 func (s *ServiceImpl) GetBidByID(ctx context.Context, bidID string) (*models.Bids, error) {
 	bid, err := s.repo.GetBidByID(ctx, bidID)
 	if err != nil {
 		return nil, err
 	}
-	return bid, nil
+
+	if principal, ok := authmodels.PrincipalFromContext(ctx); ok && (principal.IsAdmin() || principal.UserID == bid.UserID) {
+		return bid, nil
+	}
+
+	public := bid.Public()
+
+	return &public, nil
+}
+
+// ListCarBids implements Service. Bidders' identities are only shown to themselves and to admins.
+func (s *ServiceImpl) ListCarBids(ctx context.Context, carID string, page models.PageRequest) (models.Page[models.Bids], error) {
+	if _, err := s.repo.GetCarsByID(ctx, carID); err != nil {
+		return models.Page[models.Bids]{}, err
+	}
+
+	bids, err := s.repo.ListCarBids(ctx, carID, page)
+	if err != nil {
+		return models.Page[models.Bids]{}, err
+	}
+
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+
+	for i, bid := range bids.Items {
+		if !ok || (!principal.IsAdmin() && principal.UserID != bid.UserID) {
+			bids.Items[i] = bid.Public()
+		}
+	}
+
+	return bids, nil
 }
 
-// CreateUser(ctx context.Context, user models.Sellers) (*models.Sellers, error)
+// GetUserByID implements Service. Contact details are only shown to the user themselves and to admins.
 func (s *ServiceImpl) GetUserByID(ctx context.Context, userID string) (*models.Users, error) {
 	user, err := s.repo.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
+
+	if principal, ok := authmodels.PrincipalFromContext(ctx); ok && (principal.IsAdmin() || principal.UserID == user.User_id) {
+		return user, nil
+	}
+
+	public := user.Public()
+
+	return &public, nil
+}
+
+// ListUsers implements Service.
+func (s *ServiceImpl) ListUsers(ctx context.Context, role string, page models.PageRequest) (models.Page[models.Users], error) {
+	if role != "" && !authmodels.Role(role).Valid() {
+		return models.Page[models.Users]{}, authmodels.ErrInvalidRole
+	}
+
+	return s.repo.ListUsers(ctx, role, page)
 }
 
+// CreateUser implements Service. The profile is created for the authenticated user; the user_id in the payload is ignored.
 func (s *ServiceImpl) CreateUser(ctx context.Context, user models.Users) (*models.Users, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	user.User_id = principal.UserID
+
 	newUser, err := s.repo.CreateUser(ctx, user)
 	if err != nil {
 		return nil, err
 	}
+
 	return newUser, nil
 }
 
+// PayBid implements Service by requesting a CamPay mobile money collection of the bid amount. The payer is always the
+// bidder's verified phone number.
+func (s *ServiceImpl) PayBid(ctx context.Context, bidID string) (*paymentModels.ResponseBody, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	bid, err := s.repo.GetBidByID(ctx, bidID)
+	if err != nil {
+		return nil, err
+	}
+
+	if bid.UserID != principal.UserID {
+		return nil, models.ErrForbidden
+	}
+
+	car, err := s.repo.GetCarsByID(ctx, bid.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !car.WithdrawnAt.IsZero() {
+		return nil, models.ErrCarWithdrawn
+	}
+
+	payer, err := s.repo.GetUserByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payer.PhoneNumber == "" || payer.PhoneVerifiedAt.IsZero() {
+		return nil, models.ErrPhoneNotVerified
+	}
+
+	req := paymentModels.RequestBody{
+		Amount: bid.Amount,
+		// CamPay expects the number with its country code but without the +.
+		From:        strings.TrimPrefix(payer.PhoneNumber, "+"),
+		Description: "Sigma Auto bid on car " + bid.CarID,
+		ExternalRef: bid.BidID,
+	}
+
+	payment, err := s.pgGateway.InitiatePayments(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionPaymentRequested,
+		EntityType: auditmodels.EntityBid,
+		EntityID:   bid.BidID,
+		After: struct {
+			paymentModels.RequestBody
+			paymentModels.ResponseBody
+		}{req, *payment},
+	})
+
+	return payment, nil
+}
+
+// verifiedUser returns the user userID, or models.ErrAccountNotVerified if they have confirmed neither their email
+// address nor a phone number.
+func (s *ServiceImpl) verifiedUser(ctx context.Context, userID string) (*models.Users, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt.IsZero() && user.PhoneVerifiedAt.IsZero() {
+		return nil, models.ErrAccountNotVerified
+	}
+
+	return user, nil
+}
+
+// canManageCar reports whether principal may change car.
+func canManageCar(principal *authmodels.Principal, car *models.Cars) bool {
+	return principal.IsAdmin() || car.SellerID == principal.UserID
+}
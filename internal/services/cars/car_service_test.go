@@ -0,0 +1,432 @@
+package cars
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepo keeps cars in memory. Methods the tests do not use panic on the nil embedded Repository.
+type fakeRepo struct {
+	persistence.Repository
+
+	mu       sync.Mutex
+	cars     map[string]*models.Cars
+	bids     map[string]int
+	users    map[string]*models.Users
+	bidders  map[string][]models.Users
+	watchers map[string][]models.Users
+	ending   []models.Watch
+	sold     []models.Watch
+	vehicles []models.Vehicle
+	saved    []models.Cars
+}
+
+func newFakeRepo(cars ...*models.Cars) *fakeRepo {
+	repo := &fakeRepo{cars: map[string]*models.Cars{}, bids: map[string]int{}, users: map[string]*models.Users{},
+		bidders: map[string][]models.Users{}, watchers: map[string][]models.Users{}}
+	for _, car := range cars {
+		repo.cars[car.ID] = car
+	}
+
+	return repo
+}
+
+func (r *fakeRepo) GetCarsByID(_ context.Context, carID string) (*models.Cars, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car, ok := r.cars[carID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *car
+
+	return &found, nil
+}
+
+func (r *fakeRepo) GetUserByID(_ context.Context, userID string) (*models.Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *user
+
+	return &found, nil
+}
+
+func (r *fakeRepo) RegisterCar(_ context.Context, car models.Cars) (*models.Cars, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car.ID, car.DatePosted, car.Version = fmt.Sprintf("car-%d", len(r.cars)+1), models.NewTime(time.Now()), 1
+	r.cars[car.ID] = &car
+
+	registered := car
+
+	return &registered, nil
+}
+
+func (r *fakeRepo) QueueSearchAlerts(context.Context, string) (int, error) {
+	return 0, nil
+}
+
+func (r *fakeRepo) CountBids(_ context.Context, carID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.bids[carID], nil
+}
+
+func (r *fakeRepo) ListVehicles(_ context.Context, kind models.VehicleKind, parentID string) ([]models.Vehicle, error) {
+	var listed []models.Vehicle
+
+	for _, vehicle := range r.vehicles {
+		if vehicle.Kind == kind && vehicle.ParentID == parentID {
+			listed = append(listed, vehicle)
+		}
+	}
+
+	return listed, nil
+}
+
+// UpdateCar records the car it is given and, like RepositoryPg, keeps the fields held by the server.
+func (r *fakeRepo) UpdateCar(_ context.Context, car models.Cars, carID string, version int) (*models.Cars, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.saved = append(r.saved, car)
+
+	current := r.cars[carID]
+	if current.Version != version {
+		return nil, models.ErrVersionConflict
+	}
+
+	car.ID, car.SellerID, car.DatePosted, car.NumberOfBids = current.ID, current.SellerID, current.DatePosted, current.NumberOfBids
+	car.Version, car.UpdatedAt = current.Version+1, models.NewTime(time.Now())
+	r.cars[carID] = &car
+
+	updated := car
+
+	return &updated, nil
+}
+
+func (r *fakeRepo) WithdrawCar(_ context.Context, carID string, actorID string, reason string) (*models.Cars, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car := r.cars[carID]
+	car.WithdrawnAt, car.WithdrawnBy, car.WithdrawalReason = models.NewTime(time.Now()), actorID, reason
+	car.Version++
+
+	withdrawn := *car
+
+	return &withdrawn, nil
+}
+
+func (r *fakeRepo) RestoreCar(_ context.Context, carID string) (*models.Cars, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car := r.cars[carID]
+	if car.WithdrawnAt.IsZero() {
+		return nil, models.ErrCarNotWithdrawn
+	}
+
+	car.WithdrawnAt, car.WithdrawnBy, car.WithdrawalReason = models.Time{}, "", ""
+	car.Version++
+
+	restored := *car
+
+	return &restored, nil
+}
+
+func (r *fakeRepo) ListBidders(_ context.Context, carID string) ([]models.Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.bidders[carID], nil
+}
+
+func (r *fakeRepo) ListWatchers(_ context.Context, carID string) ([]models.Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.watchers[carID], nil
+}
+
+func (r *fakeRepo) GetWatchedCar(_ context.Context, _ string, carID string) (*models.WatchedCar, error) {
+	car, err := r.GetCarsByID(context.Background(), carID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WatchedCar{
+		Cars:         *car,
+		CurrentPrice: car.BidingPrice,
+		SecondsLeft:  int64(time.Until(car.BidExpirationTime.Time).Seconds()),
+	}, nil
+}
+
+// ClaimEndingWatches and ClaimSoldWatches return the watches queued in ending and sold once.
+func (r *fakeRepo) ClaimEndingWatches(context.Context, time.Duration) ([]models.Watch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := r.ending
+	r.ending = nil
+
+	return claimed, nil
+}
+
+func (r *fakeRepo) ClaimSoldWatches(context.Context) ([]models.Watch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := r.sold
+	r.sold = nil
+
+	return claimed, nil
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) Record(context.Context, auditmodels.Change) {}
+
+// fakeMailer and fakeSMS record the messages they are asked to send.
+type fakeMailer struct {
+	mu     sync.Mutex
+	emails []services.Email
+}
+
+// sent returns the recipients of the emails sent so far.
+func (m *fakeMailer) sent() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var to []string
+	for _, email := range m.emails {
+		to = append(to, email.To...)
+	}
+
+	return to
+}
+
+func (m *fakeMailer) SendEmail(_ context.Context, email services.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.emails = append(m.emails, email)
+
+	return nil
+}
+
+type fakeSMS struct {
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+// sent returns the messages sent to the number to so far.
+func (s *fakeSMS) sent(to string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.messages[to]...)
+}
+
+func (s *fakeSMS) SendSMS(_ context.Context, to string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.messages == nil {
+		s.messages = map[string][]string{}
+	}
+
+	s.messages[to] = append(s.messages[to], message)
+
+	return nil
+}
+
+func newTestService(t *testing.T, repo persistence.Repository) (*ServiceImpl, *fakeMailer, *fakeSMS) {
+	t.Helper()
+
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost/photos", "0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+
+	mailer, sender := &fakeMailer{}, &fakeSMS{}
+
+	service, err := NewService(repo, nil, "", nopRecorder{},
+		Withdrawals{Mailer: mailer, SMS: sender, Cutoff: time.Hour},
+		Photos{Storage: store, URLTTL: time.Hour},
+		Watchlist{Mailer: mailer, SMS: sender, EndingWithin: time.Hour})
+	require.NoError(t, err)
+
+	return service, mailer, sender
+}
+
+func asUser(userID string, role authmodels.Role) context.Context {
+	return authmodels.WithPrincipal(context.Background(), &authmodels.Principal{UserID: userID, Role: role})
+}
+
+func TestRegisterCar_SellerStatus(t *testing.T) {
+	tests := []struct {
+		status  sellermodels.Status
+		role    authmodels.Role
+		wantErr error
+	}{
+		{sellermodels.StatusUnverified, authmodels.RoleBuyer, sellermodels.ErrSellerNotVerified},
+		{sellermodels.StatusPending, authmodels.RoleBuyer, sellermodels.ErrSellerNotVerified},
+		{sellermodels.StatusRejected, authmodels.RoleBuyer, sellermodels.ErrSellerNotVerified},
+		{sellermodels.StatusVerified, authmodels.RoleSeller, nil},
+		{sellermodels.StatusUnverified, authmodels.RoleAdmin, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status)+" "+string(tt.role), func(t *testing.T) {
+			repo := newFakeRepo()
+			repo.users["user-1"] = &models.Users{
+				User_id:         "user-1",
+				EmailVerifiedAt: models.NewTime(time.Now()),
+				SellerStatus:    string(tt.status),
+			}
+			service, _, _ := newTestService(t, repo)
+
+			car, err := service.RegisterCar(asUser("user-1", tt.role), models.Cars{
+				CarName:           "Toyota Corolla",
+				BidExpirationTime: models.NewTime(time.Now().Add(72 * time.Hour)),
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, repo.cars)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", car.SellerID)
+		})
+	}
+}
+
+func TestPatchCar(t *testing.T) {
+	// The database keeps microseconds, which the JSON encoding of times drops.
+	now := time.Now().Truncate(time.Second).Add(123456 * time.Microsecond)
+	listed := func() *models.Cars {
+		return &models.Cars{
+			ID:                "car-1",
+			SellerID:          "seller-1",
+			CarName:           "Toyota Corolla",
+			DatePosted:        models.NewTime(now.Add(-48 * time.Hour)),
+			BidingPrice:       1000000,
+			BidExpirationTime: models.NewTime(now.Add(72 * time.Hour)),
+			Description:       "Clean",
+			UpdatedAt:         models.NewTime(now.Add(-time.Hour)),
+			Version:           3,
+			NumberOfBids:      2,
+		}
+	}
+
+	read, err := json.Marshal(listed())
+	require.NoError(t, err)
+
+	resent := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(read, &resent))
+	resent["description"] = "New tyres"
+	resentPatch, err := json.Marshal(resent)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr error
+	}{
+		{"changed field", `{"description":"New tyres"}`, nil},
+		{"car as read with a change", string(resentPatch), nil},
+		{"date posted", `{"date_posted":"2020-01-01T00:00:00Z"}`, models.ErrImmutableField},
+		{"updated at", `{"updated_at":null}`, models.ErrImmutableField},
+		{"earlier deadline", `{"bid_expiration_time":"` + now.Add(24*time.Hour).UTC().Format(time.RFC3339) + `"}`,
+			models.ErrBidExpirationLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			car := listed()
+			repo := newFakeRepo(car)
+			repo.bids[car.ID] = car.NumberOfBids
+			service, _, _ := newTestService(t, repo)
+
+			saved, err := service.PatchCar(asUser("seller-1", authmodels.RoleSeller), car.ID, []byte(tt.patch), 3)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "New tyres", saved.Description)
+			require.Len(t, repo.saved, 1)
+			assert.True(t, repo.saved[0].BidExpirationTime.Equal(car.BidExpirationTime.Time))
+			assert.True(t, repo.saved[0].DatePosted.Equal(car.DatePosted.Time))
+		})
+	}
+}
+
+func TestPatchCar_KeepsVehicleOfUnchangedNames(t *testing.T) {
+	// The car was listed before its make and model were added to the taxonomy.
+	car := &models.Cars{
+		ID:                "car-1",
+		SellerID:          "seller-1",
+		CarName:           "Toyota Corolla",
+		BidExpirationTime: models.NewTime(time.Now().Add(72 * time.Hour)),
+		Version:           1,
+		NumberOfBids:      1,
+	}
+	repo := newFakeRepo(car)
+	repo.bids[car.ID] = 1
+	repo.vehicles = []models.Vehicle{
+		{Kind: models.VehicleMake, ID: "toyota", Name: "Toyota"},
+		{Kind: models.VehicleModel, ID: "toyota-corolla", ParentID: "toyota", Name: "Corolla"},
+	}
+	service, _, _ := newTestService(t, repo)
+
+	saved, err := service.PatchCar(asUser("seller-1", authmodels.RoleSeller), car.ID, []byte(`{"description":"New tyres"}`), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "", saved.Make)
+	assert.Equal(t, "", saved.MakeID)
+
+	_, err = service.PatchCar(asUser("seller-1", authmodels.RoleSeller), car.ID, []byte(`{"car_name":"Toyota Corolla LE"}`), 0)
+	assert.ErrorIs(t, err, models.ErrFieldLocked)
+}
+
+func TestPlaceBid_AuctionClosed(t *testing.T) {
+	car := &models.Cars{
+		ID:                "car-1",
+		SellerID:          "seller-1",
+		CarName:           "Toyota Corolla",
+		BidExpirationTime: models.NewTime(time.Now().Add(-time.Minute)),
+		Version:           1,
+	}
+	service, _, _ := newTestService(t, newFakeRepo(car))
+
+	_, err := service.PlaceBid(asUser("buyer-1", authmodels.RoleBuyer), models.Bids{CarID: car.ID, Amount: "1000000"})
+	assert.ErrorIs(t, err, models.ErrAuctionClosed)
+}
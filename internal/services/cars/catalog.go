@@ -0,0 +1,38 @@
+package cars
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/catalog"
+)
+
+// checkCatalog returns models.ErrUnknownCity or models.ErrUnknownCategory when car refers to a city or a category
+// that is not in the catalog. The foreign keys of cars enforce the same, this check only reports it before writing.
+// The extras of car must match the attribute schema of its category.
+func (s *ServiceImpl) checkCatalog(ctx context.Context, car models.Cars) error {
+	if car.CityID != "" {
+		if _, err := s.repo.GetCity(ctx, car.CityID); errors.Is(err, sql.ErrNoRows) {
+			return models.ErrUnknownCity
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if car.Category == "" {
+		return nil
+	}
+
+	category, err := s.repo.GetCategory(ctx, car.Category)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrUnknownCategory
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return catalog.ValidateAttributes(*category, car.Extras)
+}
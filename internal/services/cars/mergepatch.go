@@ -0,0 +1,82 @@
+package cars
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// applyMergePatch applies an RFC 7396 JSON merge patch to the JSON object doc.
+func applyMergePatch(doc []byte, patch []byte) ([]byte, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidPatch, err)
+	}
+
+	// A patch that is not an object would replace the whole car.
+	if _, ok := patchValue.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%w: the patch must be a JSON object", models.ErrInvalidPatch)
+	}
+
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+// mergePatch implements the MergePatch function of RFC 7396, section 2.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+
+			continue
+		}
+
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// changedMembers returns the names of the members of the JSON object doc that the merge patch patch sets to another
+// value. Members the patch repeats unchanged are not included.
+func changedMembers(doc []byte, patch []byte) (map[string]bool, error) {
+	// mergePatch changes the objects of its target in place, so it is given a copy of doc.
+	var docObject, target, patchObject map[string]interface{}
+	if err := json.Unmarshal(doc, &docObject); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(patch, &patchObject); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidPatch, err)
+	}
+
+	changed := map[string]bool{}
+
+	for name, value := range patchObject {
+		if !reflect.DeepEqual(mergePatch(target[name], value), docObject[name]) {
+			changed[name] = true
+		}
+	}
+
+	return changed, nil
+}
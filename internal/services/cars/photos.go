@@ -0,0 +1,348 @@
+package cars
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/storage"
+)
+
+// ErrPhotosNotConfigured is returned by NewService without photo storage.
+var ErrPhotosNotConfigured = errors.New("car photos need a storage backend")
+
+// photoExtensions are the sniffed content types accepted for photos, with the extension they are stored under.
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Photos configures car photos.
+type Photos struct {
+	Storage storage.Storage
+	// URLTTL is how long the signed URLs of photos stay valid.
+	URLTTL time.Duration
+}
+
+// preparedPhoto is an uploaded photo that has been checked and thumbnailed but not stored yet.
+type preparedPhoto struct {
+	content     []byte
+	contentType string
+	extension   string
+	width       int
+	height      int
+	thumbnail   []byte
+}
+
+// UploadPhotos implements Service by adding photos after the other photos of the car. When primary is set the first
+// uploaded photo becomes the primary photo. Every photo is checked before any is stored; if storing one fails the
+// photos stored before it are kept.
+func (s *ServiceImpl) UploadPhotos(ctx context.Context, carID string, photos []io.Reader, primary bool) ([]models.CarPhoto, error) {
+	if len(photos) == 0 {
+		return nil, models.ErrNoPhotos
+	}
+
+	car, err := s.carToManage(ctx, carID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListCarPhotos(ctx, car.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(photos) > models.MaxPhotosPerUpload || len(existing)+len(photos) > models.MaxPhotosPerCar {
+		return nil, models.ErrTooManyPhotos
+	}
+
+	prepared := make([]*preparedPhoto, 0, len(photos))
+
+	for _, content := range photos {
+		photo, err := preparePhoto(content)
+		if err != nil {
+			return nil, err
+		}
+
+		prepared = append(prepared, photo)
+	}
+
+	created := make([]models.CarPhoto, 0, len(prepared))
+
+	for i, photo := range prepared {
+		stored, err := s.storePhoto(ctx, car.ID, photo, primary && i == 0)
+		if err != nil {
+			return created, err
+		}
+
+		s.auditor.Record(ctx, auditmodels.Change{
+			Action:     auditmodels.ActionCarPhotoAdded,
+			EntityType: auditmodels.EntityCarPhoto,
+			EntityID:   stored.ID,
+			After:      stored,
+		})
+
+		created = append(created, *stored)
+	}
+
+	return created, s.signPhotos(ctx, created)
+}
+
+// storePhoto writes photo and its thumbnail to storage and records it. The blobs are removed again if the photo can
+// not be recorded.
+func (s *ServiceImpl) storePhoto(ctx context.Context, carID string, photo *preparedPhoto, primary bool) (*models.CarPhoto, error) {
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("cars/%s/%s%s", carID, name, photo.extension)
+	thumbnailKey := fmt.Sprintf("cars/%s/%s-thumb.jpg", carID, name)
+
+	if err := s.photos.Storage.Put(ctx, key, photo.contentType, photo.content); err != nil {
+		return nil, err
+	}
+
+	if err := s.photos.Storage.Put(ctx, thumbnailKey, "image/jpeg", photo.thumbnail); err != nil {
+		s.deleteBlobs(ctx, key)
+
+		return nil, err
+	}
+
+	stored, err := s.repo.CreateCarPhoto(ctx, models.CarPhoto{
+		CarID:        carID,
+		Primary:      primary,
+		ContentType:  photo.contentType,
+		Width:        photo.width,
+		Height:       photo.height,
+		Size:         len(photo.content),
+		StorageKey:   key,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		s.deleteBlobs(ctx, key, thumbnailKey)
+
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// preparePhoto reads an uploaded photo, checks its type and dimensions and renders its thumbnail.
+func preparePhoto(content io.Reader) (*preparedPhoto, error) {
+	data, err := io.ReadAll(io.LimitReader(content, models.MaxPhotoSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > models.MaxPhotoSize {
+		return nil, models.ErrPhotoTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+
+	extension, ok := photoExtensions[contentType]
+	if !ok {
+		return nil, models.ErrUnsupportedPhotoType
+	}
+
+	// The dimensions are checked before decoding so that a small file can not claim a huge image.
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrUnsupportedPhotoType
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > models.MaxPhotoPixels {
+		return nil, models.ErrPhotoDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrUnsupportedPhotoType
+	}
+
+	thumb, err := encodeThumbnail(img, models.ThumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preparedPhoto{
+		content:     data,
+		contentType: contentType,
+		extension:   extension,
+		width:       config.Width,
+		height:      config.Height,
+		thumbnail:   thumb,
+	}, nil
+}
+
+// ListPhotos implements Service.
+func (s *ServiceImpl) ListPhotos(ctx context.Context, carID string) ([]models.CarPhoto, error) {
+	car, err := s.repo.GetCarsByID(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := s.repo.ListCarPhotos(ctx, car.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return photos, s.signPhotos(ctx, photos)
+}
+
+// ReorderPhotos implements Service. photoIDs must list every photo of the car once.
+func (s *ServiceImpl) ReorderPhotos(ctx context.Context, carID string, photoIDs []string) ([]models.CarPhoto, error) {
+	car, err := s.carToManage(ctx, carID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := s.repo.ListCarPhotos(ctx, car.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := s.repo.ReorderCarPhotos(ctx, car.ID, photoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCarPhotosReordered,
+		EntityType: auditmodels.EntityCar,
+		EntityID:   car.ID,
+		Before:     before,
+		After:      photos,
+	})
+
+	return photos, s.signPhotos(ctx, photos)
+}
+
+// SetPrimaryPhoto implements Service.
+func (s *ServiceImpl) SetPrimaryPhoto(ctx context.Context, carID string, photoID string) (*models.CarPhoto, error) {
+	car, err := s.carToManage(ctx, carID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	photo, err := s.repo.SetPrimaryCarPhoto(ctx, car.ID, photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCarPhotoPrimary,
+		EntityType: auditmodels.EntityCarPhoto,
+		EntityID:   photo.ID,
+		After:      photo,
+	})
+
+	photos := []models.CarPhoto{*photo}
+	if err := s.signPhotos(ctx, photos); err != nil {
+		return nil, err
+	}
+
+	return &photos[0], nil
+}
+
+// DeletePhoto implements Service. The photo is removed from storage after it is deleted.
+func (s *ServiceImpl) DeletePhoto(ctx context.Context, carID string, photoID string) error {
+	car, err := s.carToManage(ctx, carID, 0)
+	if err != nil {
+		return err
+	}
+
+	photo, err := s.repo.DeleteCarPhoto(ctx, car.ID, photoID)
+	if err != nil {
+		return err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCarPhotoDeleted,
+		EntityType: auditmodels.EntityCarPhoto,
+		EntityID:   photo.ID,
+		Before:     photo,
+	})
+
+	s.deleteBlobs(ctx, photo.StorageKey, photo.ThumbnailKey)
+
+	return nil
+}
+
+// OpenPhoto implements Service for storage backends whose signed URLs point at this API.
+func (s *ServiceImpl) OpenPhoto(ctx context.Context, key string, expires string, signature string) ([]byte, string, error) {
+	server, ok := s.photos.Storage.(storage.Server)
+	if !ok {
+		return nil, "", storage.ErrNotFound
+	}
+
+	return server.Open(ctx, key, expires, signature)
+}
+
+// signPhotos sets the signed URLs of photos.
+func (s *ServiceImpl) signPhotos(ctx context.Context, photos []models.CarPhoto) error {
+	for i := range photos {
+		url, err := s.photos.Storage.SignedURL(ctx, photos[i].StorageKey, s.photos.URLTTL)
+		if err != nil {
+			return err
+		}
+
+		thumbnailURL, err := s.photos.Storage.SignedURL(ctx, photos[i].ThumbnailKey, s.photos.URLTTL)
+		if err != nil {
+			return err
+		}
+
+		photos[i].URL, photos[i].ThumbnailURL = url, thumbnailURL
+	}
+
+	return nil
+}
+
+// signThumbnails sets the signed thumbnail URLs of cars with a primary photo. Cars whose URL can not be signed are
+// shown without a thumbnail.
+func (s *ServiceImpl) signThumbnails(ctx context.Context, cars []*models.Cars) {
+	for _, car := range cars {
+		if car.ThumbnailKey == "" {
+			continue
+		}
+
+		url, err := s.photos.Storage.SignedURL(ctx, car.ThumbnailKey, s.photos.URLTTL)
+		if err != nil {
+			logger.Error().Err(err).Str("carID", car.ID).Msg("signing thumbnail url")
+
+			continue
+		}
+
+		car.ThumbnailURL = url
+	}
+}
+
+// deleteBlobs removes blobs from storage. Failures only leave unreferenced blobs behind, so they are logged.
+func (s *ServiceImpl) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.photos.Storage.Delete(ctx, key); err != nil {
+			logger.Error().Err(err).Str("key", key).Msg("deleting photo from storage")
+		}
+	}
+}
+
+func randomName() (string, error) {
+	//nolint:gomnd
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(name), nil
+}
@@ -0,0 +1,52 @@
+package cars
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/vin"
+)
+
+// highlightMarkup renders escaped search snippets as HTML.
+var highlightMarkup = strings.NewReplacer(models.HighlightStart, "<mark>", models.HighlightStop, "</mark>")
+
+// SearchCars implements Service. The query uses web search syntax: quoted phrases, "or" and -excluded words.
+func (s *ServiceImpl) SearchCars(ctx context.Context, query string, filter models.CarFilter) (models.Page[models.CarSearchResult], error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > models.MaxSearchLength {
+		return models.Page[models.CarSearchResult]{}, models.ErrInvalidSearch
+	}
+
+	if err := filter.Validate(); err != nil {
+		return models.Page[models.CarSearchResult]{}, err
+	}
+
+	// A VIN is looked up however it was typed.
+	if number, err := vin.Parse(query); err == nil {
+		query = number
+	}
+
+	results, err := s.repo.SearchCars(ctx, query, filter)
+	if err != nil {
+		return models.Page[models.CarSearchResult]{}, err
+	}
+
+	listed := make([]*models.Cars, len(results.Items))
+
+	for i := range results.Items {
+		results.Items[i].Highlight = renderHighlight(results.Items[i].Highlight)
+		listed[i] = &results.Items[i].Car
+	}
+
+	s.signThumbnails(ctx, listed)
+
+	return results, nil
+}
+
+// renderHighlight escapes a search snippet and marks up its highlighted words.
+func renderHighlight(snippet string) string {
+	return highlightMarkup.Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,14 @@
+package cars
+
+import (
+	"testing"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHighlight(t *testing.T) {
+	snippet := "Clean " + models.HighlightStart + "Corolla" + models.HighlightStop + " <script>alert(1)</script> & more"
+
+	assert.Equal(t, "Clean <mark>Corolla</mark> &lt;script&gt;alert(1)&lt;/script&gt; &amp; more", renderHighlight(snippet))
+}
@@ -0,0 +1,87 @@
+package cars
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// Registered for image.Decode.
+	_ "image/gif"
+	_ "image/png"
+)
+
+// thumbnailQuality is the JPEG quality of thumbnails.
+const thumbnailQuality = 80
+
+// thumbnail scales src down so that its longest side is at most size pixels, averaging the source pixels under each
+// thumbnail pixel. Transparent areas are drawn on white, since thumbnails are JPEG.
+func thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := width, height
+	if longest := max(width, height); longest > size {
+		dstWidth = max(1, width*size/longest)
+		dstHeight = max(1, height*size/longest)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	at := rgba64At(src)
+
+	for dy := 0; dy < dstHeight; dy++ {
+		y0, y1 := bounds.Min.Y+dy*height/dstHeight, bounds.Min.Y+(dy+1)*height/dstHeight
+
+		for dx := 0; dx < dstWidth; dx++ {
+			x0, x1 := bounds.Min.X+dx*width/dstWidth, bounds.Min.X+(dx+1)*width/dstWidth
+
+			var r, g, b, n uint64
+
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					c := at(x, y)
+					// Colors are alpha-premultiplied, so white shows through by the missing alpha.
+					r += uint64(c.R) + uint64(0xffff-c.A)
+					g += uint64(c.G) + uint64(0xffff-c.A)
+					b += uint64(c.B) + uint64(0xffff-c.A)
+					n++
+				}
+			}
+
+			dst.SetRGBA(dx, dy, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: 0xff})
+		}
+	}
+
+	return dst
+}
+
+// rgba64At returns the fastest way to read the pixels of img.
+func rgba64At(img image.Image) func(x, y int) color.RGBA64 {
+	if fast, ok := img.(image.RGBA64Image); ok {
+		return fast.RGBA64At
+	}
+
+	return func(x, y int) color.RGBA64 {
+		r, g, b, a := img.At(x, y).RGBA()
+
+		return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+	}
+}
+
+// encodeThumbnail returns the JPEG thumbnail of src.
+func encodeThumbnail(src image.Image, size int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail(src, size), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
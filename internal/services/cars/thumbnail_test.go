@@ -0,0 +1,60 @@
+package cars
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbnail(t *testing.T) {
+	// Red on the left, transparent on the right.
+	src := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 500; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 0xff, A: 0xff})
+		}
+	}
+
+	thumb := thumbnail(src, 320)
+	assert.Equal(t, image.Rect(0, 0, 320, 160), thumb.Bounds())
+	assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, thumb.RGBAAt(10, 80))
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, thumb.RGBAAt(310, 80), "transparency is drawn on white")
+
+	small := thumbnail(image.NewNRGBA(image.Rect(0, 0, 40, 2)), 320)
+	assert.Equal(t, image.Rect(0, 0, 40, 2), small.Bounds(), "small images are not enlarged")
+
+	narrow := thumbnail(image.NewNRGBA(image.Rect(0, 0, 2000, 1)), 320)
+	assert.Equal(t, image.Rect(0, 0, 320, 1), narrow.Bounds())
+}
+
+func TestPreparePhoto(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 640, 480))))
+
+	photo, err := preparePhoto(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", photo.contentType)
+	assert.Equal(t, ".png", photo.extension)
+	assert.Equal(t, 640, photo.width)
+	assert.Equal(t, 480, photo.height)
+
+	thumb, _, err := image.Decode(bytes.NewReader(photo.thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 320, 240), thumb.Bounds())
+
+	_, err = preparePhoto(strings.NewReader("<svg xmlns='http://www.w3.org/2000/svg'></svg>"))
+	assert.ErrorIs(t, err, models.ErrUnsupportedPhotoType)
+
+	_, err = preparePhoto(bytes.NewReader(append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 16)...)))
+	assert.ErrorIs(t, err, models.ErrUnsupportedPhotoType, "corrupt images are refused")
+
+	_, err = preparePhoto(bytes.NewReader(make([]byte, models.MaxPhotoSize+1)))
+	assert.ErrorIs(t, err, models.ErrPhotoTooLarge)
+}
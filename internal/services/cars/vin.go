@@ -0,0 +1,42 @@
+package cars
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/vin"
+)
+
+// DecodeVIN implements Service so that listing forms can be prefilled from a VIN.
+func (s *ServiceImpl) DecodeVIN(_ context.Context, number string) (models.VINInfo, error) {
+	return vin.Decode(number, time.Now())
+}
+
+// applyVIN normalizes the VIN of car, if it has one, and fills in the make and year it encodes when the seller left
+// them out.
+func applyVIN(car *models.Cars) error {
+	if strings.TrimSpace(car.VIN) == "" {
+		car.VIN = ""
+
+		return nil
+	}
+
+	info, err := vin.Decode(car.VIN, time.Now())
+	if err != nil {
+		return err
+	}
+
+	car.VIN = info.VIN
+
+	if car.Make == "" {
+		car.Make = info.Make
+	}
+
+	if car.Year == 0 {
+		car.Year = info.ModelYear
+	}
+
+	return nil
+}
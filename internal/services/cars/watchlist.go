@@ -0,0 +1,175 @@
+package cars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"time"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/sms"
+)
+
+// ErrWatchlistNotConfigured is returned by NewService when watchers can not be notified.
+var ErrWatchlistNotConfigured = errors.New("a mailer and an sms sender are required to notify watchers")
+
+// deadlineLayout formats auction deadlines in notifications.
+const deadlineLayout = "15:04 on 2 January"
+
+// Watchlist configures the notifications sent to the users watching a car.
+type Watchlist struct {
+	// Mailer and SMS notify watchers, by email or by SMS for watchers without an email address.
+	Mailer services.Mailer
+	SMS    sms.Sender
+	// EndingWithin is how long before the end of an auction watchers are told it is ending.
+	EndingWithin time.Duration
+}
+
+// WatchCar implements Service. Watching a car twice has no effect. Withdrawn cars can not be watched.
+func (s *ServiceImpl) WatchCar(ctx context.Context, userID string, carID string) (*models.WatchedCar, error) {
+	car, err := s.repo.GetCarsByID(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !car.WithdrawnAt.IsZero() {
+		return nil, models.ErrCarWithdrawn
+	}
+
+	if err := s.repo.WatchCar(ctx, userID, car.ID); err != nil {
+		return nil, err
+	}
+
+	watched, err := s.repo.GetWatchedCar(ctx, userID, car.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.signThumbnails(ctx, []*models.Cars{&watched.Cars})
+
+	return watched, nil
+}
+
+// UnwatchCar implements Service.
+func (s *ServiceImpl) UnwatchCar(ctx context.Context, userID string, carID string) error {
+	return s.repo.UnwatchCar(ctx, userID, carID)
+}
+
+// ListWatchlist implements Service.
+func (s *ServiceImpl) ListWatchlist(ctx context.Context, userID string) ([]models.WatchedCar, error) {
+	watched, err := s.repo.ListWatchlist(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make([]*models.Cars, len(watched))
+	for i := range watched {
+		listed[i] = &watched[i].Cars
+	}
+
+	s.signThumbnails(ctx, listed)
+
+	return watched, nil
+}
+
+// NotifyWatchers tells watchers about the auctions ending within Watchlist.EndingWithin and the cars sold since it was
+// last called. A car is sold when its auction ends with at least one bid; whether the winner pays is not known here.
+// It is meant to be called periodically; every watcher is told once, however many replicas call it.
+func (s *ServiceImpl) NotifyWatchers(ctx context.Context) error {
+	ending, err := s.repo.ClaimEndingWatches(ctx, s.watchlist.EndingWithin)
+	if err != nil {
+		return err
+	}
+
+	s.notifyWatches(ctx, ending, "A car you watch is ending soon", func(car *models.WatchedCar) string {
+		minutes := int(math.Ceil(float64(car.SecondsLeft) / 60))
+
+		return fmt.Sprintf("The auction of %s ends in %d minutes, at %s. The current price is %d XAF.", car.CarName,
+			minutes, car.BidExpirationTime.In(models.DefaultLocation).Format(deadlineLayout), car.CurrentPrice)
+	})
+
+	sold, err := s.repo.ClaimSoldWatches(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.notifyWatches(ctx, sold, "A car you watch has sold", func(car *models.WatchedCar) string {
+		return fmt.Sprintf("The auction of %s has ended with a winning bid of %d XAF.", car.CarName, car.CurrentPrice)
+	})
+
+	return nil
+}
+
+// notifyWatches sends the message written for each watched car to its watcher. Sellers are not told about their own
+// cars. Failures are logged.
+func (s *ServiceImpl) notifyWatches(ctx context.Context, watches []models.Watch, subject string, message func(car *models.WatchedCar) string) {
+	for _, watch := range watches {
+		car, err := s.repo.GetWatchedCar(ctx, watch.UserID, watch.CarID)
+		if err != nil {
+			logger.Error().Err(err).Str("carID", watch.CarID).Str("userID", watch.UserID).Msg("reading watched car")
+
+			continue
+		}
+
+		if car.SellerID == watch.UserID {
+			continue
+		}
+
+		watcher, err := s.repo.GetUserByID(ctx, watch.UserID)
+		if err != nil {
+			logger.Error().Err(err).Str("userID", watch.UserID).Msg("reading watcher")
+
+			continue
+		}
+
+		s.notifyWatcher(ctx, watcher, car.ID, subject, message(car))
+	}
+}
+
+// notifyWatchersOf tells the users watching car about a change to its auction, except the user who made it. It is
+// called in the background so that cars with many watchers do not hold up bids and updates.
+func (s *ServiceImpl) notifyWatchersOf(car *models.Cars, actorID string, subject string, message string) {
+	ctx := context.Background()
+
+	watchers, err := s.repo.ListWatchers(ctx, car.ID)
+	if err != nil {
+		logger.Error().Err(err).Str("carID", car.ID).Msg("listing watchers")
+
+		return
+	}
+
+	for i := range watchers {
+		if watchers[i].User_id == actorID || watchers[i].User_id == car.SellerID {
+			continue
+		}
+
+		s.notifyWatcher(ctx, &watchers[i], car.ID, subject, message)
+	}
+}
+
+// notifyWatcher sends message by email, or by SMS to watchers without an email address.
+func (s *ServiceImpl) notifyWatcher(ctx context.Context, watcher *models.Users, carID string, subject string, message string) {
+	var err error
+
+	switch {
+	case watcher.Email != "":
+		err = s.watchlist.Mailer.SendEmail(ctx, services.Email{
+			To:      []string{watcher.Email},
+			Subject: subject,
+			HTMLBody: fmt.Sprintf(`<p>Hello %s,</p><p>%s</p>`, html.EscapeString(watcher.UserName),
+				html.EscapeString(message)),
+			TextBody: fmt.Sprintf("Hello %s,\n\n%s\n", watcher.UserName, message),
+		})
+	case watcher.PhoneNumber != "":
+		err = s.watchlist.SMS.SendSMS(ctx, watcher.PhoneNumber, "Sigma Auto: "+message)
+	default:
+		return
+	}
+
+	if err != nil {
+		logger.Error().Err(err).Str("carID", carID).Str("userID", watcher.User_id).Msg("notifying watcher")
+	}
+}
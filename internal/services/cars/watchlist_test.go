@@ -0,0 +1,103 @@
+package cars
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *fakeMailer) subjects() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var subjects []string
+	for _, email := range m.emails {
+		subjects = append(subjects, email.Subject)
+	}
+
+	return subjects
+}
+
+func watchedRepo() *fakeRepo {
+	car := &models.Cars{
+		ID:                "car-1",
+		SellerID:          "seller-1",
+		CarName:           "Toyota Corolla",
+		BidingPrice:       1000000,
+		BidExpirationTime: models.NewTime(time.Now().Add(30 * time.Minute)),
+		Version:           1,
+	}
+
+	repo := newFakeRepo(car)
+	watchers := []models.Users{
+		{User_id: "seller-1", UserName: "Sam", Email: "sam@example.com"},
+		{User_id: "buyer-1", UserName: "Ada", Email: "ada@example.com", PhoneNumber: "+237650000001"},
+		{User_id: "buyer-2", UserName: "Bo", PhoneNumber: "+237650000002"},
+	}
+	repo.watchers[car.ID] = watchers
+
+	for i := range watchers {
+		repo.users[watchers[i].User_id] = &watchers[i]
+	}
+
+	return repo
+}
+
+func TestNotifyWatchersOf(t *testing.T) {
+	repo := watchedRepo()
+	service, mailer, sender := newTestService(t, repo)
+
+	service.notifyWatchersOf(repo.cars["car-1"], "buyer-2", "A car you watch has a new bid", "Bo bid 1100000 XAF.")
+
+	assert.Equal(t, []string{"ada@example.com"}, mailer.sent(), "the seller is not told about their own car")
+	assert.Empty(t, sender.sent("+237650000001"), "watchers with an email address are not texted")
+	assert.Empty(t, sender.sent("+237650000002"), "the bidder is not told about their own bid")
+
+	service.notifyWatchersOf(repo.cars["car-1"], "buyer-1", "A car you watch has a new bid", "Ada bid 1200000 XAF.")
+
+	assert.Equal(t, []string{"Sigma Auto: Ada bid 1200000 XAF."}, sender.sent("+237650000002"))
+	assert.Len(t, mailer.sent(), 1)
+}
+
+func TestNotifyWatchers(t *testing.T) {
+	repo := watchedRepo()
+	service, mailer, sender := newTestService(t, repo)
+
+	repo.ending = []models.Watch{{UserID: "seller-1", CarID: "car-1"}, {UserID: "buyer-1", CarID: "car-1"}}
+	require.NoError(t, service.NotifyWatchers(context.Background()))
+
+	assert.Equal(t, []string{"ada@example.com"}, mailer.sent())
+	assert.Equal(t, []string{"A car you watch is ending soon"}, mailer.subjects())
+
+	repo.sold = []models.Watch{{UserID: "buyer-2", CarID: "car-1"}}
+	require.NoError(t, service.NotifyWatchers(context.Background()))
+
+	require.Len(t, sender.sent("+237650000002"), 1)
+	assert.Contains(t, sender.sent("+237650000002")[0], "ended with a winning bid of 1000000 XAF")
+
+	require.NoError(t, service.NotifyWatchers(context.Background()))
+	assert.Len(t, mailer.sent(), 1, "claimed watches are not notified again")
+	assert.Len(t, sender.sent("+237650000002"), 1)
+}
+
+func TestPatchCar_AnnouncesExtension(t *testing.T) {
+	repo := watchedRepo()
+	service, mailer, sender := newTestService(t, repo)
+
+	deadline := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	_, err := service.PatchCar(asUser("seller-1", authmodels.RoleSeller), "car-1",
+		[]byte(`{"bid_expiration_time":"`+deadline+`"}`), 0)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(mailer.sent()) == 1 && len(sender.sent("+237650000002")) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ada@example.com"}, mailer.sent(), "the seller is not told about their own change")
+	assert.Equal(t, []string{"A car you watch has been extended"}, mailer.subjects())
+	assert.Contains(t, sender.sent("+237650000002")[0], "has been extended until")
+}
@@ -0,0 +1,116 @@
+package cars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/sms"
+)
+
+// ErrWithdrawalsNotConfigured is returned by NewService when bidders can not be told about withdrawals.
+var ErrWithdrawalsNotConfigured = errors.New("a mailer and an sms sender are required to notify bidders of withdrawals")
+
+// Withdrawals configures car withdrawals.
+type Withdrawals struct {
+	// Mailer and SMS notify the bidders of a withdrawn car, by email or by SMS for bidders without an email address.
+	Mailer services.Mailer
+	SMS    sms.Sender
+	// Cutoff is how long before the end of an auction sellers can no longer withdraw their car. Admins can always
+	// withdraw cars.
+	Cutoff time.Duration
+}
+
+// WithdrawCar implements Service. The car stays with its bids but is no longer listed, and its bidders are notified.
+// The repository audits the withdrawal.
+func (s *ServiceImpl) WithdrawCar(ctx context.Context, carID string, reason string) (*models.Cars, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, models.ErrWithdrawalReasonRequired
+	}
+
+	car, err := s.repo.GetCarsByID(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canManageCar(principal, car) {
+		return nil, models.ErrForbidden
+	}
+
+	if !car.WithdrawnAt.IsZero() {
+		return nil, models.ErrCarWithdrawn
+	}
+
+	// Cars without a deadline have no auction to protect.
+	if !principal.IsAdmin() && !car.BidExpirationTime.IsZero() &&
+		time.Now().After(car.BidExpirationTime.Add(-s.withdrawals.Cutoff)) {
+		return nil, models.ErrWithdrawalClosed
+	}
+
+	withdrawn, err := s.repo.WithdrawCar(ctx, carID, principal.UserID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.notifyBidders(withdrawn, principal.IsAdmin() && principal.UserID != car.SellerID)
+
+	return withdrawn, nil
+}
+
+// RestoreCar implements Service. The repository audits the restoration.
+func (s *ServiceImpl) RestoreCar(ctx context.Context, carID string) (*models.Cars, error) {
+	return s.repo.RestoreCar(ctx, carID)
+}
+
+// notifyBidders tells the bidders of car that it has been withdrawn. It is called in the background so that cars with
+// many bidders do not hold up the withdrawal. Failures are logged, the withdrawal stands.
+func (s *ServiceImpl) notifyBidders(car *models.Cars, takenDown bool) {
+	ctx := context.Background()
+
+	bidders, err := s.repo.ListBidders(ctx, car.ID)
+	if err != nil {
+		logger.Error().Err(err).Str("carID", car.ID).Msg("listing bidders of withdrawn car")
+
+		return
+	}
+
+	by := "the seller"
+	if takenDown {
+		by = "Sigma Auto"
+	}
+
+	for _, bidder := range bidders {
+		switch {
+		case bidder.Email != "":
+			err = s.withdrawals.Mailer.SendEmail(ctx, services.Email{
+				To:      []string{bidder.Email},
+				Subject: "A car you bid on has been withdrawn",
+				HTMLBody: fmt.Sprintf(`<p>Hello %s,</p><p>%s has been withdrawn by %s. The auction is cancelled and `+
+					`your bid will not be taken up.</p>`, html.EscapeString(bidder.UserName), html.EscapeString(car.CarName), by),
+				TextBody: fmt.Sprintf("Hello %s,\n\n%s has been withdrawn by %s. The auction is cancelled and "+
+					"your bid will not be taken up.\n", bidder.UserName, car.CarName, by),
+			})
+		case bidder.PhoneNumber != "":
+			err = s.withdrawals.SMS.SendSMS(ctx, bidder.PhoneNumber,
+				fmt.Sprintf("Sigma Auto: %s has been withdrawn by %s and your bid on it is cancelled.", car.CarName, by))
+		default:
+			continue
+		}
+
+		if err != nil {
+			logger.Error().Err(err).Str("carID", car.ID).Str("userID", bidder.User_id).Msg("notifying bidder of withdrawal")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package cars
+
+import (
+	"testing"
+	"time"
+
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithdrawCar(t *testing.T) {
+	// newTestService sets a cutoff of an hour.
+	tests := []struct {
+		name     string
+		deadline time.Duration
+		userID   string
+		role     authmodels.Role
+		wantErr  error
+	}{
+		{"seller before the cutoff", 2 * time.Hour, "seller-1", authmodels.RoleSeller, nil},
+		{"seller after the cutoff", 30 * time.Minute, "seller-1", authmodels.RoleSeller, models.ErrWithdrawalClosed},
+		{"seller after the end", -time.Hour, "seller-1", authmodels.RoleSeller, models.ErrWithdrawalClosed},
+		{"seller without a deadline", 0, "seller-1", authmodels.RoleSeller, nil},
+		{"admin after the cutoff", 30 * time.Minute, "admin-1", authmodels.RoleAdmin, nil},
+		{"another seller", 2 * time.Hour, "seller-2", authmodels.RoleSeller, models.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			car := &models.Cars{ID: "car-1", SellerID: "seller-1", CarName: "Toyota Corolla", Version: 1}
+			if tt.deadline != 0 {
+				car.BidExpirationTime = models.NewTime(time.Now().Add(tt.deadline))
+			}
+
+			repo := newFakeRepo(car)
+			service, _, _ := newTestService(t, repo)
+
+			withdrawn, err := service.WithdrawCar(asUser(tt.userID, tt.role), car.ID, " sold privately ")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.True(t, repo.cars[car.ID].WithdrawnAt.IsZero())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.False(t, withdrawn.WithdrawnAt.IsZero())
+			assert.Equal(t, tt.userID, withdrawn.WithdrawnBy)
+			assert.Equal(t, "sold privately", withdrawn.WithdrawalReason)
+
+			_, err = service.WithdrawCar(asUser(tt.userID, tt.role), car.ID, "again")
+			assert.ErrorIs(t, err, models.ErrCarWithdrawn)
+		})
+	}
+}
+
+func TestWithdrawCar_NotifiesBidders(t *testing.T) {
+	car := &models.Cars{ID: "car-1", SellerID: "seller-1", CarName: "Toyota Corolla", Version: 1}
+	repo := newFakeRepo(car)
+	repo.bidders[car.ID] = []models.Users{
+		{User_id: "buyer-1", UserName: "Ada", Email: "ada@example.com", PhoneNumber: "+237650000001"},
+		{User_id: "buyer-2", UserName: "Bo", PhoneNumber: "+237650000002"},
+	}
+	service, mailer, sender := newTestService(t, repo)
+
+	_, err := service.WithdrawCar(asUser("admin-1", authmodels.RoleAdmin), car.ID, "fraud")
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(mailer.sent()) == 1 && len(sender.sent("+237650000002")) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ada@example.com"}, mailer.sent())
+	assert.Empty(t, sender.sent("+237650000001"), "bidders with an email address are not texted")
+	assert.Contains(t, sender.sent("+237650000002")[0], "withdrawn by Sigma Auto")
+}
+
+func TestRestoreCar(t *testing.T) {
+	car := &models.Cars{ID: "car-1", SellerID: "seller-1", CarName: "Toyota Corolla", Version: 1}
+	repo := newFakeRepo(car)
+	service, _, _ := newTestService(t, repo)
+
+	_, err := service.RestoreCar(asUser("admin-1", authmodels.RoleAdmin), car.ID)
+	assert.ErrorIs(t, err, models.ErrCarNotWithdrawn)
+
+	_, err = service.WithdrawCar(asUser("seller-1", authmodels.RoleSeller), car.ID, "sold")
+	require.NoError(t, err)
+
+	restored, err := service.RestoreCar(asUser("admin-1", authmodels.RoleAdmin), car.ID)
+	require.NoError(t, err)
+	assert.True(t, restored.WithdrawnAt.IsZero())
+	assert.Empty(t, restored.WithdrawalReason)
+
+	_, err = service.PatchCar(asUser("seller-1", authmodels.RoleSeller), car.ID, []byte(`{"description":"Back"}`), 0)
+	assert.NoError(t, err, "restored cars can be changed again")
+}
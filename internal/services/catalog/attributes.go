@@ -0,0 +1,103 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// CheckAttributeSchema returns models.ErrInvalidAttributeSchema unless schema is a JSON Schema of objects. References
+// must point inside the schema: the validator would otherwise fetch them.
+func CheckAttributeSchema(schema []byte) error {
+	var root map[string]interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return fmt.Errorf("%w: the schema must be a JSON object", models.ErrInvalidAttributeSchema)
+	}
+
+	if root["type"] != "object" {
+		return fmt.Errorf("%w: the schema must have type object", models.ErrInvalidAttributeSchema)
+	}
+
+	if ref, ok := externalRef(root); ok {
+		return fmt.Errorf("%w: $ref %q is not inside the schema", models.ErrInvalidAttributeSchema, ref)
+	}
+
+	if _, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema)); err != nil {
+		return fmt.Errorf("%w: %s", models.ErrInvalidAttributeSchema, err)
+	}
+
+	return nil
+}
+
+// externalRef returns the first $ref of value that does not start with #.
+func externalRef(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok && !strings.HasPrefix(ref, "#") {
+			return ref, true
+		}
+
+		for _, child := range v {
+			if ref, ok := externalRef(child); ok {
+				return ref, true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if ref, ok := externalRef(child); ok {
+				return ref, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ValidateAttributes returns a *models.AttributesError listing the extras that do not match the attribute schema of
+// category. Categories without a schema accept any extras.
+func ValidateAttributes(category models.Category, extras []byte) error {
+	if category.AttributeSchema == nil {
+		return nil
+	}
+
+	if len(extras) == 0 {
+		extras = []byte("{}")
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(*category.AttributeSchema),
+		gojsonschema.NewBytesLoader(extras))
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	fields := make([]models.FieldError, 0, len(result.Errors()))
+
+	for _, resultErr := range result.Errors() {
+		field := resultErr.Field()
+
+		// Missing properties are reported on the object that misses them.
+		if property, ok := resultErr.Details()["property"].(string); ok && resultErr.Type() == "required" {
+			field = strings.TrimPrefix(field+"."+property, "(root).")
+		}
+
+		if field == "(root)" {
+			field = "extras"
+		} else {
+			field = "extras." + field
+		}
+
+		fields = append(fields, models.FieldError{Field: field, Message: resultErr.Description()})
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+
+	return &models.AttributesError{Category: category.ID, Fields: fields}
+}
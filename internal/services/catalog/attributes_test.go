@@ -0,0 +1,59 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx/types"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const truckSchema = `{
+	"type": "object",
+	"properties": {
+		"axles": {"type": "integer", "minimum": 2},
+		"payload_kg": {"type": "integer"}
+	},
+	"required": ["axles"]
+}`
+
+func TestCheckAttributeSchema(t *testing.T) {
+	assert.NoError(t, CheckAttributeSchema([]byte(truckSchema)))
+
+	for name, schema := range map[string]string{
+		"not json":     `{"type": "object"`,
+		"not object":   `{"type": "array"}`,
+		"invalid":      `{"type": "object", "properties": {"axles": {"type": "integer", "minimum": "two"}}}`,
+		"external ref": `{"type": "object", "properties": {"axles": {"$ref": "http://example.com/axles.json"}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, CheckAttributeSchema([]byte(schema)), models.ErrInvalidAttributeSchema)
+		})
+	}
+
+	assert.NoError(t, CheckAttributeSchema([]byte(`{"type": "object", "definitions": {"n": {"type": "integer"}},
+		"properties": {"axles": {"$ref": "#/definitions/n"}}}`)))
+}
+
+func TestValidateAttributes(t *testing.T) {
+	schema := types.JSONText(truckSchema)
+	truck := models.Category{ID: "truck", AttributeSchema: &schema}
+
+	assert.NoError(t, ValidateAttributes(truck, []byte(`{"axles": 3, "color": "red"}`)))
+	assert.NoError(t, ValidateAttributes(models.Category{ID: "sedan"}, []byte(`{"axles": "many"}`)),
+		"categories without a schema accept any extras")
+
+	err := ValidateAttributes(truck, []byte(`{"axles": 1, "payload_kg": "heavy"}`))
+	require.ErrorIs(t, err, models.ErrInvalidAttributes)
+
+	var attributesErr *models.AttributesError
+	require.ErrorAs(t, err, &attributesErr)
+	require.Len(t, attributesErr.Fields, 2)
+	assert.Equal(t, "extras.axles", attributesErr.Fields[0].Field)
+	assert.Equal(t, "extras.payload_kg", attributesErr.Fields[1].Field)
+
+	err = ValidateAttributes(truck, nil)
+	require.ErrorAs(t, err, &attributesErr)
+	assert.Equal(t, []models.FieldError{{Field: "extras.axles", Message: "axles is required"}}, attributesErr.Fields)
+}
@@ -0,0 +1,241 @@
+package catalog
+
+import (
+	"context"
+	"strings"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/audit"
+)
+
+//go:generate mockgen -source ./catalog_service.go -destination mocks/catalog_service.mock.go -package mocks
+
+// Service manages the cities and categories cars are listed in.
+//
+//nolint:interfacebloat
+type Service interface {
+	ListCities(ctx context.Context) ([]models.City, error)
+	CreateCity(ctx context.Context, req models.CityRequest) (*models.City, error)
+	UpdateCity(ctx context.Context, cityID string, req models.CityRequest) (*models.City, error)
+	DeleteCity(ctx context.Context, cityID string) error
+	ListCategories(ctx context.Context) ([]models.Category, error)
+	CreateCategory(ctx context.Context, req models.CategoryRequest) (*models.Category, error)
+	UpdateCategory(ctx context.Context, categoryID string, req models.CategoryRequest) (*models.Category, error)
+	DeleteCategory(ctx context.Context, categoryID string) error
+	GetCategory(ctx context.Context, categoryID string) (*models.Category, error)
+	SetCategorySchema(ctx context.Context, categoryID string, schema []byte) (*models.Category, error)
+}
+
+type ServiceImpl struct {
+	repo    persistence.Repository
+	auditor audit.Recorder
+}
+
+//nolint:exhaustivestruct
+var _ Service = &ServiceImpl{}
+
+func NewService(repo persistence.Repository, auditor audit.Recorder) (*ServiceImpl, error) {
+	return &ServiceImpl{repo: repo, auditor: auditor}, nil
+}
+
+// ListCities implements Service.
+func (s *ServiceImpl) ListCities(ctx context.Context) ([]models.City, error) {
+	return s.repo.ListCities(ctx)
+}
+
+// CreateCity implements Service.
+func (s *ServiceImpl) CreateCity(ctx context.Context, req models.CityRequest) (*models.City, error) {
+	city, err := req.City()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateCity(ctx, city)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCityCreated,
+		EntityType: auditmodels.EntityCity,
+		EntityID:   created.ID,
+		After:      created,
+	})
+
+	return created, nil
+}
+
+// UpdateCity implements Service. The id in req, if any, must be cityID: ids are referenced by cars and never change.
+func (s *ServiceImpl) UpdateCity(ctx context.Context, cityID string, req models.CityRequest) (*models.City, error) {
+	req.ID = idOf(req.ID, cityID)
+
+	city, err := req.City()
+	if err != nil {
+		return nil, err
+	}
+
+	if city.ID != cityID {
+		return nil, models.ErrImmutableField
+	}
+
+	before, err := s.repo.GetCity(ctx, cityID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateCity(ctx, cityID, city)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCityUpdated,
+		EntityType: auditmodels.EntityCity,
+		EntityID:   updated.ID,
+		Before:     before,
+		After:      updated,
+	})
+
+	return updated, nil
+}
+
+// DeleteCity implements Service. Cities cars are listed in can not be deleted.
+func (s *ServiceImpl) DeleteCity(ctx context.Context, cityID string) error {
+	deleted, err := s.repo.DeleteCity(ctx, cityID)
+	if err != nil {
+		return err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCityDeleted,
+		EntityType: auditmodels.EntityCity,
+		EntityID:   deleted.ID,
+		Before:     deleted,
+	})
+
+	return nil
+}
+
+// ListCategories implements Service.
+func (s *ServiceImpl) ListCategories(ctx context.Context) ([]models.Category, error) {
+	return s.repo.ListCategories(ctx)
+}
+
+// CreateCategory implements Service.
+func (s *ServiceImpl) CreateCategory(ctx context.Context, req models.CategoryRequest) (*models.Category, error) {
+	category, err := req.Category()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateCategory(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCategoryCreated,
+		EntityType: auditmodels.EntityCategory,
+		EntityID:   created.ID,
+		After:      created,
+	})
+
+	return created, nil
+}
+
+// UpdateCategory implements Service. The id in req, if any, must be categoryID.
+func (s *ServiceImpl) UpdateCategory(ctx context.Context, categoryID string, req models.CategoryRequest) (*models.Category, error) {
+	req.ID = idOf(req.ID, categoryID)
+
+	category, err := req.Category()
+	if err != nil {
+		return nil, err
+	}
+
+	if category.ID != categoryID {
+		return nil, models.ErrImmutableField
+	}
+
+	before, err := s.repo.GetCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateCategory(ctx, categoryID, category)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCategoryUpdated,
+		EntityType: auditmodels.EntityCategory,
+		EntityID:   updated.ID,
+		Before:     before,
+		After:      updated,
+	})
+
+	return updated, nil
+}
+
+// DeleteCategory implements Service. Categories cars are listed in can not be deleted.
+func (s *ServiceImpl) DeleteCategory(ctx context.Context, categoryID string) error {
+	deleted, err := s.repo.DeleteCategory(ctx, categoryID)
+	if err != nil {
+		return err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCategoryDeleted,
+		EntityType: auditmodels.EntityCategory,
+		EntityID:   deleted.ID,
+		Before:     deleted,
+	})
+
+	return nil
+}
+
+// GetCategory implements Service.
+func (s *ServiceImpl) GetCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	return s.repo.GetCategory(ctx, categoryID)
+}
+
+// SetCategorySchema implements Service. A nil schema removes the attribute schema of the category. Cars already
+// listed are only checked against a new schema when they are next saved.
+func (s *ServiceImpl) SetCategorySchema(ctx context.Context, categoryID string, schema []byte) (*models.Category, error) {
+	if schema != nil {
+		if err := CheckAttributeSchema(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	before, err := s.repo.GetCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.SetCategorySchema(ctx, categoryID, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionCategorySchemaChanged,
+		EntityType: auditmodels.EntityCategory,
+		EntityID:   updated.ID,
+		Before:     before,
+		After:      updated,
+	})
+
+	return updated, nil
+}
+
+// idOf returns the id of a request to update the entry pathID, which is pathID unless the request names another.
+func idOf(requestID string, pathID string) string {
+	if strings.TrimSpace(requestID) == "" {
+		return pathID
+	}
+
+	return requestID
+}
@@ -19,7 +19,7 @@ var logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.
 //go:generate mockgen -source ./payment.go -destination mocks/payments.mock.go -package mocks
 
 type PaymentService interface {
-	InitiatePayments(ctx context.Context, req paymentModels.RequestBody) error
+	InitiatePayments(ctx context.Context, req paymentModels.RequestBody) (*paymentModels.ResponseBody, error)
 }
 
 type PymentServiceImpl struct {
@@ -36,7 +36,7 @@ func NewPymentService(user string, pwd string, baseURL string) (*PymentServiceIm
 }
 
 //nolint:funlen
-func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentModels.RequestBody) error {
+func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentModels.RequestBody) (*paymentModels.ResponseBody, error) {
 	client := &http.Client{}
 
 	token, err := p.getAcessToken(client)
@@ -44,7 +44,7 @@ func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentMod
 		errMsg := "failed to get Access Token"
 		logger.Error().Str("correlationID", fmt.Sprint(ctx.Value("correlationID"))).Msgf("%s :-> %v", errMsg, err)
 
-		return fmt.Errorf("%s :-> %w", errMsg, err)
+		return nil, fmt.Errorf("%s :-> %w", errMsg, err)
 	}
 
 	initiateBody, err := json.Marshal(req)
@@ -52,7 +52,7 @@ func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentMod
 		errMsg := "failed to Marhsal initiate pyment Req"
 		logger.Error().Str("correlationID", fmt.Sprint(ctx.Value("correlationID"))).Msgf("%s :-> %v", errMsg, err)
 
-		return fmt.Errorf("%s :-> %w", errMsg, err)
+		return nil, fmt.Errorf("%s :-> %w", errMsg, err)
 	}
 	//nolint:noctx
 	pymntsReq, err := http.NewRequest(http.MethodPost, p.baseURL+"/collect/", bytes.NewReader(initiateBody))
@@ -60,7 +60,7 @@ func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentMod
 		errMsg := "initiate pymnt error"
 		logger.Error().Str("correlationID", fmt.Sprint(ctx.Value("correlationID"))).Msgf("%s :-> %v", errMsg, err)
 
-		return fmt.Errorf("%s :-> %w", errMsg, err)
+		return nil, fmt.Errorf("%s :-> %w", errMsg, err)
 	}
 
 	pymntsReq.Header.Add("Authorization", "Token "+token.AccessToken)
@@ -71,7 +71,7 @@ func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentMod
 		errMsg := "failed to initiate payments"
 		logger.Error().Str("correlationID", fmt.Sprint(ctx.Value("correlationID"))).Msgf("%s :->  %v", errMsg, err)
 
-		return fmt.Errorf("%s :-> %w", errMsg, err)
+		return nil, fmt.Errorf("%s :-> %w", errMsg, err)
 	}
 	defer pymntRes.Body.Close()
 
@@ -82,7 +82,7 @@ func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentMod
 		logger.Error().Str("correlationID", fmt.Sprint(ctx.Value("correlationID"))).Msgf("%s :->  %v", errMsg, err)
 
 		//nolint:goerr113
-		return fmt.Errorf(" %s :-> %v %s", errMsg, pymntRes.StatusCode, string(body))
+		return nil, fmt.Errorf(" %s :-> %v %s", errMsg, pymntRes.StatusCode, string(body))
 	}
 
 	pymntBody, err := io.ReadAll(pymntRes.Body)
@@ -90,7 +90,7 @@ func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentMod
 		errMsg := "failed to read pymnt body"
 		logger.Error().Str("correlationID", fmt.Sprint(ctx.Value("correlationID"))).Msgf("%s :->  %v", errMsg, err)
 
-		return fmt.Errorf("%s :-> %w", errMsg, err)
+		return nil, fmt.Errorf("%s :-> %w", errMsg, err)
 	}
 
 	var pymntResponse paymentModels.ResponseBody
@@ -99,8 +99,8 @@ func (p *PymentServiceImpl) InitiatePayments(ctx context.Context, req paymentMod
 		errMsg := "error umarshaling pyment response body"
 		logger.Error().Str("correlationID", fmt.Sprint(ctx.Value("correlationID"))).Msgf("%s :->  %v", errMsg, err)
 
-		return fmt.Errorf("%s :-> %w", errMsg, err)
+		return nil, fmt.Errorf("%s :-> %w", errMsg, err)
 	}
 
-	return err
+	return &pymntResponse, nil
 }
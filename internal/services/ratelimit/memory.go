@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often stores drop buckets that have refilled completely.
+const pruneInterval = 10 * time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     Limit
+}
+
+// MemoryStore keeps buckets in process memory. Limits only hold per replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+//nolint:exhaustivestruct
+var _ Store = &MemoryStore{}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*bucket{},
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take implements Store.
+func (m *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	if now.Sub(m.lastPrune) > pruneInterval {
+		m.prune(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Count), updatedAt: now}
+		m.buckets[key] = b
+	}
+
+	b.limit = limit
+	b.tokens = math.Min(float64(limit.Count), b.tokens+now.Sub(b.updatedAt).Seconds()*limit.rate())
+	b.updatedAt = now
+
+	if b.tokens < 1 {
+		return result(limit, b.tokens, false), nil
+	}
+
+	b.tokens--
+
+	return result(limit, b.tokens, true), nil
+}
+
+// prune drops buckets that would be full by now; they are recreated full on the next request.
+func (m *MemoryStore) prune(now time.Time) {
+	for key, b := range m.buckets {
+		if b.tokens+now.Sub(b.updatedAt).Seconds()*b.limit.rate() >= float64(b.limit.Count) {
+			delete(m.buckets, key)
+		}
+	}
+
+	m.lastPrune = now
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/rs/zerolog/log"
+)
+
+// PostgresStore keeps buckets in the rate_limits table so that limits hold across replicas.
+type PostgresStore struct {
+	repo persistence.Repository
+	// idle is how long a bucket is kept after its last request.
+	idle      time.Duration
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+//nolint:exhaustivestruct
+var _ Store = &PostgresStore{}
+
+// NewPostgresStore returns a Store backed by repo. Buckets unused for idle are deleted; idle should be at least the
+// longest configured period.
+func NewPostgresStore(repo persistence.Repository, idle time.Duration) *PostgresStore {
+	return &PostgresStore{
+		repo:      repo,
+		idle:      idle,
+		lastPrune: time.Now(),
+	}
+}
+
+// Take implements Store.
+func (p *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	p.pruneIfDue(ctx)
+
+	tokens, allowed, err := p.repo.TakeRateLimitToken(ctx, key, limit.Count, limit.rate())
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(limit, tokens, allowed), nil
+}
+
+func (p *PostgresStore) pruneIfDue(ctx context.Context) {
+	p.mu.Lock()
+	due := time.Since(p.lastPrune) > pruneInterval
+	if due {
+		p.lastPrune = time.Now()
+	}
+	p.mu.Unlock()
+
+	if !due {
+		return
+	}
+
+	if err := p.repo.PruneRateLimits(ctx, p.idle); err != nil {
+		log.Error().Err(err).Msg("pruning rate limit buckets")
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate mockgen -source ./ratelimit.go -destination mocks/ratelimit.mock.go -package mocks
+
+var ErrInvalidLimit = errors.New("rate limits must look like group=count/period, e.g. bids=30/1m")
+
+// Limit is a token bucket holding up to Count tokens that refills at Count tokens per Period.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+// rate is the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Count) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// RetryAfter is how long a refused caller has to wait for the next token.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps token buckets.
+type Store interface {
+	// Take takes a token from the bucket key, creating a full bucket if there is none.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result describes a bucket holding tokens after a request was allowed or refused.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.rate()
+
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(limit.Count) - tokens) / rate),
+	}
+
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+// ParseLimits parses a comma or semicolon separated list of group=count/period limits, e.g. "default=300/1m,bids=30/1m".
+func ParseLimits(limits string) (map[string]Limit, error) {
+	parsed := map[string]Limit{}
+
+	for _, entry := range strings.FieldsFunc(limits, func(r rune) bool { return r == ',' || r == ';' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, spec, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLimit, entry)
+		}
+
+		count, period, found := strings.Cut(spec, "/")
+		if !found {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLimit, entry)
+		}
+
+		n, err := strconv.Atoi(count)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLimit, entry)
+		}
+
+		d, err := time.ParseDuration(period)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLimit, entry)
+		}
+
+		parsed[strings.TrimSpace(group)] = Limit{Count: n, Period: d}
+	}
+
+	return parsed, nil
+}
+
+// LongestPeriod returns the longest period of all limits.
+func LongestPeriod(limits ...map[string]Limit) time.Duration {
+	var longest time.Duration
+
+	for _, group := range limits {
+		for _, limit := range group {
+			if limit.Period > longest {
+				longest = limit.Period
+			}
+		}
+	}
+
+	return longest
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Count: 2, Period: time.Minute}
+
+	for i := 1; i >= 0; i-- {
+		res, err := store.Take(context.Background(), "bids:ip:1.2.3.4", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := store.Take(context.Background(), "bids:ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 30*time.Second, res.RetryAfter)
+	assert.Equal(t, time.Minute, res.Reset)
+
+	res, err = store.Take(context.Background(), "bids:ip:5.6.7.8", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "buckets are per key")
+
+	now = now.Add(30 * time.Second)
+
+	res, err = store.Take(context.Background(), "bids:ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "a token is refilled every 30s")
+	assert.Equal(t, 0, res.Remaining)
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("default=300/1m; bids=30/1m,listings=20/1h")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"default":  {Count: 300, Period: time.Minute},
+		"bids":     {Count: 30, Period: time.Minute},
+		"listings": {Count: 20, Period: time.Hour},
+	}, limits)
+	assert.Equal(t, time.Hour, LongestPeriod(limits))
+
+	for _, invalid := range []string{"bids", "bids=30", "bids=0/1m", "bids=30/forever"} {
+		_, err := ParseLimits(invalid)
+		assert.ErrorIs(t, err, ErrInvalidLimit, invalid)
+	}
+}
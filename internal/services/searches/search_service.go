@@ -0,0 +1,194 @@
+package searches
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"time"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/sms"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/vin"
+	"github.com/rs/zerolog"
+)
+
+//go:generate mockgen -source ./search_service.go -destination mocks/search_service.mock.go -package mocks
+
+// ErrAlertsNotConfigured is returned by NewService when users can not be alerted of new listings.
+var ErrAlertsNotConfigured = errors.New("a mailer and an sms sender are required to send search alerts")
+
+// dailyDigest is how long daily alerts are gathered before they are sent.
+const dailyDigest = 24 * time.Hour
+
+// deadlineLayout formats auction deadlines in alerts.
+const deadlineLayout = "2 January at 15:04"
+
+var logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+
+// Service manages the saved searches of users.
+type Service interface {
+	ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error)
+	CreateSavedSearch(ctx context.Context, userID string, req models.SavedSearchRequest) (*models.SavedSearch, error)
+	UpdateSavedSearch(ctx context.Context, userID string, searchID string, req models.SavedSearchRequest) (*models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, userID string, searchID string) error
+}
+
+// Alerts configures the alerts of new listings matching saved searches.
+type Alerts struct {
+	// Mailer and SMS alert users, by email or by SMS for users without an email address.
+	Mailer services.Mailer
+	SMS    sms.Sender
+}
+
+type ServiceImpl struct {
+	repo   persistence.Repository
+	alerts Alerts
+}
+
+//nolint:exhaustivestruct
+var _ Service = &ServiceImpl{}
+
+func NewService(repo persistence.Repository, alerts Alerts) (*ServiceImpl, error) {
+	if alerts.Mailer == nil || alerts.SMS == nil {
+		return nil, ErrAlertsNotConfigured
+	}
+
+	return &ServiceImpl{repo: repo, alerts: alerts}, nil
+}
+
+// ListSavedSearches implements Service.
+func (s *ServiceImpl) ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error) {
+	return s.repo.ListSavedSearches(ctx, userID)
+}
+
+// CreateSavedSearch implements Service. Only cars listed from then on are alerted.
+func (s *ServiceImpl) CreateSavedSearch(ctx context.Context, userID string, req models.SavedSearchRequest) (*models.SavedSearch, error) {
+	search, err := savedSearch(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateSavedSearch(ctx, search)
+}
+
+// UpdateSavedSearch implements Service. Alerts already queued are sent at the frequency they were queued with.
+func (s *ServiceImpl) UpdateSavedSearch(ctx context.Context, userID string, searchID string, req models.SavedSearchRequest) (*models.SavedSearch, error) {
+	search, err := savedSearch(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	search.ID = searchID
+
+	return s.repo.UpdateSavedSearch(ctx, search)
+}
+
+// DeleteSavedSearch implements Service.
+func (s *ServiceImpl) DeleteSavedSearch(ctx context.Context, userID string, searchID string) error {
+	_, err := s.repo.DeleteSavedSearch(ctx, userID, searchID)
+
+	return err
+}
+
+// savedSearch validates req and normalizes the VIN it filters on, as GET /cars does.
+func savedSearch(userID string, req models.SavedSearchRequest) (models.SavedSearch, error) {
+	search, err := req.SavedSearch(userID)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	if search.Filters.VIN != "" {
+		search.Filters.VIN, err = vin.Parse(search.Filters.VIN)
+		if err != nil {
+			return models.SavedSearch{}, err
+		}
+	}
+
+	return search, nil
+}
+
+// SendAlerts sends the instant alerts queued since it was last called, and the daily alerts of the users whose oldest
+// pending daily alert is a day old. Each user gets a single message listing their new matches. It is meant to be
+// called periodically; every alert is sent once, however many replicas call it.
+func (s *ServiceImpl) SendAlerts(ctx context.Context) error {
+	instant, err := s.repo.ClaimSearchAlerts(ctx, models.AlertsInstant, 0)
+	if err != nil {
+		return err
+	}
+
+	s.sendAlerts(ctx, instant)
+
+	daily, err := s.repo.ClaimSearchAlerts(ctx, models.AlertsDaily, dailyDigest)
+	if err != nil {
+		return err
+	}
+
+	s.sendAlerts(ctx, daily)
+
+	return nil
+}
+
+// sendAlerts sends one message to each user of alerts, which are ordered by user. Failures are logged.
+func (s *ServiceImpl) sendAlerts(ctx context.Context, alerts []models.SearchAlert) {
+	for start := 0; start < len(alerts); {
+		end := start + 1
+		for end < len(alerts) && alerts[end].UserID == alerts[start].UserID {
+			end++
+		}
+
+		s.alertUser(ctx, alerts[start].UserID, alerts[start:end])
+
+		start = end
+	}
+}
+
+// alertUser sends the new listings of alerts to userID by email, or by SMS to users without an email address.
+func (s *ServiceImpl) alertUser(ctx context.Context, userID string, alerts []models.SearchAlert) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Error().Err(err).Str("userID", userID).Msg("reading user to alert")
+
+		return
+	}
+
+	subject := fmt.Sprintf("A new car matches your search %q", alerts[0].SearchName)
+	if len(alerts) > 1 {
+		subject = fmt.Sprintf("%d new cars match your saved searches", len(alerts))
+	}
+
+	lines := make([]string, len(alerts))
+	for i, alert := range alerts {
+		lines[i] = fmt.Sprintf("%s, from %d XAF, bidding ends %s (%s)", alert.CarName, alert.BidingPrice,
+			alert.BidExpirationTime.In(models.DefaultLocation).Format(deadlineLayout), alert.SearchName)
+	}
+
+	switch {
+	case user.Email != "":
+		items := make([]string, len(lines))
+		for i, line := range lines {
+			items[i] = "<li>" + html.EscapeString(line) + "</li>"
+		}
+
+		err = s.alerts.Mailer.SendEmail(ctx, services.Email{
+			To:      []string{user.Email},
+			Subject: subject,
+			HTMLBody: fmt.Sprintf(`<p>Hello %s,</p><p>New cars match your saved searches:</p><ul>%s</ul>`,
+				html.EscapeString(user.UserName), strings.Join(items, "")),
+			TextBody: fmt.Sprintf("Hello %s,\n\nNew cars match your saved searches:\n\n- %s\n", user.UserName,
+				strings.Join(lines, "\n- ")),
+		})
+	case user.PhoneNumber != "":
+		err = s.alerts.SMS.SendSMS(ctx, user.PhoneNumber, "Sigma Auto: "+subject+": "+strings.Join(lines, "; "))
+	default:
+		return
+	}
+
+	if err != nil {
+		logger.Error().Err(err).Str("userID", userID).Int("alerts", len(alerts)).Msg("sending search alerts")
+	}
+}
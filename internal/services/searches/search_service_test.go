@@ -0,0 +1,157 @@
+package searches
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type queuedAlert struct {
+	models.SearchAlert
+	frequency models.AlertFrequency
+	queuedAt  time.Time
+	sent      bool
+}
+
+// fakeRepo keeps users and queued alerts in memory and claims alerts with the rules of RepositoryPg.
+// Methods the tests do not use panic on the nil embedded Repository.
+type fakeRepo struct {
+	persistence.Repository
+
+	users  map[string]*models.Users
+	alerts []*queuedAlert
+}
+
+func (r *fakeRepo) queue(userID string, carName string, frequency models.AlertFrequency, age time.Duration) {
+	r.alerts = append(r.alerts, &queuedAlert{
+		SearchAlert: models.SearchAlert{UserID: userID, CarID: carName, SearchName: "Corollas", CarName: carName,
+			BidingPrice: 1000000, BidExpirationTime: models.NewTime(time.Now().Add(72 * time.Hour))},
+		frequency: frequency,
+		queuedAt:  time.Now().Add(-age),
+	})
+}
+
+func (r *fakeRepo) GetUserByID(_ context.Context, userID string) (*models.Users, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *user
+
+	return &found, nil
+}
+
+func (r *fakeRepo) ClaimSearchAlerts(_ context.Context, frequency models.AlertFrequency, age time.Duration) ([]models.SearchAlert, error) {
+	due := map[string]bool{}
+
+	for _, alert := range r.alerts {
+		if !alert.sent && alert.frequency == frequency && !alert.queuedAt.After(time.Now().Add(-age)) {
+			due[alert.UserID] = true
+		}
+	}
+
+	claimed := []models.SearchAlert{}
+
+	for _, userID := range []string{"buyer-1", "buyer-2", "buyer-3"} {
+		for _, alert := range r.alerts {
+			if due[userID] && alert.UserID == userID && !alert.sent && alert.frequency == frequency {
+				alert.sent = true
+				claimed = append(claimed, alert.SearchAlert)
+			}
+		}
+	}
+
+	return claimed, nil
+}
+
+type fakeMailer struct {
+	emails []services.Email
+}
+
+func (m *fakeMailer) SendEmail(_ context.Context, email services.Email) error {
+	m.emails = append(m.emails, email)
+
+	return nil
+}
+
+type fakeSMS struct {
+	messages map[string][]string
+}
+
+func (s *fakeSMS) SendSMS(_ context.Context, to string, message string) error {
+	s.messages[to] = append(s.messages[to], message)
+
+	return nil
+}
+
+func newTestService(t *testing.T) (*ServiceImpl, *fakeRepo, *fakeMailer, *fakeSMS) {
+	t.Helper()
+
+	repo := &fakeRepo{users: map[string]*models.Users{
+		"buyer-1": {User_id: "buyer-1", UserName: "Ada", Email: "ada@example.com", PhoneNumber: "+237650000001"},
+		"buyer-2": {User_id: "buyer-2", UserName: "Bo", PhoneNumber: "+237650000002"},
+		"buyer-3": {User_id: "buyer-3", UserName: "Cy", Email: "cy@example.com"},
+	}}
+	mailer, sender := &fakeMailer{}, &fakeSMS{messages: map[string][]string{}}
+
+	service, err := NewService(repo, Alerts{Mailer: mailer, SMS: sender})
+	require.NoError(t, err)
+
+	return service, repo, mailer, sender
+}
+
+func TestSendAlerts_OneMessagePerUser(t *testing.T) {
+	service, repo, mailer, sender := newTestService(t)
+
+	repo.queue("buyer-1", "Toyota Corolla", models.AlertsInstant, time.Minute)
+	repo.queue("buyer-1", "Toyota Camry", models.AlertsInstant, 0)
+	repo.queue("buyer-2", "Toyota Corolla", models.AlertsInstant, 0)
+	repo.queue("buyer-3", "Toyota Yaris", models.AlertsInstant, 0)
+
+	require.NoError(t, service.SendAlerts(context.Background()))
+
+	require.Len(t, mailer.emails, 2)
+	assert.Equal(t, []string{"ada@example.com"}, mailer.emails[0].To)
+	assert.Equal(t, "2 new cars match your saved searches", mailer.emails[0].Subject)
+	assert.Contains(t, mailer.emails[0].TextBody, "Toyota Corolla")
+	assert.Contains(t, mailer.emails[0].TextBody, "Toyota Camry")
+	assert.Equal(t, []string{"cy@example.com"}, mailer.emails[1].To)
+	assert.Equal(t, `A new car matches your search "Corollas"`, mailer.emails[1].Subject)
+
+	assert.Empty(t, sender.messages["+237650000001"], "users with an email address are not texted")
+	require.Len(t, sender.messages["+237650000002"], 1)
+	assert.Contains(t, sender.messages["+237650000002"][0], "Toyota Corolla")
+
+	require.NoError(t, service.SendAlerts(context.Background()))
+	assert.Len(t, mailer.emails, 2, "alerts are sent once")
+}
+
+func TestSendAlerts_DailyDigest(t *testing.T) {
+	service, repo, mailer, _ := newTestService(t)
+
+	repo.queue("buyer-1", "Toyota Corolla", models.AlertsDaily, 23*time.Hour)
+	repo.queue("buyer-3", "Toyota Yaris", models.AlertsDaily, 25*time.Hour)
+	repo.queue("buyer-3", "Toyota Camry", models.AlertsDaily, time.Hour)
+
+	require.NoError(t, service.SendAlerts(context.Background()))
+
+	require.Len(t, mailer.emails, 1, "daily alerts wait until the oldest is a day old")
+	assert.Equal(t, []string{"cy@example.com"}, mailer.emails[0].To)
+	assert.Equal(t, "2 new cars match your saved searches", mailer.emails[0].Subject,
+		"newer alerts go out with the oldest one")
+
+	repo.alerts[0].queuedAt = time.Now().Add(-dailyDigest)
+
+	require.NoError(t, service.SendAlerts(context.Background()))
+
+	require.Len(t, mailer.emails, 2)
+	assert.Equal(t, []string{"ada@example.com"}, mailer.emails[1].To)
+}
@@ -0,0 +1,243 @@
+package sellers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/audit"
+)
+
+//go:generate mockgen -source ./seller_service.go -destination mocks/seller_service.mock.go -package mocks
+
+// allowedContentTypes are the sniffed content types accepted for KYC documents.
+var allowedContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+// Service manages seller identity and vehicle ownership verification.
+//
+//nolint:interfacebloat
+type Service interface {
+	UploadDocument(ctx context.Context, kind sellermodels.DocumentKind, fileName string, content io.Reader) (*sellermodels.Document, error)
+	SubmitVerification(ctx context.Context) (*sellermodels.Verification, error)
+	GetVerificationStatus(ctx context.Context) (*sellermodels.SellerVerificationStatus, error)
+	ListVerifications(ctx context.Context, status string) ([]sellermodels.Verification, error)
+	GetVerification(ctx context.Context, verificationID string) (*sellermodels.Verification, error)
+	GetDocument(ctx context.Context, documentID string) (*sellermodels.Document, []byte, error)
+	ApproveVerification(ctx context.Context, verificationID string) (*sellermodels.Verification, error)
+	RejectVerification(ctx context.Context, verificationID string, reason string) (*sellermodels.Verification, error)
+}
+
+type ServiceImpl struct {
+	repo    persistence.Repository
+	auditor audit.Recorder
+}
+
+//nolint:exhaustivestruct
+var _ Service = &ServiceImpl{}
+
+func NewService(repo persistence.Repository, auditor audit.Recorder) (*ServiceImpl, error) {
+	return &ServiceImpl{
+		repo:    repo,
+		auditor: auditor,
+	}, nil
+}
+
+// UploadDocument implements Service. Documents can not be added while a request is being reviewed or once the seller
+// is verified.
+func (s *ServiceImpl) UploadDocument(ctx context.Context, kind sellermodels.DocumentKind, fileName string, content io.Reader) (*sellermodels.Document, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	if !kind.Valid() {
+		return nil, sellermodels.ErrInvalidDocumentKind
+	}
+
+	if err := s.checkCanSubmit(ctx, principal.UserID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, sellermodels.MaxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > sellermodels.MaxDocumentSize {
+		return nil, sellermodels.ErrDocumentTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedContentTypes[contentType] {
+		return nil, sellermodels.ErrUnsupportedDocumentType
+	}
+
+	doc, err := s.repo.CreateKYCDocument(ctx, sellermodels.Document{
+		UserID:      principal.UserID,
+		Kind:        kind,
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+	}, data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionKYCDocumentUploaded,
+		EntityType: auditmodels.EntityKYCDocument,
+		EntityID:   doc.ID,
+		After:      doc,
+	})
+
+	return doc, nil
+}
+
+// SubmitVerification implements Service by sending the uploaded documents for review. At least one identity document
+// and one vehicle ownership document are required.
+func (s *ServiceImpl) SubmitVerification(ctx context.Context) (*sellermodels.Verification, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	if err := s.checkCanSubmit(ctx, principal.UserID); err != nil {
+		return nil, err
+	}
+
+	docs, err := s.repo.ListUnsubmittedKYCDocuments(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasIdentity, hasOwnership := false, false
+
+	for _, doc := range docs {
+		hasIdentity = hasIdentity || doc.Kind.IsIdentity()
+		hasOwnership = hasOwnership || doc.Kind.IsOwnership()
+	}
+
+	if !hasIdentity || !hasOwnership {
+		return nil, sellermodels.ErrMissingDocuments
+	}
+
+	verification, err := s.repo.SubmitSellerVerification(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	verification.Documents = docs
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionVerificationSubmitted,
+		EntityType: auditmodels.EntitySellerVerification,
+		EntityID:   verification.ID,
+		After:      verification,
+	})
+
+	return verification, nil
+}
+
+// GetVerificationStatus implements Service for the authenticated user.
+func (s *ServiceImpl) GetVerificationStatus(ctx context.Context) (*sellermodels.SellerVerificationStatus, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	user, err := s.repo.GetUserByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.repo.GetLatestSellerVerification(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.repo.ListUnsubmittedKYCDocuments(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sellermodels.SellerVerificationStatus{
+		Status:    sellermodels.Status(user.SellerStatus),
+		Latest:    latest,
+		Documents: docs,
+	}, nil
+}
+
+// ListVerifications implements Service. The review queue is listed with status pending.
+func (s *ServiceImpl) ListVerifications(ctx context.Context, status string) ([]sellermodels.Verification, error) {
+	return s.repo.ListSellerVerifications(ctx, status)
+}
+
+// GetVerification implements Service. The documents of the request are included without their content.
+func (s *ServiceImpl) GetVerification(ctx context.Context, verificationID string) (*sellermodels.Verification, error) {
+	verification, err := s.repo.GetSellerVerification(ctx, verificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	verification.Documents, err = s.repo.ListVerificationDocuments(ctx, verificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return verification, nil
+}
+
+// GetDocument implements Service.
+func (s *ServiceImpl) GetDocument(ctx context.Context, documentID string) (*sellermodels.Document, []byte, error) {
+	return s.repo.GetKYCDocumentContent(ctx, documentID)
+}
+
+// ApproveVerification implements Service. The seller is marked verified, and promoted from buyer to seller; the new
+// role is picked up on their next token refresh.
+func (s *ServiceImpl) ApproveVerification(ctx context.Context, verificationID string) (*sellermodels.Verification, error) {
+	return s.review(ctx, verificationID, true, "")
+}
+
+// RejectVerification implements Service. The seller can upload new documents and submit again.
+func (s *ServiceImpl) RejectVerification(ctx context.Context, verificationID string, reason string) (*sellermodels.Verification, error) {
+	if reason == "" {
+		return nil, sellermodels.ErrReasonRequired
+	}
+
+	return s.review(ctx, verificationID, false, reason)
+}
+
+// review records the decision of the admin in ctx. The repository audits it.
+func (s *ServiceImpl) review(ctx context.Context, verificationID string, approve bool, reason string) (*sellermodels.Verification, error) {
+	principal, ok := authmodels.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, authmodels.ErrUnauthenticated
+	}
+
+	return s.repo.ReviewSellerVerification(ctx, verificationID, principal.UserID, approve, reason)
+}
+
+// checkCanSubmit refuses changes while a request is being reviewed and once the seller is verified.
+func (s *ServiceImpl) checkCanSubmit(ctx context.Context, userID string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	switch sellermodels.Status(user.SellerStatus) {
+	case sellermodels.StatusPending:
+		return sellermodels.ErrVerificationPending
+	case sellermodels.StatusVerified:
+		return sellermodels.ErrAlreadyVerified
+	default:
+		return nil
+	}
+}
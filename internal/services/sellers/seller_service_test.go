@@ -0,0 +1,208 @@
+package sellers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	authmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/auth"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	sellermodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/sellers"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepo keeps users, documents and verification requests in memory, with the review rules of RepositoryPg.
+// Methods the tests do not use panic on the nil embedded Repository.
+type fakeRepo struct {
+	persistence.Repository
+
+	users         map[string]*models.Users
+	documents     []sellermodels.Document
+	verifications map[string]*sellermodels.Verification
+}
+
+func newFakeRepo(userIDs ...string) *fakeRepo {
+	repo := &fakeRepo{users: map[string]*models.Users{}, verifications: map[string]*sellermodels.Verification{}}
+	for _, id := range userIDs {
+		repo.users[id] = &models.Users{User_id: id, SellerStatus: string(sellermodels.StatusUnverified)}
+	}
+
+	return repo
+}
+
+func (r *fakeRepo) GetUserByID(_ context.Context, userID string) (*models.Users, error) {
+	user := *r.users[userID]
+
+	return &user, nil
+}
+
+func (r *fakeRepo) CreateKYCDocument(_ context.Context, doc sellermodels.Document, content []byte) (*sellermodels.Document, error) {
+	doc.ID, doc.Size = fmt.Sprintf("doc-%d", len(r.documents)+1), len(content)
+	r.documents = append(r.documents, doc)
+
+	return &doc, nil
+}
+
+func (r *fakeRepo) ListUnsubmittedKYCDocuments(_ context.Context, userID string) ([]sellermodels.Document, error) {
+	docs := []sellermodels.Document{}
+
+	for _, doc := range r.documents {
+		if doc.UserID == userID && doc.VerificationID == nil {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+func (r *fakeRepo) SubmitSellerVerification(_ context.Context, userID string) (*sellermodels.Verification, error) {
+	id := fmt.Sprintf("verification-%d", len(r.verifications)+1)
+	r.verifications[id] = &sellermodels.Verification{ID: id, UserID: userID, Status: sellermodels.VerificationPending}
+	r.users[userID].SellerStatus = string(sellermodels.StatusPending)
+
+	for i := range r.documents {
+		if r.documents[i].UserID == userID && r.documents[i].VerificationID == nil {
+			r.documents[i].VerificationID = &id
+		}
+	}
+
+	verification := *r.verifications[id]
+
+	return &verification, nil
+}
+
+func (r *fakeRepo) GetSellerVerification(_ context.Context, verificationID string) (*sellermodels.Verification, error) {
+	found, ok := r.verifications[verificationID]
+	if !ok {
+		return nil, sellermodels.ErrVerificationNotFound
+	}
+
+	verification := *found
+
+	return &verification, nil
+}
+
+func (r *fakeRepo) ReviewSellerVerification(_ context.Context, verificationID string, reviewerID string, approve bool,
+	reason string,
+) (*sellermodels.Verification, error) {
+	verification, ok := r.verifications[verificationID]
+	if !ok {
+		return nil, sellermodels.ErrVerificationNotFound
+	}
+
+	if verification.Status != sellermodels.VerificationPending {
+		return nil, sellermodels.ErrVerificationNotPending
+	}
+
+	verification.Status, r.users[verification.UserID].SellerStatus = sellermodels.VerificationRejected, string(sellermodels.StatusRejected)
+	if approve {
+		verification.Status, r.users[verification.UserID].SellerStatus = sellermodels.VerificationApproved, string(sellermodels.StatusVerified)
+	}
+
+	verification.ReviewedBy, verification.Reason = &reviewerID, &reason
+	reviewed := *verification
+
+	return &reviewed, nil
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) Record(context.Context, auditmodels.Change) {}
+
+var pdf = []byte("%PDF-1.4\n%âãÏÓ\n")
+
+func asUser(userID string, role authmodels.Role) context.Context {
+	return authmodels.WithPrincipal(context.Background(), &authmodels.Principal{UserID: userID, Role: role})
+}
+
+func upload(t *testing.T, service *ServiceImpl, ctx context.Context, kinds ...sellermodels.DocumentKind) {
+	t.Helper()
+
+	for _, kind := range kinds {
+		_, err := service.UploadDocument(ctx, kind, "scan.pdf", bytes.NewReader(pdf))
+		require.NoError(t, err)
+	}
+}
+
+func TestSubmitVerification(t *testing.T) {
+	repo := newFakeRepo("seller-1")
+	service, err := NewService(repo, nopRecorder{})
+	require.NoError(t, err)
+
+	ctx := asUser("seller-1", authmodels.RoleBuyer)
+
+	upload(t, service, ctx, sellermodels.DocumentPassport)
+
+	_, err = service.SubmitVerification(ctx)
+	assert.ErrorIs(t, err, sellermodels.ErrMissingDocuments, "an ownership document is required")
+
+	upload(t, service, ctx, sellermodels.DocumentVehicleRegistration)
+
+	verification, err := service.SubmitVerification(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, sellermodels.VerificationPending, verification.Status)
+	assert.Len(t, verification.Documents, 2)
+	assert.Equal(t, string(sellermodels.StatusPending), repo.users["seller-1"].SellerStatus)
+
+	_, err = service.SubmitVerification(ctx)
+	assert.ErrorIs(t, err, sellermodels.ErrVerificationPending, "a request is already being reviewed")
+
+	_, err = service.UploadDocument(ctx, sellermodels.DocumentNationalID, "id.pdf", bytes.NewReader(pdf))
+	assert.ErrorIs(t, err, sellermodels.ErrVerificationPending)
+}
+
+func TestReviewVerification(t *testing.T) {
+	repo := newFakeRepo("seller-1", "seller-2")
+	service, err := NewService(repo, nopRecorder{})
+	require.NoError(t, err)
+
+	admin := asUser("admin-1", authmodels.RoleAdmin)
+	submit := func(userID string) *sellermodels.Verification {
+		ctx := asUser(userID, authmodels.RoleBuyer)
+		upload(t, service, ctx, sellermodels.DocumentNationalID, sellermodels.DocumentOwnershipCertificate)
+
+		verification, err := service.SubmitVerification(ctx)
+		require.NoError(t, err)
+
+		return verification
+	}
+
+	t.Run("approve", func(t *testing.T) {
+		verification := submit("seller-1")
+
+		approved, err := service.ApproveVerification(admin, verification.ID)
+		require.NoError(t, err)
+		assert.Equal(t, sellermodels.VerificationApproved, approved.Status)
+		assert.Equal(t, string(sellermodels.StatusVerified), repo.users["seller-1"].SellerStatus)
+
+		_, err = service.RejectVerification(admin, verification.ID, "blurry")
+		assert.ErrorIs(t, err, sellermodels.ErrVerificationNotPending)
+
+		_, err = service.SubmitVerification(asUser("seller-1", authmodels.RoleSeller))
+		assert.ErrorIs(t, err, sellermodels.ErrAlreadyVerified)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		verification := submit("seller-2")
+
+		_, err := service.RejectVerification(admin, verification.ID, "")
+		assert.ErrorIs(t, err, sellermodels.ErrReasonRequired)
+
+		rejected, err := service.RejectVerification(admin, verification.ID, "blurry")
+		require.NoError(t, err)
+		assert.Equal(t, sellermodels.VerificationRejected, rejected.Status)
+		assert.Equal(t, string(sellermodels.StatusRejected), repo.users["seller-2"].SellerStatus)
+
+		resubmitted := submit("seller-2")
+		assert.NotEqual(t, verification.ID, resubmitted.ID, "rejected sellers can submit again")
+	})
+
+	t.Run("unknown request", func(t *testing.T) {
+		_, err := service.ApproveVerification(admin, "not-a-uuid")
+		assert.ErrorIs(t, err, sellermodels.ErrVerificationNotFound)
+	})
+}
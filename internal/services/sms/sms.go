@@ -0,0 +1,72 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+
+var ErrNoOutboxFile = errors.New("the file sms sender needs a file path")
+
+//go:generate mockgen -source ./sms.go -destination mocks/sms.mock.go -package mocks
+
+// Sender sends text messages to E.164 phone numbers.
+type Sender interface {
+	SendSMS(ctx context.Context, to string, message string) error
+}
+
+// LogSender logs the recipients of messages instead of sending them. It is meant for local development. Messages are
+// not logged: they carry one-time login codes.
+type LogSender struct{}
+
+//nolint:exhaustivestruct
+var _ Sender = LogSender{}
+
+func (LogSender) SendSMS(_ context.Context, to string, message string) error {
+	logger.Info().Str("to", to).Msg("sms not sent")
+
+	return nil
+}
+
+// FileSender appends messages to a file instead of sending them, so that tests and scripts can read the codes back.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+//nolint:exhaustivestruct
+var _ Sender = &FileSender{}
+
+func NewFileSender(path string) (*FileSender, error) {
+	if path == "" {
+		return nil, ErrNoOutboxFile
+	}
+
+	return &FileSender{path: path}, nil
+}
+
+func (f *FileSender) SendSMS(_ context.Context, to string, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	//nolint:gomnd
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), to, message); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
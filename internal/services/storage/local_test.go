@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+
+	local, err := NewLocal(t.TempDir(), "http://localhost:9080/photos/", "0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+
+	now := time.Now()
+	local.now = func() time.Time { return now }
+
+	require.NoError(t, local.Put(ctx, "cars/c1/p1.jpg", "image/jpeg", []byte("jpeg")))
+
+	signed, err := local.SignedURL(ctx, "cars/c1/p1.jpg", time.Hour)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(signed, "http://localhost:9080/photos/cars/c1/p1.jpg?"))
+
+	parsed, err := url.Parse(signed)
+	require.NoError(t, err)
+
+	expires, signature := parsed.Query().Get("expires"), parsed.Query().Get("signature")
+
+	content, contentType, err := local.Open(ctx, "cars/c1/p1.jpg", expires, signature)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", string(content))
+	assert.Equal(t, "image/jpeg", contentType)
+
+	_, _, err = local.Open(ctx, "cars/c1/p2.jpg", expires, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "signatures are bound to their key")
+
+	_, _, err = local.Open(ctx, "cars/c1/p1.jpg", expires+"0", signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "the expiry is signed")
+
+	local.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	_, _, err = local.Open(ctx, "cars/c1/p1.jpg", expires, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "expired urls are refused")
+
+	require.NoError(t, local.Delete(ctx, "cars/c1/p1.jpg"))
+	require.NoError(t, local.Delete(ctx, "cars/c1/p1.jpg"), "deleting a missing blob is not an error")
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "cars/../../secret", "cars//p1.jpg", `cars\p1.jpg`} {
+		assert.ErrorIs(t, local.Put(ctx, key, "image/jpeg", nil), ErrInvalidKey, key)
+	}
+
+	_, err = NewLocal(t.TempDir(), "", "short")
+	assert.ErrorIs(t, err, ErrWeakURLSecret)
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var ErrNoBucket = errors.New("s3 storage needs a bucket")
+
+// S3Config configures storage in an S3 compatible bucket.
+type S3Config struct {
+	Bucket string
+	Region string
+	// Endpoint is set for S3 compatible services other than AWS.
+	Endpoint string
+	// PathStyle addresses the bucket in the URL path instead of the host name, as most S3 compatible services require.
+	PathStyle bool
+}
+
+// S3 keeps blobs in an S3 compatible bucket. Its signed URLs are presigned GET requests to the bucket.
+type S3 struct {
+	client *s3.S3
+	bucket string
+}
+
+//nolint:exhaustivestruct
+var _ Storage = &S3{}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, ErrNoBucket
+	}
+
+	awsConfig := &aws.Config{
+		Region:           aws.String(cfg.Region),
+		S3ForcePathStyle: aws.Bool(cfg.PathStyle),
+	}
+
+	if cfg.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(cfg.Endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3{
+		client: s3.New(sess),
+		bucket: cfg.Bucket,
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, contentType string, content []byte) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String(contentType),
+	})
+
+	return err
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
+
+func (s *S3) SignedURL(_ context.Context, key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+
+	return req.Presign(ttl)
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("file not found")
+	// ErrInvalidSignature is returned for signed URLs that have been tampered with or have expired.
+	ErrInvalidSignature = errors.New("the link is invalid or has expired")
+	ErrInvalidKey       = errors.New("invalid storage key")
+)
+
+//go:generate mockgen -source ./storage.go -destination mocks/storage.mock.go -package mocks
+
+// Storage keeps blobs under slash separated keys and hands out time limited URLs to read them.
+type Storage interface {
+	Put(ctx context.Context, key string, contentType string, content []byte) error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that reads the blob key until ttl has passed.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Server is implemented by the backends whose signed URLs point at this API rather than at the backend itself.
+type Server interface {
+	// Open checks the expiry and signature of a signed URL and returns the blob and its content type.
+	Open(ctx context.Context, key string, expires string, signature string) ([]byte, string, error)
+}
+
+// validKey reports whether key is a relative slash separated path that stays below the storage root.
+func validKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && !strings.Contains(key, "\\") && path.Clean(key) == key &&
+		key != ".." && !strings.HasPrefix(key, "../")
+}
@@ -1,112 +1,124 @@
 package services
 
 import (
-	"fmt"
+	"context"
+	"os"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/rs/zerolog"
 )
 
-const (
-	// Replace sender@example.com with your "From" address.
-	// This address must be verified with Amazon SES.
-	Sender = "namkatcedrickjumtock3@gmail.com"
-	// Replace recipient@example.com with a "To" address. If your account
-	// is still in the sandbox, this address must be verified.
-	Recipient = "cedrickjumtock@gmail.com"
+var logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
 
-	// Specify a configuration set. To use a configuration.
-	// set, comment the next line and line 92.
-	// ConfigurationSet = "ConfigSet".
+//go:generate mockgen -source ./utils.go -destination mocks/utils.mock.go -package mocks
 
-	// The subject line for the email.
-	Subject = "Amazon SES Test (AWS SDK for Go)"
+// The character encoding for the email.
+const CharSet = "UTF-8"
 
-	// The HTML body for the email.
-	HTMLBody = "<h1>Amazon SES Test Email (AWS SDK for Go) From Cliqkets.com</h1><p>This email was sent with " +
-		"<a href='https://aws.amazon.com/ses/'>Amazon SES</a> using the " +
-		"<a href='https://aws.amazon.com/sdk-for-go/'>AWS SDK for Go</a>.</p>"
+// Email is a message to one or more recipients.
+type Email struct {
+	To       []string
+	Subject  string
+	HTMLBody string
+	// TextBody is shown by non-HTML email clients.
+	TextBody string
+}
 
-	// The email body for recipients with non-HTML email clients.
-	TextBody = "This email was sent with Amazon SES using the AWS SDK for Go."
+// Mailer sends transactional emails.
+type Mailer interface {
+	SendEmail(ctx context.Context, email Email) error
+}
 
-	// The character encoding for the email.
-	CharSet = "UTF-8"
-)
+// SESMailer sends emails through Amazon SES.
+type SESMailer struct {
+	svc *ses.SES
+	// sender is the "From" address. It must be verified with Amazon SES.
+	sender string
+	// configurationSet is optional.
+	configurationSet string
+}
 
-//nolint:funlen
-func SendEmail() (bool, error) {
-	// Create a new session in the us-west-2 region.
-	// Replace us-west-2 with the AWS Region you're using for Amazon SES.
+//nolint:exhaustivestruct
+var _ Mailer = &SESMailer{}
+
+// NewSESMailer returns a Mailer that sends from sender through SES in region.
+func NewSESMailer(region string, sender string, configurationSet string) (*SESMailer, error) {
 	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-east-1"),
+		Region: aws.String(region),
 	},
 	)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	// create an SES session.
-	svc := ses.New(sess)
 
+	return &SESMailer{
+		svc:              ses.New(sess),
+		sender:           sender,
+		configurationSet: configurationSet,
+	}, nil
+}
+
+func (m *SESMailer) SendEmail(ctx context.Context, email Email) error {
 	// Assemble the email.
 	input := &ses.SendEmailInput{
 		Destination: &ses.Destination{
 			CcAddresses: []*string{},
-			ToAddresses: []*string{
-				aws.String(Recipient),
-			},
+			ToAddresses: aws.StringSlice(email.To),
 		},
 		Message: &ses.Message{
 			Body: &ses.Body{
 				Html: &ses.Content{
 					Charset: aws.String(CharSet),
-					Data:    aws.String(HTMLBody),
+					Data:    aws.String(email.HTMLBody),
 				},
 				Text: &ses.Content{
 					Charset: aws.String(CharSet),
-					Data:    aws.String(TextBody),
+					Data:    aws.String(email.TextBody),
 				},
 			},
 			Subject: &ses.Content{
 				Charset: aws.String(CharSet),
-				Data:    aws.String(Subject),
+				Data:    aws.String(email.Subject),
 			},
 		},
-		Source: aws.String(Sender),
-		// Uncomment to use a configuration set.
-		// ConfigurationSetName: aws.String(ConfigurationSet),
+		Source: aws.String(m.sender),
+	}
+
+	if m.configurationSet != "" {
+		input.ConfigurationSetName = aws.String(m.configurationSet)
 	}
 
 	// Attempt to send the email.
-	result, err := svc.SendEmail(input)
-	// Display error messages if they occur.
+	result, err := m.svc.SendEmailWithContext(ctx, input)
 	if err != nil {
 		//nolint:errorlint
 		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case ses.ErrCodeMessageRejected:
-				// logger.Error().Str(ses.ErrCodeMessageRejected, aerr.Error()).Str("Email could not be sent", aerr.Message())
-			case ses.ErrCodeMailFromDomainNotVerifiedException:
-				// logger.Error().Str(ses.ErrCodeMailFromDomainNotVerifiedException, aerr.Error()).Str("Email could not be sent", aerr.Message())
-			case ses.ErrCodeConfigurationSetDoesNotExistException:
-				// logger.Error().Str(ses.ErrCodeConfigurationSetDoesNotExistException, aerr.Error()).Str("Error Occure while sending the Email", aerr.Message())
-			default:
-				// logger.Error().Str("Error occure while sending the Email", aerr.Error())
-			}
+			logger.Error().Str("code", aerr.Code()).Msgf("email could not be sent: %s", aerr.Message())
 		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			// logger.Error().Str("Error occure while sending the Email", aerr.Error())
+			logger.Error().Msgf("error occurred while sending the email: %v", err)
 		}
 
-		return false, err
+		return err
 	}
 
-	// logger.Info().Str("Email Sent to address: ", Recipient)
-	// logger.Debug().Str("Email process results", fmt.Sprint(result))
-	fmt.Sprint(result)
+	logger.Debug().Str("messageID", aws.StringValue(result.MessageId)).Msg("email sent")
+
+	return nil
+}
+
+// LogMailer logs the recipients and subjects of emails instead of sending them. It is meant for local development.
+// Bodies are not logged: they carry verification and password reset links.
+type LogMailer struct{}
+
+//nolint:exhaustivestruct
+var _ Mailer = LogMailer{}
+
+func (LogMailer) SendEmail(_ context.Context, email Email) error {
+	logger.Info().Strs("to", email.To).Str("subject", email.Subject).Msg("email not sent")
 
-	return true, err
+	return nil
 }
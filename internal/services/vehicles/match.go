@@ -0,0 +1,185 @@
+package vehicles
+
+import (
+	"sort"
+	"strings"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// key folds name for matching, so that "Mercedes-Benz", "MERCEDES BENZ" and "mercedesbenz" are the same.
+func key(name string) string {
+	return strings.ReplaceAll(models.Slug(name), "-", "")
+}
+
+// tolerance is the number of typos allowed in a key of n characters. Short names such as "X5" or "206" must match
+// exactly, since a single typo turns them into another model.
+func tolerance(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// names returns the name and the aliases of vehicle.
+func names(vehicle models.Vehicle) []string {
+	return append([]string{vehicle.Name}, vehicle.Aliases...)
+}
+
+// Match returns the entry of vehicles called name, or else the one closest to it within the typo tolerance. Names
+// equally close to several entries do not match.
+func Match(name string, vehicles []models.Vehicle) (models.Vehicle, bool) {
+	vehicle, _, ok := closest(name, vehicles)
+
+	return vehicle, ok
+}
+
+// closest is Match that also returns the number of typos of the match.
+func closest(name string, vehicles []models.Vehicle) (models.Vehicle, int, bool) {
+	k := key(name)
+	if k == "" {
+		return models.Vehicle{}, 0, false
+	}
+
+	best, bestDistance, ambiguous := -1, tolerance(len(k))+1, false
+
+	for i, vehicle := range vehicles {
+		for _, n := range names(vehicle) {
+			d := distance(k, key(n))
+
+			switch {
+			case d < bestDistance:
+				best, bestDistance, ambiguous = i, d, false
+			case d == bestDistance && best != i:
+				ambiguous = true
+			}
+		}
+	}
+
+	if best < 0 || ambiguous {
+		return models.Vehicle{}, 0, false
+	}
+
+	return vehicles[best], bestDistance, true
+}
+
+// matchWords matches the leading run of words that names an entry of vehicles with the fewest typos, preferring
+// longer runs, and returns the entry and the number of words it took. Typos are compared so that the trim in
+// "Corolla LE" is not taken for two typos in "Corolla".
+func matchWords(words []string, vehicles []models.Vehicle) (models.Vehicle, int, bool) {
+	var (
+		best      models.Vehicle
+		bestWords int
+		bestTypos int
+	)
+
+	for n := len(words); n > 0; n-- {
+		vehicle, typos, ok := closest(strings.Join(words[:n], " "), vehicles)
+		if ok && (bestWords == 0 || typos < bestTypos) {
+			best, bestWords, bestTypos = vehicle, n, typos
+		}
+	}
+
+	return best, bestWords, bestWords > 0
+}
+
+// suggest ranks the entries of vehicles for an autocomplete of query: names starting with it, then names containing
+// it, then names starting with it up to the typo tolerance.
+func suggest(query string, vehicles []models.Vehicle, limit int) []models.Vehicle {
+	k := key(query)
+
+	type suggestion struct {
+		vehicle models.Vehicle
+		rank    int
+	}
+
+	suggestions := []suggestion{}
+
+	for _, vehicle := range vehicles {
+		rank := -1
+
+		for _, n := range names(vehicle) {
+			nk := key(n)
+
+			var r int
+
+			switch {
+			case strings.HasPrefix(nk, k):
+				r = 0
+			case strings.Contains(nk, k):
+				r = 1
+			case len(nk) >= len(k) && distance(k, nk[:len(k)]) <= tolerance(len(k)):
+				r = 1 + distance(k, nk[:len(k)])
+			default:
+				continue
+			}
+
+			if rank < 0 || r < rank {
+				rank = r
+			}
+		}
+
+		if rank >= 0 {
+			suggestions = append(suggestions, suggestion{vehicle: vehicle, rank: rank})
+		}
+	}
+
+	// vehicles are sorted by name, so a stable sort keeps suggestions of the same rank by name.
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].rank < suggestions[j].rank })
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	matched := make([]models.Vehicle, len(suggestions))
+	for i, s := range suggestions {
+		matched[i] = s.vehicle
+	}
+
+	return matched
+}
+
+// distance is the optimal string alignment distance between a and b: the number of insertions, deletions,
+// substitutions and transpositions of adjacent characters that turn a into b.
+func distance(a string, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+func min(n int, others ...int) int {
+	for _, o := range others {
+		if o < n {
+			n = o
+		}
+	}
+
+	return n
+}
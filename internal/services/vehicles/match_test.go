@@ -0,0 +1,107 @@
+package vehicles
+
+import (
+	"testing"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	makes := []models.Vehicle{
+		{ID: "mercedes-benz", Name: "Mercedes-Benz", Aliases: []string{"Mercedes"}},
+		{ID: "toyota", Name: "Toyota"},
+		{ID: "volkswagen", Name: "Volkswagen"},
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Toyota", "toyota"},
+		{"TOYOTA", "toyota"},
+		{"toyta", "toyota"},
+		{"otyota", "toyota"},
+		{"mercedes benz", "mercedes-benz"},
+		{"MERCEDES", "mercedes-benz"},
+		{"volkswagon", "volkswagen"},
+		{"volks wagen", "volkswagen"},
+		{"Tata", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Match(tt.name, makes)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got.ID)
+		})
+	}
+
+	peugeots := []models.Vehicle{{ID: "peugeot-206", Name: "206"}, {ID: "peugeot-207", Name: "207"}}
+	_, ok := Match("208", peugeots)
+	assert.False(t, ok, "short names must match exactly")
+
+	_, ok = Match("Colt", []models.Vehicle{{ID: "a", Name: "Cola"}, {ID: "b", Name: "Bolt"}})
+	assert.False(t, ok, "names equally close to two entries are ambiguous")
+}
+
+func TestMatchWords(t *testing.T) {
+	vehicleModels := []models.Vehicle{
+		{ID: "toyota-land-cruiser", Name: "Land Cruiser"},
+		{ID: "toyota-land-cruiser-prado", Name: "Land Cruiser Prado"},
+	}
+
+	got, n, ok := matchWords([]string{"Land", "Cruiser", "Prado", "VX"}, vehicleModels)
+	require.True(t, ok)
+	assert.Equal(t, "toyota-land-cruiser-prado", got.ID)
+	assert.Equal(t, 3, n)
+
+	got, n, ok = matchWords([]string{"landcruiser", "GX"}, vehicleModels)
+	require.True(t, ok)
+	assert.Equal(t, "toyota-land-cruiser", got.ID)
+	assert.Equal(t, 1, n)
+}
+
+func TestSuggest(t *testing.T) {
+	makes := []models.Vehicle{
+		{ID: "land-rover", Name: "Land Rover"},
+		{ID: "lexus", Name: "Lexus"},
+		{ID: "toyota", Name: "Toyota"},
+	}
+
+	ids := func(vehicles []models.Vehicle) []string {
+		ids := []string{}
+		for _, v := range vehicles {
+			ids = append(ids, v.ID)
+		}
+
+		return ids
+	}
+
+	assert.Equal(t, []string{"land-rover", "lexus"}, ids(suggest("l", makes, 10)))
+	assert.Equal(t, []string{"land-rover"}, ids(suggest("rover", makes, 10)))
+	assert.Equal(t, []string{"toyota"}, ids(suggest("toyt", makes, 10)))
+	assert.Equal(t, []string{"land-rover", "lexus"}, ids(suggest("", makes, 2)))
+}
+
+func TestLoadDataset(t *testing.T) {
+	vehicles, err := loadDataset(dataset)
+	require.NoError(t, err)
+
+	seen := map[string]bool{}
+
+	for _, vehicle := range vehicles {
+		if parent, ok := vehicle.Kind.Parent(); ok {
+			assert.True(t, seen[string(parent)+"/"+vehicle.ParentID], "%s %s is listed before its parent", vehicle.Kind, vehicle.ID)
+		}
+
+		assert.False(t, seen[string(vehicle.Kind)+"/"+vehicle.ID], "%s %s is listed twice", vehicle.Kind, vehicle.ID)
+		seen[string(vehicle.Kind)+"/"+vehicle.ID] = true
+	}
+
+	assert.True(t, seen["make/mercedes-benz"])
+	assert.True(t, seen["model/toyota-land-cruiser-prado"])
+	assert.True(t, seen["trim/toyota-corolla-le"])
+}
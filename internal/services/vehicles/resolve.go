@@ -0,0 +1,98 @@
+package vehicles
+
+import (
+	"context"
+	"strings"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+)
+
+// Resolve maps the make, model and trim of car onto the taxonomy. The make is read from Make, or from the leading
+// words of CarName when Make is empty, and the model from CarModel, or from the words of CarName after the make.
+// Matched fields are replaced by their canonical names. Fields that match nothing keep their text and have no id.
+func Resolve(ctx context.Context, repo persistence.Repository, car *models.Cars) error {
+	car.MakeID, car.ModelID, car.TrimID = "", "", ""
+
+	makes, err := repo.ListVehicles(ctx, models.VehicleMake, "")
+	if err != nil {
+		return err
+	}
+
+	var (
+		vehicleMake models.Vehicle
+		ok          bool
+		rest        []string
+	)
+
+	if strings.TrimSpace(car.Make) != "" {
+		vehicleMake, ok = Match(car.Make, makes)
+	} else {
+		words := strings.Fields(car.CarName)
+
+		var n int
+		vehicleMake, n, ok = matchWords(words, makes)
+		rest = words[n:]
+	}
+
+	if !ok {
+		return nil
+	}
+
+	car.MakeID, car.Make = vehicleMake.ID, vehicleMake.Name
+
+	modelWords := strings.Fields(car.CarModel)
+	if len(modelWords) == 0 {
+		modelWords = rest
+	}
+
+	// Sellers often repeat the make in the model: "Toyota Corolla".
+	if _, n, ok := matchWords(modelWords, []models.Vehicle{vehicleMake}); ok && n < len(modelWords) {
+		modelWords = modelWords[n:]
+	}
+
+	vehicleModels, err := repo.ListVehicles(ctx, models.VehicleModel, vehicleMake.ID)
+	if err != nil {
+		return err
+	}
+
+	model, n, ok := matchWords(modelWords, vehicleModels)
+	if !ok {
+		return nil
+	}
+
+	car.ModelID = model.ID
+	leftover := strings.Join(modelWords[n:], " ")
+
+	if leftover == "" || strings.TrimSpace(car.CarModel) == "" {
+		car.CarModel = model.Name
+	}
+
+	trimName := car.Trim
+	if strings.TrimSpace(trimName) == "" {
+		trimName = leftover
+	}
+
+	if strings.TrimSpace(trimName) == "" {
+		return nil
+	}
+
+	trims, err := repo.ListVehicles(ctx, models.VehicleTrim, model.ID)
+	if err != nil {
+		return err
+	}
+
+	trim, ok := Match(trimName, trims)
+	if !ok {
+		return nil
+	}
+
+	// The trim was written after the model: "Corolla LE".
+	if trimName == leftover {
+		car.CarModel = model.Name
+	}
+
+	car.TrimID, car.Trim = trim.ID, trim.Name
+
+	return nil
+}
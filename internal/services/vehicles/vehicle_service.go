@@ -0,0 +1,203 @@
+// Package vehicles maintains the make, model and trim taxonomy that listings are mapped onto.
+package vehicles
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	auditmodels "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/audit"
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/persistence"
+	"github.com/namkatcedrickjumtock/sigma-auto-api/internal/services/audit"
+)
+
+//go:generate mockgen -source ./vehicle_service.go -destination mocks/vehicle_service.mock.go -package mocks
+
+// dataset lists the seeded taxonomy as make,model,trim. Rows without a trim only add the make and the model.
+//
+//go:embed vehicles.csv
+var dataset string
+
+// Service suggests and maintains taxonomy entries.
+type Service interface {
+	Autocomplete(ctx context.Context, query models.VehicleQuery) ([]models.Vehicle, error)
+	CreateVehicle(ctx context.Context, req models.VehicleRequest) (*models.Vehicle, error)
+	MergeVehicles(ctx context.Context, req models.MergeVehiclesRequest) (*models.VehicleMerge, error)
+	RemapCars(ctx context.Context) (*models.VehicleRemap, error)
+	Seed(ctx context.Context) (bool, error)
+}
+
+type ServiceImpl struct {
+	repo    persistence.Repository
+	auditor audit.Recorder
+}
+
+//nolint:exhaustivestruct
+var _ Service = &ServiceImpl{}
+
+func NewService(repo persistence.Repository, auditor audit.Recorder) (*ServiceImpl, error) {
+	return &ServiceImpl{repo: repo, auditor: auditor}, nil
+}
+
+// Autocomplete implements Service. It suggests the trims of query.ModelID, else the models of query.MakeID, else
+// makes.
+func (s *ServiceImpl) Autocomplete(ctx context.Context, query models.VehicleQuery) ([]models.Vehicle, error) {
+	kind, parentID := models.VehicleMake, ""
+
+	switch {
+	case query.ModelID != "":
+		kind, parentID = models.VehicleTrim, query.ModelID
+	case query.MakeID != "":
+		kind, parentID = models.VehicleModel, query.MakeID
+	}
+
+	vehicles, err := s.repo.ListVehicles(ctx, kind, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return suggest(query.Query, vehicles, models.AutocompleteLimit), nil
+}
+
+// CreateVehicle implements Service.
+func (s *ServiceImpl) CreateVehicle(ctx context.Context, req models.VehicleRequest) (*models.Vehicle, error) {
+	vehicle, err := req.Vehicle()
+	if err != nil {
+		return nil, err
+	}
+
+	if parent, ok := vehicle.Kind.Parent(); ok {
+		if _, err := s.repo.GetVehicle(ctx, parent, vehicle.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	created, err := s.repo.CreateVehicle(ctx, vehicle)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionVehicleCreated,
+		EntityType: entityType(created.Kind),
+		EntityID:   created.ID,
+		After:      created,
+	})
+
+	return created, nil
+}
+
+// MergeVehicles implements Service.
+func (s *ServiceImpl) MergeVehicles(ctx context.Context, req models.MergeVehiclesRequest) (*models.VehicleMerge, error) {
+	kind, err := models.ParseVehicleKind(req.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := s.repo.GetVehicle(ctx, kind, req.FromID)
+	if err != nil {
+		return nil, err
+	}
+
+	merge, err := s.repo.MergeVehicles(ctx, kind, req.FromID, req.IntoID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Record(ctx, auditmodels.Change{
+		Action:     auditmodels.ActionVehiclesMerged,
+		EntityType: entityType(kind),
+		EntityID:   from.ID,
+		Before:     from,
+		After:      merge,
+	})
+
+	return merge, nil
+}
+
+// RemapCars implements Service by matching the cars that are not fully mapped onto the taxonomy again. Cars the
+// taxonomy still does not know are left as they are.
+func (s *ServiceImpl) RemapCars(ctx context.Context) (*models.VehicleRemap, error) {
+	cars, err := s.repo.ListUnmappedCars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	remap := &models.VehicleRemap{Checked: len(cars)}
+
+	for _, car := range cars {
+		mapped := car
+		if err := Resolve(ctx, s.repo, &mapped); err != nil {
+			return remap, err
+		}
+
+		if mapped.MakeID == car.MakeID && mapped.ModelID == car.ModelID && mapped.TrimID == car.TrimID {
+			continue
+		}
+
+		if err := s.repo.SetCarVehicle(ctx, mapped); err != nil {
+			return remap, err
+		}
+
+		remap.Remapped++
+	}
+
+	return remap, nil
+}
+
+// Seed implements Service by loading the embedded dataset into an empty taxonomy. It reports whether it did.
+func (s *ServiceImpl) Seed(ctx context.Context) (bool, error) {
+	vehicles, err := loadDataset(dataset)
+	if err != nil {
+		return false, err
+	}
+
+	return s.repo.SeedVehicles(ctx, vehicles)
+}
+
+// loadDataset returns the entries of table with every make before its models and every model before its trims.
+func loadDataset(table string) ([]models.Vehicle, error) {
+	records, err := csv.NewReader(strings.NewReader(table)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading the vehicle dataset: %w", err)
+	}
+
+	seen := map[string]bool{}
+	makes, modelsOf, trims := []models.Vehicle{}, []models.Vehicle{}, []models.Vehicle{}
+
+	add := func(list *[]models.Vehicle, kind models.VehicleKind, parentID string, name string) string {
+		id := models.VehicleID(parentID, name)
+		if !seen[string(kind)+"/"+id] {
+			seen[string(kind)+"/"+id] = true
+			*list = append(*list, models.Vehicle{Kind: kind, ID: id, ParentID: parentID, Name: name})
+		}
+
+		return id
+	}
+
+	// The first record is the header.
+	for _, record := range records[1:] {
+		makeID := add(&makes, models.VehicleMake, "", record[0])
+		modelID := add(&modelsOf, models.VehicleModel, makeID, record[1])
+
+		if record[2] != "" {
+			add(&trims, models.VehicleTrim, modelID, record[2])
+		}
+	}
+
+	return append(append(makes, modelsOf...), trims...), nil
+}
+
+func entityType(kind models.VehicleKind) string {
+	switch kind {
+	case models.VehicleModel:
+		return auditmodels.EntityVehicleModel
+	case models.VehicleTrim:
+		return auditmodels.EntityVehicleTrim
+	default:
+		return auditmodels.EntityVehicleMake
+	}
+}
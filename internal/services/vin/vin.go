@@ -0,0 +1,140 @@
+// Package vin validates and decodes ISO 3779 vehicle identification numbers.
+package vin
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+)
+
+// Length is the number of characters of a VIN.
+const Length = 17
+
+// checkDigitPosition is the index of the check digit.
+const checkDigitPosition = 8
+
+// modelYearPosition is the index of the model year code.
+const modelYearPosition = 9
+
+// transliteration gives the value of each character allowed in a VIN. I, O and Q are never used.
+var transliteration = map[rune]int{
+	'0': 0, '1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '6': 6, '7': 7, '8': 8, '9': 9,
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+}
+
+// weights are the check digit weights of each position.
+var weights = [Length]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// yearCodes are the model year codes in order, starting with 1980. They repeat every 30 years.
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// firstModelYear is the model year of the first code of yearCodes.
+const firstModelYear = 1980
+
+// wmiTable lists world manufacturer identifiers as wmi,manufacturer,make.
+//
+//go:embed wmi.csv
+var wmiTable string
+
+type manufacturer struct {
+	name string
+	make string
+}
+
+var manufacturers = loadManufacturers(wmiTable)
+
+func loadManufacturers(table string) map[string]manufacturer {
+	records, err := csv.NewReader(strings.NewReader(table)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("reading the embedded wmi table: %v", err))
+	}
+
+	byWMI := make(map[string]manufacturer, len(records))
+
+	// The first record is the header.
+	for _, record := range records[1:] {
+		byWMI[record[0]] = manufacturer{name: record[1], make: record[2]}
+	}
+
+	return byWMI
+}
+
+// Parse returns vin in upper case without spaces or dashes. It returns models.ErrInvalidVIN unless the result is 17
+// valid characters with a correct check digit. The check digit is only verified where it is mandatory: European and
+// Japanese manufacturers commonly put other characters in its position.
+func Parse(vin string) (string, error) {
+	vin = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(vin)))
+
+	if len(vin) != Length {
+		return "", fmt.Errorf("%w: a vin has %d characters", models.ErrInvalidVIN, Length)
+	}
+
+	sum := 0
+
+	for i, c := range vin {
+		value, ok := transliteration[c]
+		if !ok {
+			return "", fmt.Errorf("%w: %q is not allowed in a vin", models.ErrInvalidVIN, c)
+		}
+
+		sum += value * weights[i]
+	}
+
+	check := byte('0' + sum%11)
+	if sum%11 == 10 {
+		check = 'X'
+	}
+
+	if requiresCheckDigit(vin) && vin[checkDigitPosition] != check {
+		return "", fmt.Errorf("%w: the check digit does not match", models.ErrInvalidVIN)
+	}
+
+	return vin, nil
+}
+
+// Decode parses vin and decodes its manufacturer and model year. Model year codes repeat every 30 years, so the later
+// cycle is assumed unless that year is still to come at now. North American VINs mark the later cycle with a letter in
+// the seventh position.
+func Decode(vin string, now time.Time) (models.VINInfo, error) {
+	vin, err := Parse(vin)
+	if err != nil {
+		return models.VINInfo{}, err
+	}
+
+	info := models.VINInfo{VIN: vin, WMI: vin[:3]}
+
+	if m, ok := manufacturers[info.WMI]; ok {
+		info.Manufacturer, info.Make = m.name, m.make
+	}
+
+	if code := strings.IndexByte(yearCodes, vin[modelYearPosition]); code >= 0 {
+		info.ModelYear = firstModelYear + code
+
+		laterCycle := info.ModelYear + len(yearCodes)
+		if laterCycle <= now.Year()+1 && (!isNorthAmerican(vin) || isLetter(vin[6])) {
+			info.ModelYear = laterCycle
+		}
+	}
+
+	return info, nil
+}
+
+// requiresCheckDigit reports whether vin was assigned in North America or China, where the check digit is mandatory.
+func requiresCheckDigit(vin string) bool {
+	return isNorthAmerican(vin) || vin[0] == 'L'
+}
+
+// isNorthAmerican reports whether vin was assigned in the United States, Canada or Mexico.
+func isNorthAmerican(vin string) bool {
+	return vin[0] >= '1' && vin[0] <= '5'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,69 @@
+package vin
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/namkatcedrickjumtock/sigma-auto-api/internal/models/cars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		vin   string
+		want  string
+		valid bool
+	}{
+		{"valid", "1HGCM82633A004352", "1HGCM82633A004352", true},
+		{"check digit X", "1M8GDM9AXKP042788", "1M8GDM9AXKP042788", true},
+		{"normalized", " 1hgcm8-2633 a004352", "1HGCM82633A004352", true},
+		{"wrong check digit", "1HGCM82643A004352", "", false},
+		{"check digit not used in europe", "WVWZZZ1KZ8W000000", "WVWZZZ1KZ8W000000", true},
+		{"check digit used in china", "LSVAB2AT5E2000001", "", false},
+		{"too short", "1HGCM82633A00435", "", false},
+		{"letter O", "1HGCM82633AO04352", "", false},
+		{"letter I", "WVWZZZ1KZ8W00000I", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.vin)
+			if !tt.valid {
+				assert.ErrorIs(t, err, models.ErrInvalidVIN)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	now := time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		vin  string
+		want models.VINInfo
+	}{
+		{"1HGCM82633A004352", models.VINInfo{VIN: "1HGCM82633A004352", WMI: "1HG", Manufacturer: "Honda of America", Make: "Honda", ModelYear: 2003}},
+		// A letter in the seventh position of a North American VIN marks the later cycle.
+		{"5YJ3E1EA2KF317000", models.VINInfo{VIN: "5YJ3E1EA2KF317000", WMI: "5YJ", Manufacturer: "Tesla", Make: "Tesla", ModelYear: 2019}},
+		{"1M8GDM9AXKP042788", models.VINInfo{VIN: "1M8GDM9AXKP042788", WMI: "1M8", ModelYear: 1989}},
+		// Elsewhere the later cycle is assumed unless it is still to come.
+		{"WVWZZZ1KZ8W000000", models.VINInfo{VIN: "WVWZZZ1KZ8W000000", WMI: "WVW", Manufacturer: "Volkswagen", Make: "Volkswagen", ModelYear: 2008}},
+		{"VF1RFB00XY5000000", models.VINInfo{VIN: "VF1RFB00XY5000000", WMI: "VF1", Manufacturer: "Renault", Make: "Renault", ModelYear: 2000}},
+		{"JTDBR32E7Z0012345", models.VINInfo{VIN: "JTDBR32E7Z0012345", WMI: "JTD", Manufacturer: "Toyota Motor Corporation", Make: "Toyota"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vin, func(t *testing.T) {
+			got, err := Decode(tt.vin, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}